@@ -15,6 +15,7 @@ import (
 	"github.com/triabokon/gotagv/internal/controller"
 	"github.com/triabokon/gotagv/internal/flags"
 	"github.com/triabokon/gotagv/internal/postgresql"
+	"github.com/triabokon/gotagv/internal/ratelimit"
 	"github.com/triabokon/gotagv/internal/server"
 	"github.com/triabokon/gotagv/internal/storage"
 )
@@ -32,6 +33,9 @@ func Cmd() *cobra.Command {
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
 		flags.MustBindEnvToFlagSet(cmd.Flags())
+		if vErr := config.Controller.Validate(); vErr != nil {
+			return fmt.Errorf("invalid controller config: %w", vErr)
+		}
 		var logger, _ = zap.NewProduction(zap.AddStacktrace(zapcore.InfoLevel))
 		pgClient, pgClientCl, err := postgresql.New(cmd.Context(), config.Postgres)
 		if err != nil {
@@ -44,11 +48,21 @@ func Cmd() *cobra.Command {
 			}
 		}()
 
-		srv := server.New(logger, &config.HTTP, auth.New(&config.Auth), controller.New(storage.New(pgClient)))
-		srv.SetRoutes()
-
 		ctx, cancel := context.WithCancel(cmd.Context())
 		defer cancel()
+
+		st := storage.New(pgClient)
+		authSvc, err := auth.New(&config.Auth, st)
+		if err != nil {
+			return fmt.Errorf("failed to init auth: %w", err)
+		}
+		go authSvc.RunPruner(ctx, logger)
+		limiter := ratelimit.New(&config.RateLimit, st, logger)
+		go limiter.RunPruner(ctx)
+
+		srv := server.New(logger, &config.HTTP, authSvc, limiter, controller.New(&config.Controller, st))
+		srv.SetRoutes()
+
 		// Handle SIGINT and SIGTERM signals
 		go func() {
 			signals := make(chan os.Signal, 1)
@@ -4,7 +4,9 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/triabokon/gotagv/internal/auth"
+	"github.com/triabokon/gotagv/internal/controller"
 	"github.com/triabokon/gotagv/internal/postgresql"
+	"github.com/triabokon/gotagv/internal/ratelimit"
 	"github.com/triabokon/gotagv/internal/server"
 )
 
@@ -12,7 +14,9 @@ type Config struct {
 	HTTP     server.Config
 	Postgres postgresql.Config
 
-	Auth auth.Config
+	Auth       auth.Config
+	RateLimit  ratelimit.Config
+	Controller controller.Config
 }
 
 func (c *Config) Flags() *pflag.FlagSet {
@@ -22,5 +26,7 @@ func (c *Config) Flags() *pflag.FlagSet {
 	f.AddFlagSet(c.Postgres.Flags("postgres"))
 
 	f.AddFlagSet(c.Auth.Flags("auth"))
+	f.AddFlagSet(c.RateLimit.Flags("ratelimit"))
+	f.AddFlagSet(c.Controller.Flags("controller"))
 	return f
 }
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+const (
+	// APIKeyHeader is an alternative to Authorization header for clients authenticated with API key.
+	APIKeyHeader = "X-API-Key"
+	// apiKeyPrefix makes keys recognizable, e.g. by secret scanners.
+	apiKeyPrefix = "gtv_"
+)
+
+// NewAPIKey generates a new API key, only its hash should be stored.
+func NewAPIKey() (string, error) {
+	token, err := NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + token, nil
+}
+
+// ValidateAPIKey returns claims of the key owner limited by key scopes.
+func (a *Auth) ValidateAPIKey(ctx context.Context, key string) (*Claims, error) {
+	k, err := a.store.UseAPIKey(ctx, HashToken(key))
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, fmt.Errorf("unknown or expired api key: %w", model.ErrUnauthenticated)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to use api key: %w", err)
+	}
+	u, uErr := a.store.GetUser(ctx, k.UserID)
+	if uErr != nil {
+		return nil, fmt.Errorf("failed to get user: %w", uErr)
+	}
+
+	claims := &Claims{
+		UserID:      u.ID,
+		Role:        u.Role,
+		WorkspaceID: u.WorkspaceID,
+		APIKeyID:    k.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       k.ID,
+			IssuedAt: jwt.NewNumericDate(k.CreatedAt),
+		},
+	}
+	for _, s := range k.Scopes {
+		claims.Scopes = append(claims.Scopes, Permission(s))
+	}
+	if k.ExpiresAt != nil {
+		claims.ExpiresAt = jwt.NewNumericDate(*k.ExpiresAt)
+	}
+	// keys are revoked together with the other tokens of the user
+	if rErr := a.checkRevoked(ctx, claims); rErr != nil {
+		return nil, rErr
+	}
+	return claims, nil
+}
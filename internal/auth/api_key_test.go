@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// fakeAPIKeyStore keeps API keys by hash, tokens of revoked users are rejected.
+type fakeAPIKeyStore struct {
+	Store
+	keys    map[string]*model.APIKey
+	users   map[string]*model.User
+	revoked map[string]bool
+}
+
+func (s *fakeAPIKeyStore) UseAPIKey(_ context.Context, keyHash string) (*model.APIKey, error) {
+	k, ok := s.keys[keyHash]
+	if !ok || k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now()) {
+		return nil, model.ErrNotFound
+	}
+	return k, nil
+}
+
+func (s *fakeAPIKeyStore) GetUser(_ context.Context, id string) (*model.User, error) {
+	return s.users[id], nil
+}
+
+func (s *fakeAPIKeyStore) IsTokenRevoked(_ context.Context, _, userID string, _ time.Time) (bool, error) {
+	return s.revoked[userID], nil
+}
+
+func TestAuthorizeAPIKey(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	store := &fakeAPIKeyStore{
+		keys: map[string]*model.APIKey{},
+		users: map[string]*model.User{
+			"editor":     {ID: "editor", Role: model.EditorRole, WorkspaceID: "workspace"},
+			"viewer":     {ID: "viewer", Role: model.ViewerRole, WorkspaceID: "workspace"},
+			"signed out": {ID: "signed out", Role: model.EditorRole},
+		},
+		revoked: map[string]bool{"signed out": true},
+	}
+	addKey := func(key, userID string, scopes []string, expiresAt *time.Time) string {
+		store.keys[HashToken(key)] = &model.APIKey{ID: key, UserID: userID, Scopes: scopes, ExpiresAt: expiresAt}
+		return key
+	}
+	a, err := New(&Config{JWTSecret: "secret"}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		p        Permission
+		wantCode int
+	}{
+		{name: "unscoped key", key: addKey("unscoped", "editor", nil, nil), p: WritePermission, wantCode: http.StatusOK},
+		{name: "read scope reads", key: addKey("read", "editor", []string{"read"}, nil), p: ReadPermission,
+			wantCode: http.StatusOK},
+		{name: "read scope writes", key: "read", p: WritePermission, wantCode: http.StatusForbidden},
+		{name: "write scope reads", key: addKey("write", "editor", []string{"write"}, nil), p: ReadPermission,
+			wantCode: http.StatusOK},
+		// scopes don't extend the role
+		{name: "admin scope of editor", key: addKey("admin", "editor", []string{"admin"}, nil), p: AdminPermission,
+			wantCode: http.StatusForbidden},
+		{name: "write scope of viewer", key: addKey("viewer", "viewer", []string{"write"}, nil), p: WritePermission,
+			wantCode: http.StatusForbidden},
+		{name: "unknown key", key: "unknown", p: ReadPermission, wantCode: http.StatusUnauthorized},
+		{name: "expired key", key: addKey("expired", "editor", nil, &expired), p: ReadPermission,
+			wantCode: http.StatusUnauthorized},
+		{name: "revoked user tokens", key: addKey("revoked", "signed out", nil, nil), p: ReadPermission,
+			wantCode: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims *Claims
+			handler := a.Authorize(tt.p, func(w http.ResponseWriter, r *http.Request) {
+				claims, _ = r.Context().Value(ClaimsKey).(*Claims)
+			})
+			r := httptest.NewRequest(http.MethodGet, "/v1/videos", http.NoBody)
+			r.Header.Set(APIKeyHeader, tt.key)
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			if w.Code == http.StatusOK && (claims == nil || claims.APIKeyID != tt.key) {
+				t.Errorf("claims = %+v, want claims of api key %s", claims, tt.key)
+			}
+		})
+	}
+}
+
+func TestNewAPIKey(t *testing.T) {
+	key, err := NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := NewAPIKey()
+	if !strings.HasPrefix(key, apiKeyPrefix) || key == other || HashToken(key) == key {
+		t.Errorf("NewAPIKey() = %s, %s, want distinct prefixed keys", key, other)
+	}
+}
@@ -8,39 +8,95 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/pborman/uuid"
+
+	"github.com/triabokon/gotagv/internal/model"
 )
 
 type ContextKeys string
 
-const UserIDKey ContextKeys = "user_id"
+const (
+	UserIDKey      ContextKeys = "user_id"
+	RoleKey        ContextKeys = "role"
+	WorkspaceIDKey ContextKeys = "workspace_id"
+	ClaimsKey      ContextKeys = "claims"
+)
+
+// WorkspaceHeader allows to choose workspace of the request instead of the one from token.
+const WorkspaceHeader = "X-Workspace-ID"
+
+type Store interface {
+	GetUser(ctx context.Context, id string) (*model.User, error)
+	GetUserByOIDCSubject(ctx context.Context, issuer, subject string) (*model.User, error)
+
+	GetRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	InsertRefreshToken(ctx context.Context, t *model.RefreshToken) error
+	UseRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+
+	UseAPIKey(ctx context.Context, keyHash string) (*model.APIKey, error)
+
+	RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti, userID string, issuedAt time.Time) (bool, error)
+	RevokeUserTokens(ctx context.Context, userID string) error
+	PruneExpiredTokens(ctx context.Context, before time.Time) (int64, error)
+}
 
 type Auth struct {
 	config *Config
+	store  Store
+	keys   *keySet
+	oidc   *oidcProvider
 }
 
-func New(config *Config) *Auth {
+func New(config *Config, store Store) (*Auth, error) {
+	keys, err := loadKeys(config.SigningKeys, config.SigningKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	oidc, oErr := newOIDCProvider(config)
+	if oErr != nil {
+		return nil, oErr
+	}
 	srv := &Auth{
 		config: config,
+		store:  store,
+		keys:   keys,
+		oidc:   oidc,
 	}
-	return srv
+	return srv, nil
 }
 
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID      string     `json:"user_id"`
+	Role        model.Role `json:"role"`
+	WorkspaceID string     `json:"workspace_id"`
+	// Scopes limit permissions of the role, they are set for API keys only.
+	Scopes   []Permission `json:"-"`
+	APIKeyID string       `json:"-"`
 	jwt.RegisteredClaims
 }
 
-func (a *Auth) CreateToken(userID string) (string, error) {
-	// set token expiration time to be 30 minutes from now for testing purposes.
-	expirationTime := time.Now().Add(30 * time.Minute)
+func (a *Auth) CreateToken(u *model.User) (string, error) {
+	now := time.Now()
 	claims := &Claims{
-		UserID: userID,
+		UserID:      u.ID,
+		Role:        u.Role,
+		WorkspaceID: u.WorkspaceID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			ID:        uuid.New(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(a.config.AccessTokenTTL)),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(a.config.JWTSecret))
+	if a.keys.active == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(a.config.JWTSecret))
+	}
+
+	token := jwt.NewWithClaims(a.keys.active.method, claims)
+	token.Header["kid"] = a.keys.active.id
+	tokenString, err := token.SignedString(a.keys.active.private)
 	if err != nil {
 		return "", err
 	}
@@ -48,38 +104,95 @@ func (a *Auth) CreateToken(userID string) (string, error) {
 	return tokenString, nil
 }
 
-func (a *Auth) ValidateToken(tknStr string) (*Claims, error) {
+// ValidateToken accepts tokens issued by the service and ID tokens of OIDC provider.
+func (a *Auth) ValidateToken(ctx context.Context, tknStr string) (*Claims, error) {
+	if a.oidc != nil && a.oidc.issued(tknStr) {
+		return a.validateOIDCToken(ctx, tknStr)
+	}
+
 	claims := &Claims{}
+	tkn, err := jwt.ParseWithClaims(tknStr, claims, a.verificationKey)
+	if err != nil {
+		return nil, err
+	}
+	if !tkn.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	if rErr := a.checkRevoked(ctx, claims); rErr != nil {
+		return nil, rErr
+	}
+	return claims, nil
+}
 
-	tkn, err := jwt.ParseWithClaims(tknStr, claims, func(token *jwt.Token) (interface{}, error) {
+func (a *Auth) checkRevoked(ctx context.Context, claims *Claims) error {
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revoked, err := a.store.IsTokenRevoked(ctx, claims.ID, claims.UserID, issuedAt)
+	if err != nil {
+		return fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return fmt.Errorf("token revoked")
+	}
+	return nil
+}
+
+// verificationKey selects key by kid header of the token, so tokens signed with keys
+// that are no longer active stay valid while the keys are configured.
+// Tokens without kid are signed with shared secret.
+func (a *Auth) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, hasKID := token.Header["kid"].(string)
+	if !hasKID {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
+		if a.config.JWTSecret == "" && len(a.keys.keys) > 0 {
+			return nil, fmt.Errorf("shared secret tokens aren't accepted")
+		}
 		return []byte(a.config.JWTSecret), nil
-	})
-	if err != nil {
-		return nil, err
 	}
-	if !tkn.Valid {
-		return nil, err
+
+	key := a.keys.get(kid)
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
 	}
-	return claims, nil
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.public, nil
 }
 
 func (a *Auth) HandleAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		splitToken := strings.Split(authHeader, "Bearer ")
-		if len(splitToken) != 2 {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-		claims, err := a.ValidateToken(splitToken[1])
+		claims, err := a.requestClaims(r)
 		if err != nil {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
-		r = r.WithContext(context.WithValue(r.Context(), UserIDKey, claims.UserID))
-		next(w, r)
+		workspaceID := r.Header.Get(WorkspaceHeader)
+		if workspaceID == "" {
+			workspaceID = claims.WorkspaceID
+		}
+		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, RoleKey, claims.Role)
+		ctx = context.WithValue(ctx, WorkspaceIDKey, workspaceID)
+		ctx = context.WithValue(ctx, ClaimsKey, claims)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// requestClaims authenticates request either by API key or by bearer token.
+func (a *Auth) requestClaims(r *http.Request) (*Claims, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.ValidateAPIKey(r.Context(), key)
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	splitToken := strings.Split(authHeader, "Bearer ")
+	if len(splitToken) != 2 {
+		return nil, fmt.Errorf("no bearer token: %w", model.ErrUnauthenticated)
 	}
+	return a.ValidateToken(r.Context(), splitToken[1])
 }
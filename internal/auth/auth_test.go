@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+func TestHandleAuthWorkspace(t *testing.T) {
+	a, err := New(&Config{JWTSecret: "secret", AccessTokenTTL: time.Minute}, &fakeAPIKeyStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tkn, err := a.CreateToken(&model.User{ID: "user", Role: model.EditorRole, WorkspaceID: "personal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for header, want := range map[string]string{"": "personal", "team": "team"} {
+		var workspaceID string
+		handler := a.HandleAuth(func(w http.ResponseWriter, r *http.Request) {
+			workspaceID, _ = r.Context().Value(WorkspaceIDKey).(string)
+		})
+		r := httptest.NewRequest(http.MethodGet, "/v1/videos", http.NoBody)
+		r.Header.Set("Authorization", "Bearer "+tkn)
+		r.Header.Set(WorkspaceHeader, header)
+		handler(httptest.NewRecorder(), r)
+		if workspaceID != want {
+			t.Errorf("workspace with header %q = %q, want %q", header, workspaceID, want)
+		}
+	}
+}
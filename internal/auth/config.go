@@ -1,13 +1,26 @@
 package auth
 
 import (
+	"time"
+
 	"github.com/spf13/pflag"
 
 	"github.com/triabokon/gotagv/internal/flags"
 )
 
 type Config struct {
-	JWTSecret string
+	JWTSecret       string
+	SigningKeys     []string
+	SigningKeyID    string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	PruneInterval   time.Duration
+
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       []string
 }
 
 func (c *Config) Flags(prefix string) *pflag.FlagSet {
@@ -15,5 +28,21 @@ func (c *Config) Flags(prefix string) *pflag.FlagSet {
 	f := pflag.NewFlagSet(name, pflag.PanicOnError)
 
 	f.StringVar(&c.JWTSecret, "jwt_secret", "", "secret to generate jwt tokens")
+	f.StringSliceVar(
+		&c.SigningKeys, "signing_keys", nil,
+		"RSA or Ed25519 PEM keys as <kid>=<path> to sign and verify jwt tokens, public keys only verify",
+	)
+	f.StringVar(&c.SigningKeyID, "signing_key_id", "", "kid of the signing key used to sign new jwt tokens")
+	f.DurationVar(&c.AccessTokenTTL, "access_token_ttl", 30*time.Minute, "lifetime of jwt access tokens")
+	f.DurationVar(&c.RefreshTokenTTL, "refresh_token_ttl", 30*24*time.Hour, "lifetime of refresh tokens")
+	f.DurationVar(&c.PruneInterval, "prune_interval", time.Hour, "how often expired tokens are deleted, 0 disables it")
+
+	f.StringVar(&c.OIDCIssuer, "oidc_issuer", "", "issuer url of OpenID Connect provider, empty disables OIDC login")
+	f.StringVar(&c.OIDCClientID, "oidc_client_id", "", "client id registered at OpenID Connect provider")
+	f.StringVar(&c.OIDCClientSecret, "oidc_client_secret", "", "client secret registered at OpenID Connect provider")
+	f.StringVar(&c.OIDCRedirectURL, "oidc_redirect_url", "", "url of /oidc/callback the provider redirects to")
+	f.StringSliceVar(
+		&c.OIDCScopes, "oidc_scopes", []string{"openid", "email", "profile"}, "scopes requested from OIDC provider",
+	)
 	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
 }
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is an asymmetric key identified by kid header of the token,
+// keys loaded from public key files can only verify tokens.
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+type keySet struct {
+	keys   []*signingKey
+	active *signingKey
+}
+
+// loadKeys reads keys specified as "<kid>=<path to pem file>", token are signed with key activeID.
+func loadKeys(specs []string, activeID string) (*keySet, error) {
+	set := &keySet{}
+	for _, spec := range specs {
+		id, path, ok := strings.Cut(spec, "=")
+		if !ok || id == "" || path == "" {
+			return nil, fmt.Errorf("invalid signing key %q, expected <kid>=<path>", spec)
+		}
+		if set.get(id) != nil {
+			return nil, fmt.Errorf("duplicate signing key id %q", id)
+		}
+		key, err := readKey(id, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load signing key %q: %w", id, err)
+		}
+		set.keys = append(set.keys, key)
+	}
+
+	if activeID == "" {
+		if len(set.keys) > 0 {
+			return nil, fmt.Errorf("active signing key id is required when signing keys are set")
+		}
+		return set, nil
+	}
+	set.active = set.get(activeID)
+	if set.active == nil {
+		return nil, fmt.Errorf("unknown active signing key id %q", activeID)
+	}
+	if set.active.private == nil {
+		return nil, fmt.Errorf("active signing key %q has no private key", activeID)
+	}
+	return set, nil
+}
+
+func (s *keySet) get(id string) *signingKey {
+	for _, k := range s.keys {
+		if k.id == id {
+			return k
+		}
+	}
+	return nil
+}
+
+func readKey(id, path string) (*signingKey, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path comes from service configuration
+	if err != nil {
+		return nil, err
+	}
+
+	if private, pErr := jwt.ParseRSAPrivateKeyFromPEM(data); pErr == nil {
+		return &signingKey{id: id, method: jwt.SigningMethodRS256, private: private, public: &private.PublicKey}, nil
+	}
+	if private, pErr := jwt.ParseEdPrivateKeyFromPEM(data); pErr == nil {
+		if edKey, ok := private.(ed25519.PrivateKey); ok {
+			return &signingKey{id: id, method: jwt.SigningMethodEdDSA, private: edKey, public: edKey.Public()}, nil
+		}
+	}
+	if public, pErr := jwt.ParseRSAPublicKeyFromPEM(data); pErr == nil {
+		return &signingKey{id: id, method: jwt.SigningMethodRS256, public: public}, nil
+	}
+	if public, pErr := jwt.ParseEdPublicKeyFromPEM(data); pErr == nil {
+		return &signingKey{id: id, method: jwt.SigningMethodEdDSA, public: public}, nil
+	}
+	return nil, fmt.Errorf("%s is neither RSA nor Ed25519 PEM key", path)
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA public key
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 public key
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns public keys which can be used to verify tokens issued by the service.
+func (a *Auth) JWKS() *JWKSet {
+	set := &JWKSet{Keys: []JWK{}}
+	for _, k := range a.keys.keys {
+		jwk := JWK{Kid: k.id, Use: "sig", Alg: k.method.Alg()}
+		switch public := k.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// testKeys are RSA and Ed25519 private keys and the public RSA key written into PEM files,
+// keys are specified as "<kid>=<path>".
+type testKeys struct {
+	rsa       string
+	ed25519   string
+	rsaPublic string
+	rsaKey    *rsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name+".pem")
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return name + "=" + path
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKeys{
+		rsa:       write("rsa", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)),
+		ed25519:   write("ed", "PRIVATE KEY", edDER),
+		rsaPublic: write("rsa-public", "PUBLIC KEY", publicDER),
+		rsaKey:    rsaKey,
+	}
+}
+
+func newKeyAuth(t *testing.T, config *Config) *Auth {
+	t.Helper()
+	config.AccessTokenTTL = time.Minute
+	a, err := New(config, &fakeAPIKeyStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestLoadKeysErrors(t *testing.T) {
+	keys := newTestKeys(t)
+	tests := []struct {
+		name     string
+		specs    []string
+		activeID string
+	}{
+		{name: "no path", specs: []string{"rsa"}, activeID: "rsa"},
+		{name: "missing file", specs: []string{"rsa=/nonexistent.pem"}, activeID: "rsa"},
+		{name: "duplicate id", specs: []string{keys.rsa, keys.rsa}, activeID: "rsa"},
+		{name: "no active key", specs: []string{keys.rsa}},
+		{name: "unknown active key", specs: []string{keys.rsa}, activeID: "ed"},
+		{name: "public active key", specs: []string{keys.rsaPublic}, activeID: "rsa-public"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadKeys(tt.specs, tt.activeID); err == nil {
+				t.Error("loadKeys() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestSigningKeys(t *testing.T) {
+	keys := newTestKeys(t)
+	user := &model.User{ID: "user", Role: model.EditorRole, WorkspaceID: "workspace"}
+	for _, tt := range []struct {
+		name   string
+		active string
+		alg    string
+	}{
+		{name: "rsa", active: "rsa", alg: "RS256"},
+		{name: "ed25519", active: "ed", alg: "EdDSA"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newKeyAuth(t, &Config{SigningKeys: []string{keys.rsa, keys.ed25519}, SigningKeyID: tt.active})
+			tkn, err := a.CreateToken(user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, _, err := jwt.NewParser().ParseUnverified(tkn, &Claims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Header["kid"] != tt.active || parsed.Method.Alg() != tt.alg {
+				t.Errorf("header = %v, want kid %s and alg %s", parsed.Header, tt.active, tt.alg)
+			}
+			claims, err := a.ValidateToken(context.Background(), tkn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.UserID != user.ID || claims.Role != user.Role {
+				t.Errorf("claims = %+v, want claims of %+v", claims, user)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	keys := newTestKeys(t)
+	user := &model.User{ID: "user", Role: model.EditorRole}
+	old := newKeyAuth(t, &Config{SigningKeys: []string{keys.rsa}, SigningKeyID: "rsa"})
+	inFlight, err := old.CreateToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newKeyAuth(t, &Config{SigningKeys: []string{keys.rsa, keys.ed25519}, SigningKeyID: "ed"})
+	if _, vErr := rotated.ValidateToken(context.Background(), inFlight); vErr != nil {
+		t.Errorf("token of the previous key is rejected after rotation: %v", vErr)
+	}
+	// verifying services need only public part of the previous key
+	verifier := newKeyAuth(t, &Config{SigningKeys: []string{keys.rsaPublic, keys.ed25519}, SigningKeyID: "ed"})
+	if _, vErr := verifier.ValidateToken(context.Background(), sign(
+		t, jwt.SigningMethodRS256, "rsa-public", keys.rsaKey, &Claims{UserID: "user"},
+	)); vErr != nil {
+		t.Errorf("token of the public key is rejected: %v", vErr)
+	}
+
+	retired := newKeyAuth(t, &Config{SigningKeys: []string{keys.ed25519}, SigningKeyID: "ed"})
+	if _, vErr := retired.ValidateToken(context.Background(), inFlight); vErr == nil {
+		t.Error("token of the removed key is accepted")
+	}
+}
+
+func TestValidateTokenRejectsForgedKeys(t *testing.T) {
+	keys := newTestKeys(t)
+	a := newKeyAuth(t, &Config{SigningKeys: []string{keys.rsa}, SigningKeyID: "rsa"})
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: "user"})
+	secretToken, err := hmac.SignedString([]byte(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"shared secret without secret": secretToken,
+		// public key must not be used as HMAC secret
+		"hmac with kid of rsa key": sign(
+			t, jwt.SigningMethodHS256, "rsa", x509.MarshalPKCS1PublicKey(&keys.rsaKey.PublicKey), &Claims{UserID: "user"},
+		),
+		"unknown kid": sign(t, jwt.SigningMethodRS256, "unknown", keys.rsaKey, &Claims{UserID: "user"}),
+	}
+	for name, tkn := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, vErr := a.ValidateToken(context.Background(), tkn); vErr == nil {
+				t.Error("ValidateToken() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	keys := newTestKeys(t)
+	a := newKeyAuth(t, &Config{SigningKeys: []string{keys.rsa, keys.ed25519}, SigningKeyID: "rsa"})
+	set := a.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("keys = %+v, want rsa and ed25519 keys", set.Keys)
+	}
+	rsaJWK, edJWK := set.Keys[0], set.Keys[1]
+	if rsaJWK.Kid != "rsa" || rsaJWK.Kty != "RSA" || rsaJWK.Alg != "RS256" || rsaJWK.N == "" || rsaJWK.E != "AQAB" {
+		t.Errorf("rsa key = %+v", rsaJWK)
+	}
+	if edJWK.Kid != "ed" || edJWK.Kty != "OKP" || edJWK.Crv != "Ed25519" || edJWK.Alg != "EdDSA" || edJWK.X == "" {
+		t.Errorf("ed25519 key = %+v", edJWK)
+	}
+
+	if secretOnly := newKeyAuth(t, &Config{JWTSecret: "secret"}).JWKS(); len(secretOnly.Keys) != 0 {
+		t.Errorf("keys of shared secret = %+v, want none", secretOnly.Keys)
+	}
+}
@@ -0,0 +1,380 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+const (
+	oidcRequestTimeout = 10 * time.Second
+	// oidcKeysRefreshInterval limits how often provider keys are fetched again
+	// when token is signed with unknown key.
+	oidcKeysRefreshInterval = time.Minute
+	// oidcErrorBodyLimit is how much of provider error response is kept in error message.
+	oidcErrorBodyLimit = 512
+)
+
+var oidcSigningMethods = []string{
+	"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA",
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type idTokenClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Nonce         string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// providerJWK is a key published by the provider, unlike the service providers sign with ECDSA keys too.
+type providerJWK struct {
+	JWK
+	// ECDSA public key, uses Crv and X as well
+	Y string `json:"y,omitempty"`
+}
+
+// oidcProvider is external OpenID Connect identity provider,
+// its discovery document and keys are fetched on first use and cached.
+type oidcProvider struct {
+	config *Config
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+func newOIDCProvider(config *Config) (*oidcProvider, error) {
+	if config.OIDCIssuer == "" {
+		return nil, nil
+	}
+	if config.OIDCClientID == "" || config.OIDCRedirectURL == "" {
+		return nil, fmt.Errorf("oidc client id and redirect url are required when oidc issuer is set")
+	}
+	p := &oidcProvider{
+		config: config,
+		client: &http.Client{Timeout: oidcRequestTimeout},
+	}
+	return p, nil
+}
+
+// OIDCAuthURL returns url of the provider login page, after login provider
+// redirects to the configured redirect url with code and given state.
+func (a *Auth) OIDCAuthURL(ctx context.Context, state, nonce string) (string, error) {
+	if a.oidc == nil {
+		return "", fmt.Errorf("oidc login isn't configured: %w", model.ErrNotFound)
+	}
+	d, err := a.oidc.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, pErr := url.Parse(d.AuthorizationEndpoint)
+	if pErr != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", pErr)
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", a.config.OIDCClientID)
+	q.Set("redirect_uri", a.config.OIDCRedirectURL)
+	q.Set("scope", strings.Join(a.config.OIDCScopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// OIDCExchange exchanges authorization code for ID token and returns identity of the user it was issued to.
+func (a *Auth) OIDCExchange(ctx context.Context, code, nonce string) (*model.OIDCIdentity, error) {
+	if a.oidc == nil {
+		return nil, fmt.Errorf("oidc login isn't configured: %w", model.ErrNotFound)
+	}
+	if code == "" {
+		return nil, fmt.Errorf("empty authorization code: %w", model.ErrInvalidArgument)
+	}
+
+	rawIDToken, err := a.oidc.exchangeCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	claims, vErr := a.oidc.verifyIDToken(ctx, rawIDToken)
+	if vErr != nil {
+		return nil, vErr
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("id token nonce mismatch: %w", model.ErrUnauthenticated)
+	}
+
+	identity := &model.OIDCIdentity{Issuer: claims.Issuer, Subject: claims.Subject}
+	// unverified email can belong to someone else
+	if claims.EmailVerified {
+		identity.Email = claims.Email
+	}
+	return identity, nil
+}
+
+// issued reports whether token claims to be issued by the provider, signature isn't verified.
+func (p *oidcProvider) issued(tknStr string) bool {
+	claims := &jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tknStr, claims); err != nil {
+		return false
+	}
+	return claims.Issuer == p.config.OIDCIssuer
+}
+
+// validateOIDCToken verifies ID token issued by the provider and maps it to the user signed up with it.
+func (a *Auth) validateOIDCToken(ctx context.Context, tknStr string) (*Claims, error) {
+	idClaims, err := a.oidc.verifyIDToken(ctx, tknStr)
+	if err != nil {
+		return nil, err
+	}
+	u, uErr := a.store.GetUserByOIDCSubject(ctx, idClaims.Issuer, idClaims.Subject)
+	if errors.Is(uErr, model.ErrNotFound) {
+		return nil, fmt.Errorf("user has to sign in with oidc first: %w", model.ErrUnauthenticated)
+	}
+	if uErr != nil {
+		return nil, fmt.Errorf("failed to get user: %w", uErr)
+	}
+
+	claims := &Claims{
+		UserID:           u.ID,
+		Role:             u.Role,
+		WorkspaceID:      u.WorkspaceID,
+		RegisteredClaims: idClaims.RegisteredClaims,
+	}
+	if rErr := a.checkRevoked(ctx, claims); rErr != nil {
+		return nil, rErr
+	}
+	return claims, nil
+}
+
+func (p *oidcProvider) verifyIDToken(ctx context.Context, rawIDToken string) (*idTokenClaims, error) {
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(
+		rawIDToken, claims,
+		func(t *jwt.Token) (interface{}, error) {
+			kid, _ := t.Header["kid"].(string)
+			return p.key(ctx, kid)
+		},
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(p.config.OIDCIssuer),
+		jwt.WithAudience(p.config.OIDCClientID),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %v: %w", err, model.ErrUnauthenticated)
+	}
+	if claims.ExpiresAt == nil || claims.Subject == "" {
+		return nil, fmt.Errorf("id token has no exp or sub: %w", model.ErrUnauthenticated)
+	}
+	return claims, nil
+}
+
+func (p *oidcProvider) exchangeCode(ctx context.Context, code string) (string, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.OIDCRedirectURL)
+	req, rErr := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if rErr != nil {
+		return "", fmt.Errorf("failed to create token request: %w", rErr)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(p.config.OIDCClientID), url.QueryEscape(p.config.OIDCClientSecret))
+
+	resp := &struct {
+		IDToken string `json:"id_token"`
+	}{}
+	if dErr := p.do(req, resp); dErr != nil {
+		// provider rejects invalid or already used codes with 400
+		return "", fmt.Errorf("failed to exchange code: %v: %w", dErr, model.ErrUnauthenticated)
+	}
+	if resp.IDToken == "" {
+		return "", fmt.Errorf("token response has no id token: %w", model.ErrUnauthenticated)
+	}
+	return resp.IDToken, nil
+}
+
+func (p *oidcProvider) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.loadDiscovery(ctx)
+}
+
+// loadDiscovery fetches discovery document once, p.mu has to be held.
+func (p *oidcProvider) loadDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	discoveryURL := strings.TrimSuffix(p.config.OIDCIssuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery request: %w", err)
+	}
+	d := &oidcDiscovery{}
+	if dErr := p.do(req, d); dErr != nil {
+		return nil, fmt.Errorf("failed to get oidc discovery document: %w", dErr)
+	}
+	if d.Issuer != p.config.OIDCIssuer {
+		return nil, fmt.Errorf("oidc issuer %q doesn't match configured %q", d.Issuer, p.config.OIDCIssuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery document misses endpoints")
+	}
+	p.discovery = d
+	return d, nil
+}
+
+// key returns provider key by kid, keys are fetched again when kid is unknown
+// as provider might have rotated them.
+func (p *oidcProvider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if k := p.cachedKey(kid); k != nil {
+		return k, nil
+	}
+	if time.Since(p.keysFetchedAt) < oidcKeysRefreshInterval {
+		return nil, fmt.Errorf("unknown oidc signing key %q", kid)
+	}
+	if err := p.fetchKeys(ctx); err != nil {
+		return nil, err
+	}
+	if k := p.cachedKey(kid); k != nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown oidc signing key %q", kid)
+}
+
+// cachedKey looks up the key, tokens without kid are accepted when provider has a single key.
+func (p *oidcProvider) cachedKey(kid string) crypto.PublicKey {
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k
+		}
+	}
+	return p.keys[kid]
+}
+
+// fetchKeys replaces cached provider keys, p.mu has to be held.
+func (p *oidcProvider) fetchKeys(ctx context.Context) error {
+	d, err := p.loadDiscovery(ctx)
+	if err != nil {
+		return err
+	}
+	req, rErr := http.NewRequestWithContext(ctx, http.MethodGet, d.JWKSURI, http.NoBody)
+	if rErr != nil {
+		return fmt.Errorf("failed to create jwks request: %w", rErr)
+	}
+	set := &struct {
+		Keys []providerJWK `json:"keys"`
+	}{}
+	if dErr := p.do(req, set); dErr != nil {
+		return fmt.Errorf("failed to get oidc keys: %w", dErr)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// keys of unsupported types are skipped, provider may publish them for other clients
+		if k, pErr := parseJWK(&jwk); pErr == nil {
+			keys[jwk.Kid] = k
+		}
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+	return nil
+}
+
+// do sends request and decodes json response into v.
+func (p *oidcProvider) do(req *http.Request, v interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, oidcErrorBodyLimit))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+	if dErr := json.NewDecoder(resp.Body).Decode(v); dErr != nil {
+		return fmt.Errorf("failed to decode response: %w", dErr)
+	}
+	return nil
+}
+
+func parseJWK(k *providerJWK) (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, nErr := base64.RawURLEncoding.DecodeString(k.N)
+		e, eErr := base64.RawURLEncoding.DecodeString(k.E)
+		if nErr != nil || eErr != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA key %q", k.Kid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q of key %q", k.Crv, k.Kid)
+		}
+		x, xErr := base64.RawURLEncoding.DecodeString(k.X)
+		y, yErr := base64.RawURLEncoding.DecodeString(k.Y)
+		if xErr != nil || yErr != nil {
+			return nil, fmt.Errorf("invalid EC key %q", k.Kid)
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("invalid EC key %q", k.Kid)
+		}
+		return key, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if k.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid OKP key %q", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q of key %q", k.Kty, k.Kid)
+	}
+}
@@ -0,0 +1,311 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+const (
+	testClientID     = "gotagv"
+	testClientSecret = "secret"
+	testCode         = "code"
+	testNonce        = "nonce"
+)
+
+// testIdP is OpenID Connect provider serving discovery document, keys and token endpoint,
+// the token endpoint responds with idToken to testCode.
+type testIdP struct {
+	*httptest.Server
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	idToken string
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &testIdP{rsaKey: rsaKey, ecKey: ecKey}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, &oidcDiscovery{
+			Issuer:                idp.URL,
+			AuthorizationEndpoint: idp.URL + "/authorize",
+			TokenEndpoint:         idp.URL + "/token",
+			JWKSURI:               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		b64 := base64.RawURLEncoding.EncodeToString
+		writeJSON(t, w, map[string]interface{}{"keys": []providerJWK{
+			{JWK: JWK{
+				Kty: "RSA", Kid: "rsa", Use: "sig",
+				N: b64(rsaKey.N.Bytes()), E: b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+			}},
+			{
+				JWK: JWK{Kty: "EC", Kid: "ec", Use: "sig", Crv: "P-256", X: b64(ecKey.X.Bytes())},
+				Y:   b64(ecKey.Y.Bytes()),
+			},
+			// encryption keys aren't used to verify tokens
+			{JWK: JWK{Kty: "RSA", Kid: "enc", Use: "enc", N: b64(rsaKey.N.Bytes()), E: "AQAB"}},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if r.Method != http.MethodPost || !ok || id != testClientID || secret != testClientSecret {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		if r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("code") != testCode {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		writeJSON(t, w, map[string]string{"id_token": idp.idToken})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Error(err)
+	}
+}
+
+// claims returns valid claims of ID token issued by the provider.
+func (idp *testIdP) claims() *idTokenClaims {
+	now := time.Now()
+	return &idTokenClaims{
+		Email:         "user@example.com",
+		EmailVerified: true,
+		Nonce:         testNonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    idp.URL,
+			Subject:   "subject",
+			Audience:  jwt.ClaimStrings{testClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key crypto.PrivateKey, claims jwt.Claims) string {
+	t.Helper()
+	tkn := jwt.NewWithClaims(method, claims)
+	tkn.Header["kid"] = kid
+	s, err := tkn.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newTestOIDCAuth(t *testing.T, issuer string) *Auth {
+	t.Helper()
+	a, err := New(&Config{
+		JWTSecret:        "jwt-secret",
+		OIDCIssuer:       issuer,
+		OIDCClientID:     testClientID,
+		OIDCClientSecret: testClientSecret,
+		OIDCRedirectURL:  "http://localhost/oidc/callback",
+		OIDCScopes:       []string{"openid", "email"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestOIDCExchange(t *testing.T) {
+	idp := newTestIdP(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		nonce   string
+		idToken func() string
+		want    *model.OIDCIdentity
+		wantErr error
+	}{
+		{
+			name:    "rsa signed",
+			idToken: func() string { return sign(t, jwt.SigningMethodRS256, "rsa", idp.rsaKey, idp.claims()) },
+			want:    &model.OIDCIdentity{Issuer: idp.URL, Subject: "subject", Email: "user@example.com"},
+		},
+		{
+			name:    "ecdsa signed",
+			idToken: func() string { return sign(t, jwt.SigningMethodES256, "ec", idp.ecKey, idp.claims()) },
+			want:    &model.OIDCIdentity{Issuer: idp.URL, Subject: "subject", Email: "user@example.com"},
+		},
+		{
+			name: "unverified email is dropped",
+			idToken: func() string {
+				c := idp.claims()
+				c.EmailVerified = false
+				return sign(t, jwt.SigningMethodRS256, "rsa", idp.rsaKey, c)
+			},
+			want: &model.OIDCIdentity{Issuer: idp.URL, Subject: "subject"},
+		},
+		{
+			name:    "invalid signature",
+			idToken: func() string { return sign(t, jwt.SigningMethodRS256, "rsa", otherKey, idp.claims()) },
+			wantErr: model.ErrUnauthenticated,
+		},
+		{
+			name:    "unknown key",
+			idToken: func() string { return sign(t, jwt.SigningMethodRS256, "other", otherKey, idp.claims()) },
+			wantErr: model.ErrUnauthenticated,
+		},
+		{
+			name:    "encryption key",
+			idToken: func() string { return sign(t, jwt.SigningMethodRS256, "enc", idp.rsaKey, idp.claims()) },
+			wantErr: model.ErrUnauthenticated,
+		},
+		{
+			name: "hmac signed with public key",
+			idToken: func() string {
+				return sign(t, jwt.SigningMethodHS256, "rsa", idp.rsaKey.N.Bytes(), idp.claims())
+			},
+			wantErr: model.ErrUnauthenticated,
+		},
+		{
+			name: "other issuer",
+			idToken: func() string {
+				c := idp.claims()
+				c.Issuer = "https://issuer.example.com"
+				return sign(t, jwt.SigningMethodRS256, "rsa", idp.rsaKey, c)
+			},
+			wantErr: model.ErrUnauthenticated,
+		},
+		{
+			name: "other audience",
+			idToken: func() string {
+				c := idp.claims()
+				c.Audience = jwt.ClaimStrings{"other"}
+				return sign(t, jwt.SigningMethodRS256, "rsa", idp.rsaKey, c)
+			},
+			wantErr: model.ErrUnauthenticated,
+		},
+		{
+			name: "expired",
+			idToken: func() string {
+				c := idp.claims()
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return sign(t, jwt.SigningMethodRS256, "rsa", idp.rsaKey, c)
+			},
+			wantErr: model.ErrUnauthenticated,
+		},
+		{
+			name: "no exp",
+			idToken: func() string {
+				c := idp.claims()
+				c.ExpiresAt = nil
+				return sign(t, jwt.SigningMethodRS256, "rsa", idp.rsaKey, c)
+			},
+			wantErr: model.ErrUnauthenticated,
+		},
+		{
+			name:    "nonce mismatch",
+			nonce:   "other",
+			idToken: func() string { return sign(t, jwt.SigningMethodRS256, "rsa", idp.rsaKey, idp.claims()) },
+			wantErr: model.ErrUnauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonce := testNonce
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			idp.idToken = tt.idToken()
+
+			// every case fetches the keys again
+			identity, err := newTestOIDCAuth(t, idp.URL).OIDCExchange(context.Background(), testCode, nonce)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("OIDCExchange() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OIDCExchange() error = %v", err)
+			}
+			if *identity != *tt.want {
+				t.Errorf("OIDCExchange() = %+v, want %+v", identity, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCExchangeCode(t *testing.T) {
+	idp := newTestIdP(t)
+	idp.idToken = sign(t, jwt.SigningMethodRS256, "rsa", idp.rsaKey, idp.claims())
+
+	tests := []struct {
+		name    string
+		code    string
+		wantErr error
+	}{
+		{name: "empty", code: "", wantErr: model.ErrInvalidArgument},
+		{name: "rejected by provider", code: "unknown", wantErr: model.ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestOIDCAuth(t, idp.URL).OIDCExchange(context.Background(), tt.code, testNonce)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("OIDCExchange() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOIDCAuthURL(t *testing.T) {
+	idp := newTestIdP(t)
+	authURL, err := newTestOIDCAuth(t, idp.URL).OIDCAuthURL(context.Background(), "state", testNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := idp.URL + "/authorize?client_id=gotagv&nonce=nonce" +
+		"&redirect_uri=http%3A%2F%2Flocalhost%2Foidc%2Fcallback&response_type=code&scope=openid+email&state=state"
+	if authURL != want {
+		t.Errorf("OIDCAuthURL() = %s, want %s", authURL, want)
+	}
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	idp := newTestIdP(t)
+	// discovery document of the provider names another issuer
+	_, err := newTestOIDCAuth(t, idp.URL+"/").OIDCAuthURL(context.Background(), "state", testNonce)
+	if err == nil {
+		t.Fatal("OIDCAuthURL() error = nil, want issuer mismatch")
+	}
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+type Permission string
+
+const (
+	// ReadPermission allows listing and fetching entities.
+	ReadPermission Permission = "read"
+	// WritePermission allows creating entities and changing owned ones.
+	WritePermission Permission = "write"
+	// AdminPermission allows managing users and changing any entity.
+	AdminPermission Permission = "admin"
+)
+
+// Valid reports whether p is one of known permissions.
+func (p Permission) Valid() bool {
+	return p == ReadPermission || p == WritePermission || p == AdminPermission
+}
+
+// Allows reports whether role is granted permission p.
+func Allows(role model.Role, p Permission) bool {
+	switch role {
+	case model.AdminRole:
+		return true
+	case model.EditorRole:
+		return p == ReadPermission || p == WritePermission
+	case model.ViewerRole:
+		return p == ReadPermission
+	default:
+		return false
+	}
+}
+
+// Authorize authenticates request and checks that caller's role is granted permission p,
+// API keys with scopes additionally need p to be one of the scopes.
+func (a *Auth) Authorize(p Permission, next http.HandlerFunc) http.HandlerFunc {
+	return a.HandleAuth(func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value(RoleKey).(model.Role)
+		claims, _ := r.Context().Value(ClaimsKey).(*Claims)
+		if !Allows(role, p) || (claims != nil && !claims.inScope(p)) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (c *Claims) inScope(p Permission) bool {
+	if len(c.Scopes) == 0 {
+		return true
+	}
+	for _, s := range c.Scopes {
+		// scopes are nested the same way as permissions of roles
+		if s == p || s == AdminPermission || (s == WritePermission && p == ReadPermission) {
+			return true
+		}
+	}
+	return false
+}
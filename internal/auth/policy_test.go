@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		role model.Role
+		want map[Permission]bool
+	}{
+		{
+			role: model.AdminRole,
+			want: map[Permission]bool{ReadPermission: true, WritePermission: true, AdminPermission: true},
+		},
+		{role: model.EditorRole, want: map[Permission]bool{ReadPermission: true, WritePermission: true}},
+		{role: model.ViewerRole, want: map[Permission]bool{ReadPermission: true}},
+		{role: model.UnspecifiedRole, want: map[Permission]bool{}},
+		{role: model.Role("root"), want: map[Permission]bool{}},
+	}
+	for _, tt := range tests {
+		for _, p := range []Permission{ReadPermission, WritePermission, AdminPermission} {
+			if got := Allows(tt.role, p); got != tt.want[p] {
+				t.Errorf("Allows(%s, %s) = %t, want %t", tt.role, p, got, tt.want[p])
+			}
+		}
+	}
+}
+
+func TestAuthorizeRoles(t *testing.T) {
+	a, err := New(&Config{JWTSecret: "secret", AccessTokenTTL: time.Minute}, &fakeAPIKeyStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := func(role model.Role) string {
+		tkn, tErr := a.CreateToken(&model.User{ID: string(role), Role: role, WorkspaceID: "workspace"})
+		if tErr != nil {
+			t.Fatal(tErr)
+		}
+		return tkn
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		p        Permission
+		wantCode int
+	}{
+		{name: "viewer reads", token: token(model.ViewerRole), p: ReadPermission, wantCode: http.StatusOK},
+		{name: "viewer writes", token: token(model.ViewerRole), p: WritePermission, wantCode: http.StatusForbidden},
+		{name: "editor writes", token: token(model.EditorRole), p: WritePermission, wantCode: http.StatusOK},
+		{name: "editor manages users", token: token(model.EditorRole), p: AdminPermission, wantCode: http.StatusForbidden},
+		{name: "admin manages users", token: token(model.AdminRole), p: AdminPermission, wantCode: http.StatusOK},
+		{name: "no token", p: ReadPermission, wantCode: http.StatusUnauthorized},
+		{name: "invalid token", token: "invalid", p: ReadPermission, wantCode: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var role model.Role
+			handler := a.Authorize(tt.p, func(w http.ResponseWriter, r *http.Request) {
+				role, _ = r.Context().Value(RoleKey).(model.Role)
+			})
+			r := httptest.NewRequest(http.MethodGet, "/v1/videos", http.NoBody)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			if w.Code == http.StatusOK && role == "" {
+				t.Error("role of the token isn't passed in context")
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+const opaqueTokenSize = 32
+
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// IssueTokens creates access token and starts a new family of refresh tokens for the user.
+func (a *Auth) IssueTokens(ctx context.Context, u *model.User) (*Tokens, error) {
+	return a.issueTokens(ctx, u, uuid.New())
+}
+
+// RefreshTokens exchanges refresh token for a new pair of tokens. Every refresh token can be used once,
+// presenting already used token revokes the whole family as it's likely to be stolen.
+func (a *Auth) RefreshTokens(ctx context.Context, refreshToken string) (*Tokens, error) {
+	if refreshToken == "" {
+		return nil, fmt.Errorf("empty refresh token: %w", model.ErrInvalidArgument)
+	}
+	tokenHash := HashToken(refreshToken)
+
+	t, err := a.store.UseRefreshToken(ctx, tokenHash)
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, a.rejectRefreshToken(ctx, tokenHash)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to use refresh token: %w", err)
+	}
+	// expiry is stored without time zone in UTC
+	if time.Now().UTC().After(t.ExpiresAt) {
+		return nil, fmt.Errorf("refresh token expired: %w", model.ErrUnauthenticated)
+	}
+
+	u, uErr := a.store.GetUser(ctx, t.UserID)
+	if uErr != nil {
+		return nil, fmt.Errorf("failed to get user: %w", uErr)
+	}
+	return a.issueTokens(ctx, u, t.FamilyID)
+}
+
+// rejectRefreshToken explains why refresh token can't be used and revokes its family on reuse.
+func (a *Auth) rejectRefreshToken(ctx context.Context, tokenHash string) error {
+	t, err := a.store.GetRefreshToken(ctx, tokenHash)
+	if errors.Is(err, model.ErrNotFound) {
+		return fmt.Errorf("unknown refresh token: %w", model.ErrUnauthenticated)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if t.RevokedAt == nil {
+		if rErr := a.store.RevokeRefreshTokenFamily(ctx, t.FamilyID); rErr != nil {
+			return fmt.Errorf("failed to revoke reused refresh token: %w", rErr)
+		}
+		return fmt.Errorf("refresh token reused: %w", model.ErrUnauthenticated)
+	}
+	return fmt.Errorf("refresh token revoked: %w", model.ErrUnauthenticated)
+}
+
+func (a *Auth) issueTokens(ctx context.Context, u *model.User, familyID string) (*Tokens, error) {
+	accessToken, err := a.CreateToken(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create access token: %w", err)
+	}
+	refreshToken, err := NewOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if iErr := a.store.InsertRefreshToken(ctx, &model.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    u.ID,
+		FamilyID:  familyID,
+		TokenHash: HashToken(refreshToken),
+		ExpiresAt: now.Add(a.config.RefreshTokenTTL),
+		CreatedAt: now,
+	}); iErr != nil {
+		return nil, fmt.Errorf("failed to insert refresh token: %w", iErr)
+	}
+	return &Tokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// NewOpaqueToken generates random url-safe token.
+func NewOpaqueToken() (string, error) {
+	b := make([]byte, opaqueTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns hash under which opaque token is stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
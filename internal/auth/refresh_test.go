@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// fakeRefreshStore keeps refresh tokens the way timestamp without time zone columns do,
+// with wall clock of times and zone dropped.
+type fakeRefreshStore struct {
+	Store
+	tokens map[string]*model.RefreshToken
+}
+
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+func (s *fakeRefreshStore) GetUser(_ context.Context, id string) (*model.User, error) {
+	return &model.User{ID: id, Role: model.EditorRole}, nil
+}
+
+func (s *fakeRefreshStore) InsertRefreshToken(_ context.Context, t *model.RefreshToken) error {
+	stored := *t
+	stored.ExpiresAt, stored.CreatedAt = wallClock(t.ExpiresAt), wallClock(t.CreatedAt)
+	s.tokens[t.TokenHash] = &stored
+	return nil
+}
+
+func (s *fakeRefreshStore) UseRefreshToken(_ context.Context, tokenHash string) (*model.RefreshToken, error) {
+	t, ok := s.tokens[tokenHash]
+	if !ok || t.UsedAt != nil {
+		return nil, model.ErrNotFound
+	}
+	usedAt := wallClock(time.Now())
+	t.UsedAt = &usedAt
+	return t, nil
+}
+
+func TestRefreshTokensExpiry(t *testing.T) {
+	local := time.Local
+	defer func() { time.Local = local }()
+
+	tests := []struct {
+		name    string
+		zone    *time.Location
+		ttl     time.Duration
+		wantErr error
+	}{
+		{name: "valid east of utc", zone: time.FixedZone("east", 3*3600), ttl: time.Hour},
+		{name: "valid west of utc", zone: time.FixedZone("west", -5*3600), ttl: time.Hour},
+		{name: "expired east of utc", zone: time.FixedZone("east", 3*3600), ttl: -time.Minute,
+			wantErr: model.ErrUnauthenticated},
+		{name: "expired west of utc", zone: time.FixedZone("west", -5*3600), ttl: -time.Minute,
+			wantErr: model.ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			time.Local = tt.zone
+			a, err := New(&Config{JWTSecret: "secret", RefreshTokenTTL: tt.ttl}, &fakeRefreshStore{
+				tokens: make(map[string]*model.RefreshToken),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokens, iErr := a.IssueTokens(context.Background(), &model.User{ID: "user", Role: model.EditorRole})
+			if iErr != nil {
+				t.Fatal(iErr)
+			}
+			_, rErr := a.RefreshTokens(context.Background(), tokens.RefreshToken)
+			if tt.wantErr == nil && rErr != nil || tt.wantErr != nil && !errors.Is(rErr, tt.wantErr) {
+				t.Errorf("RefreshTokens() error = %v, want %v", rErr, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// SignOut revokes access token of the request and, if given, the family of refresh token.
+func (a *Auth) SignOut(ctx context.Context, refreshToken string) error {
+	claims, ok := ctx.Value(ClaimsKey).(*Claims)
+	if !ok {
+		return fmt.Errorf("no token claims: %w", model.ErrUnauthenticated)
+	}
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := a.store.RevokeToken(ctx, claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+			return fmt.Errorf("failed to revoke access token: %w", err)
+		}
+	}
+	if refreshToken == "" {
+		return nil
+	}
+
+	t, err := a.store.GetRefreshToken(ctx, HashToken(refreshToken))
+	if errors.Is(err, model.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if t.UserID != claims.UserID {
+		return fmt.Errorf("refresh token belongs to another user: %w", model.ErrForbidden)
+	}
+	if rErr := a.store.RevokeRefreshTokenFamily(ctx, t.FamilyID); rErr != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", rErr)
+	}
+	return nil
+}
+
+// RevokeUserTokens invalidates all tokens issued to the user so far.
+func (a *Auth) RevokeUserTokens(ctx context.Context, userID string) error {
+	if userID == "" {
+		return fmt.Errorf("empty user id: %w", model.ErrInvalidArgument)
+	}
+	if err := a.store.RevokeUserTokens(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	return nil
+}
+
+// RunPruner periodically deletes revocation entries of expired tokens until ctx is done.
+func (a *Auth) RunPruner(ctx context.Context, logger *zap.Logger) {
+	if a.config.PruneInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(a.config.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pruned, err := a.store.PruneExpiredTokens(ctx, time.Now().UTC())
+			if err != nil {
+				logger.Error("failed to prune expired tokens", zap.Error(err))
+				continue
+			}
+			logger.Info("expired tokens pruned", zap.Int64("prunedCount", pruned))
+		}
+	}
+}
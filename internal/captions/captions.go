@@ -0,0 +1,169 @@
+// Package captions renders annotations as caption tracks consumed by players, WebVTT and SubRip.
+package captions
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// Format is a caption track format.
+type Format string
+
+const (
+	// VTTFormat is WebVTT, cues are identified by their numbers followed by titles, links become NOTE blocks
+	// and regions become cue settings.
+	VTTFormat Format = "vtt"
+	// SRTFormat is SubRip, it has neither identifiers nor comments, so titles and links are lines of the cue.
+	SRTFormat Format = "srt"
+)
+
+// ContentType returns media type of the format.
+func (f Format) ContentType() string {
+	if f == VTTFormat {
+		return "text/vtt; charset=utf-8"
+	}
+	return "application/x-subrip; charset=utf-8"
+}
+
+// Render renders annotations ordered by start time as caption track.
+// Annotations without text and empty ones, which end when they start, have no cues.
+func Render(f Format, annotations []*model.Annotation) (string, error) {
+	switch f {
+	case VTTFormat:
+		return renderVTT(annotations), nil
+	case SRTFormat:
+		return renderSRT(annotations), nil
+	default:
+		return "", fmt.Errorf("unknown caption format %q", f)
+	}
+}
+
+func renderVTT(annotations []*model.Annotation) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	n := 0
+	for _, a := range annotations {
+		text := cueText(a)
+		if text == "" || a.EndTime <= a.StartTime {
+			continue
+		}
+		n++
+		b.WriteString("\n")
+		if a.Type == model.LinkAnnotationType && a.URL != "" {
+			fmt.Fprintf(&b, "NOTE link %s\n\n", escapeURL(a.URL))
+		}
+		// the number keeps identifiers unique and titles from being taken for NOTE and other blocks,
+		// identifiers can't contain "-->" and line breaks
+		id := strconv.Itoa(n)
+		if a.Title != "" {
+			id += " " + strings.ReplaceAll(singleLine(a.Title), "-->", "->")
+		}
+		b.WriteString(id + "\n")
+		b.WriteString(timestamp(a.StartTime, '.') + " --> " + timestamp(a.EndTime, '.'))
+		if settings := cueSettings(a.Region); settings != "" {
+			b.WriteString(" " + settings)
+		}
+		b.WriteString("\n" + escapeVTT(text) + "\n")
+	}
+	return b.String()
+}
+
+func renderSRT(annotations []*model.Annotation) string {
+	var b strings.Builder
+	n := 0
+	for _, a := range annotations {
+		if cueText(a) == "" || a.EndTime <= a.StartTime {
+			continue
+		}
+		// arrows would be taken for timing line by lenient parsers
+		var lines []string
+		if a.Title != "" {
+			lines = append(lines, strings.ReplaceAll(singleLine(a.Title), "-->", "->"))
+		}
+		if a.Message != "" {
+			lines = append(lines, strings.ReplaceAll(withoutBlankLines(a.Message), "-->", "->"))
+		}
+		if a.URL != "" && (a.Type == model.LinkAnnotationType || len(lines) == 0) {
+			lines = append(lines, escapeURL(a.URL))
+		}
+		n++
+		if n > 1 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%d\n%s --> %s\n", n, timestamp(a.StartTime, ','), timestamp(a.EndTime, ','))
+		b.WriteString(strings.Join(lines, "\n") + "\n")
+	}
+	return b.String()
+}
+
+// cueText returns text shown by the cue: message, or title of title annotations, or url of links.
+func cueText(a *model.Annotation) string {
+	switch {
+	case a.Message != "":
+		return withoutBlankLines(a.Message)
+	case a.Title != "":
+		return singleLine(a.Title)
+	default:
+		return singleLine(a.URL)
+	}
+}
+
+// timestamp renders HH:MM:SS.mmm, SubRip separates milliseconds with comma.
+func timestamp(d time.Duration, sep byte) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// escapeVTT escapes characters which start tags and entities in cue text, "-->" is escaped with ">".
+func escapeVTT(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// withoutBlankLines drops empty lines, which would end the cue.
+func withoutBlankLines(text string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escapeURL percent-encodes ">" of "-->", which can't appear in comments and cues,
+// valid urls have it encoded anyway.
+func escapeURL(u string) string {
+	return strings.ReplaceAll(singleLine(u), "-->", "--%3E")
+}
+
+func singleLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// cueSettings places the cue at the region where annotation starts, polygons are placed at their bounds.
+func cueSettings(r *model.Region) string {
+	if r == nil || r.Empty() {
+		return ""
+	}
+	box := r.Box
+	if box == nil {
+		minX, minY, maxX, maxY := 1.0, 1.0, 0.0, 0.0
+		for _, p := range r.Polygon {
+			minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+			maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+		}
+		box = &model.Box{X: minX, Y: minY, W: maxX - minX, H: maxY - minY}
+	}
+	return fmt.Sprintf(
+		"position:%s line:%s size:%s align:start", percent(box.X), percent(box.Y), percent(box.W),
+	)
+}
+
+func percent(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10000)/100, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,80 @@
+package captions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// goldenAnnotations cover text which has special meaning in caption formats.
+func goldenAnnotations() []*model.Annotation {
+	return []*model.Annotation{
+		{
+			Type: model.TitleAnnotationType, StartTime: time.Second, EndTime: 2500 * time.Millisecond,
+			Title: "Intro --> part\n1", Message: "Fish & chips\n\n<b>bold</b> a --> b\r\n  \nlast line",
+		},
+		// empty annotations have no cues
+		{Type: model.TextAnnotationType, StartTime: 3 * time.Second, EndTime: 3 * time.Second, Message: "skipped"},
+		{Type: "custom", StartTime: 4 * time.Second, EndTime: 5 * time.Second, Payload: []byte(`{"a":1}`)},
+		{
+			Type: model.LinkAnnotationType, StartTime: 10 * time.Second, EndTime: 12 * time.Second,
+			URL: "https://example.com/docs?a=1&b=2", Message: "See docs",
+		},
+		{
+			Type: model.TextAnnotationType, StartTime: 20 * time.Second, EndTime: 21 * time.Second, Message: "boxed",
+			Region: &model.Region{Shape: model.Shape{Box: &model.Box{X: 0.1, Y: 0.25, W: 0.5, H: 0.1}}},
+		},
+		{
+			Type: model.TextAnnotationType, StartTime: 22 * time.Second, EndTime: 23 * time.Second, Message: "polygon",
+			Region: &model.Region{Shape: model.Shape{
+				Polygon: []model.Point{{X: 0.2, Y: 0.3}, {X: 0.6, Y: 0.3}, {X: 0.4, Y: 0.9}},
+			}},
+		},
+		// titles which would start NOTE block and duplicate ones
+		{
+			Type: model.TitleAnnotationType, StartTime: 30 * time.Second, EndTime: 31 * time.Second,
+			Title: "NOTE\nthis", Message: "first",
+		},
+		{
+			Type: model.TitleAnnotationType, StartTime: 31 * time.Second, EndTime: 32 * time.Second,
+			Title: "NOTE this", Message: "second",
+		},
+		{
+			Type: model.LinkAnnotationType, StartTime: time.Hour + 2*time.Minute + 3004*time.Millisecond,
+			EndTime: 100*time.Hour + 5*time.Second, URL: "https://example.com/a-->b",
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, f := range []Format{VTTFormat, SRTFormat} {
+		t.Run(string(f), func(t *testing.T) {
+			got, err := Render(f, goldenAnnotations())
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", "annotations."+string(f))
+			want, rErr := os.ReadFile(golden)
+			if rErr != nil {
+				t.Fatal(rErr)
+			}
+			if got != string(want) {
+				t.Errorf("Render() differs from %s:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestRenderEmpty(t *testing.T) {
+	for f, want := range map[Format]string{VTTFormat: "WEBVTT\n", SRTFormat: ""} {
+		if got, err := Render(f, nil); err != nil || got != want {
+			t.Errorf("Render(%s) = %q, %v, want %q", f, got, err, want)
+		}
+	}
+	if _, err := Render("ass", nil); err == nil {
+		t.Error("Render() of unknown format succeeded")
+	}
+}
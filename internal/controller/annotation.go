@@ -12,85 +12,276 @@ import (
 
 type ListAnnotationsParams struct {
 	VideoID string `json:"video_id"`
+	Limit   int    `json:"limit"`
+	Cursor  string `json:"cursor"`
+	Sort    string `json:"sort"`
+	Order   string `json:"order"`
+
+	Type          string         `json:"type"`
+	UserID        string         `json:"user_id"`
+	From          *time.Duration `json:"from"`
+	To            *time.Duration `json:"to"`
+	CreatedAfter  *time.Time     `json:"created_after"`
+	CreatedBefore *time.Time     `json:"created_before"`
+}
+
+func (p *ListAnnotationsParams) filter() *model.AnnotationFilter {
+	f := &model.AnnotationFilter{
+		UserID:        p.UserID,
+		From:          p.From,
+		To:            p.To,
+		CreatedAfter:  p.CreatedAfter,
+		CreatedBefore: p.CreatedBefore,
+	}
+	f.Type = model.AnnotationType(p.Type)
+	return f
 }
 
-func (c *Controller) ListAnnotations(ctx context.Context, p *ListAnnotationsParams) ([]*model.Annotation, error) {
+// ListAnnotations returns page of annotations and cursor of the next page, empty for the last one.
+func (c *Controller) ListAnnotations(
+	ctx context.Context, p *ListAnnotationsParams,
+) ([]*model.Annotation, string, error) {
 	if p.VideoID == "" {
+		return nil, "", fmt.Errorf("empty video id: %w", model.ErrInvalidArgument)
+	}
+	page, pErr := toPage(p.Limit, p.Cursor, p.Sort, p.Order)
+	if pErr != nil {
+		return nil, "", pErr
+	}
+	filter := p.filter()
+	if fErr := filter.Validate(); fErr != nil {
+		return nil, "", fErr
+	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return nil, "", wErr
+	}
+	if _, _, aErr := c.requireVideoAccess(ctx, workspaceID, p.VideoID, model.ReadMemberPermission); aErr != nil {
+		return nil, "", aErr
+	}
+	annotations, next, err := c.storage.ListAnnotations(ctx, workspaceID, p.VideoID, filter, page)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list annotations: %w", err)
+	}
+	return annotations, encodeCursor(next), nil
+}
+
+// ListAnnotationsInRange returns all annotations of the video intersecting [from, to] ordered by start time,
+// equal from and to select annotations shown at that moment.
+func (c *Controller) ListAnnotationsInRange(
+	ctx context.Context, videoID string, from, to time.Duration,
+) ([]*model.Annotation, error) {
+	if videoID == "" {
 		return nil, fmt.Errorf("empty video id: %w", model.ErrInvalidArgument)
 	}
-	annotations, err := c.storage.ListAnnotations(ctx, p.VideoID)
+	filter := &model.AnnotationFilter{From: &from, To: &to}
+	if fErr := filter.Validate(); fErr != nil {
+		return nil, fErr
+	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return nil, wErr
+	}
+	if _, _, aErr := c.requireVideoAccess(ctx, workspaceID, videoID, model.ReadMemberPermission); aErr != nil {
+		return nil, aErr
+	}
+	page := &model.Page{Sort: model.Sort{Field: model.StartTimeSortField}}
+	annotations, _, err := c.storage.ListAnnotations(ctx, workspaceID, videoID, filter, page)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list annotations: %w", err)
 	}
 	return annotations, nil
 }
 
-func (c *Controller) CreateAnnotation(ctx context.Context, p *model.CreateAnnotationParams) (string, error) {
+// GetAnnotation returns the annotation together with duration of its video.
+func (c *Controller) GetAnnotation(ctx context.Context, id string) (*model.Annotation, error) {
+	if id == "" {
+		return nil, fmt.Errorf("empty annotation id: %w", model.ErrInvalidArgument)
+	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return nil, wErr
+	}
+	annotation, err := c.storage.GetAnnotationWithDuration(ctx, workspaceID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get annotation: %w", err)
+	}
+	if _, _, aErr := c.requireVideoAccess(
+		ctx, workspaceID, annotation.VideoID, model.ReadMemberPermission,
+	); aErr != nil {
+		return nil, aErr
+	}
+	return annotation, nil
+}
+
+// CreateAnnotation creates the annotation and returns its id with ids of annotations of the same type
+// it overlaps, overlaps are rejected with model.ConflictError or allowed depending on policy of the type.
+func (c *Controller) CreateAnnotation(
+	ctx context.Context, p *model.CreateAnnotationParams,
+) (string, []string, error) {
 	if vErr := p.Validate(); vErr != nil {
-		return "", fmt.Errorf("invalid annotation params: %w", vErr)
+		return "", nil, fmt.Errorf("invalid annotation params: %w", vErr)
+	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return "", nil, wErr
 	}
-	video, vErr := c.storage.GetVideo(ctx, p.VideoID)
+	video, _, vErr := c.requireVideoAccess(ctx, workspaceID, p.VideoID, model.AnnotateMemberPermission)
 	if vErr != nil {
-		return "", fmt.Errorf("failed to get video: %w", vErr)
+		return "", nil, vErr
+	}
+	if aErr := p.Align(video.Rate()); aErr != nil {
+		return "", nil, aErr
+	}
+	if vErr = p.ValidateAligned(); vErr != nil {
+		return "", nil, fmt.Errorf("invalid annotation params: %w", vErr)
+	}
+	if p.Region != nil && p.UsesFrames() {
+		if rErr := p.Region.Validate(p.StartTime, p.EndTime); rErr != nil {
+			return "", nil, fmt.Errorf("invalid region: %w", rErr)
+		}
 	}
 	if video.Duration < p.StartTime {
-		return "", fmt.Errorf("annotation start time exceeds video duration: %w", model.ErrInvalidArgument)
+		return "", nil, fmt.Errorf("annotation start time exceeds video duration: %w", model.ErrInvalidArgument)
 	}
 	if video.Duration < p.EndTime {
-		return "", fmt.Errorf("annotation end time exceeds video duration: %w", model.ErrInvalidArgument)
+		return "", nil, fmt.Errorf("annotation end time exceeds video duration: %w", model.ErrInvalidArgument)
+	}
+	aType, tErr := c.annotationType(ctx, p.Type)
+	if tErr != nil {
+		return "", nil, tErr
+	}
+	if cErr := aType.ValidateContent(p.Content()); cErr != nil {
+		return "", nil, fmt.Errorf("invalid annotation params: %w", cErr)
+	}
+	check, oErr := c.overlapCheck(p.Type, p.StartTime, p.EndTime)
+	if oErr != nil {
+		return "", nil, oErr
 	}
 
 	annotationID := uuid.New()
 	annotation := &model.Annotation{
-		ID:        annotationID,
-		VideoID:   p.VideoID,
-		UserID:    p.UserID,
-		StartTime: p.StartTime,
-		EndTime:   p.EndTime,
-		Type:      p.Type,
-		Message:   p.Message,
-		URL:       p.URL,
-		Title:     p.Title,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-	if err := c.storage.InsertAnnotation(ctx, annotation); err != nil {
-		return "", fmt.Errorf("failed to insert annotation: %w", err)
-	}
-	return annotationID, nil
+		ID:          annotationID,
+		WorkspaceID: workspaceID,
+		VideoID:     p.VideoID,
+		UserID:      p.UserID,
+		StartTime:   p.StartTime,
+		EndTime:     p.EndTime,
+		Type:        p.Type,
+		Message:     p.Message,
+		URL:         p.URL,
+		Title:       p.Title,
+		Payload:     p.Payload,
+		Region:      p.Region,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	overlapping, err := c.storage.InsertAnnotation(ctx, annotation, check)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to insert annotation: %w", err)
+	}
+	return annotationID, overlapping, nil
 }
 
-func (c *Controller) UpdateAnnotation(ctx context.Context, id string, p *model.UpdateAnnotationParams) error {
+// UpdateAnnotation changes the annotation and returns ids of annotations of the same type it overlaps
+// after update, see CreateAnnotation. Overlaps are checked only if the annotation moves,
+// so its content can be fixed in any case.
+func (c *Controller) UpdateAnnotation(
+	ctx context.Context, id string, p *model.UpdateAnnotationParams,
+) ([]string, error) {
 	if p.NoUpdates() {
-		return fmt.Errorf("no updates")
+		return nil, fmt.Errorf("no updates")
 	}
 	if vErr := p.Validate(); vErr != nil {
-		return fmt.Errorf("invalid annotation params: %w", vErr)
+		return nil, fmt.Errorf("invalid annotation params: %w", vErr)
+	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return nil, wErr
 	}
-	annotation, qErr := c.storage.GetAnnotationWithDuration(ctx, id)
+	annotation, qErr := c.storage.GetAnnotationWithDuration(ctx, workspaceID, id)
 	if qErr != nil {
-		return fmt.Errorf("failed to get annotation: %w", qErr)
+		return nil, fmt.Errorf("failed to get annotation: %w", qErr)
+	}
+	userID, aErr := c.annotationScope(ctx, workspaceID, annotation.VideoID)
+	if aErr != nil {
+		return nil, aErr
+	}
+	if p.UsesFrames() {
+		video, gErr := c.storage.GetVideo(ctx, workspaceID, annotation.VideoID)
+		if gErr != nil {
+			return nil, fmt.Errorf("failed to get video: %w", gErr)
+		}
+		if fErr := p.Align(video.Rate()); fErr != nil {
+			return nil, fErr
+		}
+	}
+	typeName := annotation.Type
+	if p.Type != nil {
+		typeName = *p.Type
+	}
+	if sErr := p.ValidateAligned(typeName); sErr != nil {
+		return nil, fmt.Errorf("invalid annotation params: %w", sErr)
+	}
+	if rErr := p.ValidateRegion(annotation); rErr != nil {
+		return nil, rErr
 	}
 	if p.StartTime != nil && annotation.VideoDuration < *p.StartTime {
-		return fmt.Errorf("annotation start time exceeds video duration: %w", model.ErrInvalidArgument)
+		return nil, fmt.Errorf("annotation start time exceeds video duration: %w", model.ErrInvalidArgument)
 	}
 	if p.EndTime != nil && annotation.VideoDuration < *p.EndTime {
-		return fmt.Errorf("annotation end time exceeds video duration: %w", model.ErrInvalidArgument)
+		return nil, fmt.Errorf("annotation end time exceeds video duration: %w", model.ErrInvalidArgument)
 	}
-
-	if err := c.storage.UpdateAnnotation(ctx, id, p); err != nil {
-		return fmt.Errorf("failed to update annotation: %w", err)
+	aType, tErr := c.annotationType(ctx, typeName)
+	if tErr != nil {
+		return nil, tErr
 	}
-	return nil
+	if cErr := aType.ValidateContent(p.Content(annotation)); cErr != nil {
+		return nil, fmt.Errorf("invalid annotation params: %w", cErr)
+	}
+	// the annotation is merged with the update and checked for overlaps by storage, which locks it,
+	// checks above may use its outdated version
+	overlapping, err := c.storage.UpdateAnnotation(
+		ctx, workspaceID, annotation.VideoID, id, userID, p, c.overlapCheck,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update annotation: %w", err)
+	}
+	return overlapping, nil
 }
 
 func (c *Controller) DeleteAnnotation(ctx context.Context, id string) error {
 	if id == "" {
 		return fmt.Errorf("empty annotation id: %w", model.ErrInvalidArgument)
 	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return wErr
+	}
+	annotation, qErr := c.storage.GetAnnotationWithDuration(ctx, workspaceID, id)
+	if qErr != nil {
+		return fmt.Errorf("failed to get annotation: %w", qErr)
+	}
+	userID, aErr := c.annotationScope(ctx, workspaceID, annotation.VideoID)
+	if aErr != nil {
+		return aErr
+	}
 
-	if err := c.storage.DeleteAnnotation(ctx, id); err != nil {
+	if err := c.storage.DeleteAnnotation(ctx, workspaceID, id, userID); err != nil {
 		return fmt.Errorf("failed to delete annotation: %w", err)
 	}
 	return nil
 }
+
+// annotationScope checks that caller may annotate the video and returns id of the user
+// whose annotations caller is allowed to change, empty id means annotations of any user.
+func (c *Controller) annotationScope(ctx context.Context, workspaceID, videoID string) (string, error) {
+	_, access, aErr := c.requireVideoAccess(ctx, workspaceID, videoID, model.AnnotateMemberPermission)
+	if aErr != nil {
+		return "", aErr
+	}
+	if access == model.ManageMemberPermission {
+		return "", nil
+	}
+	return callerID(ctx)
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// newOwnershipStorage returns video of the owner with annotation of the author,
+// the author and the annotator may annotate the video, the reader only reads it.
+func newOwnershipStorage() *fakeStorage {
+	s := newReadAccessStorage()
+	for _, userID := range []string{"author", "annotator"} {
+		s.workspaceMembers[userID] = &model.WorkspaceMember{WorkspaceID: "workspace", UserID: userID}
+		s.videoMembers = append(s.videoMembers, &model.VideoMember{
+			WorkspaceID: "workspace", VideoID: "video", UserID: userID, Permission: model.AnnotateMemberPermission,
+		})
+	}
+	s.annotations = append(s.annotations, &model.Annotation{
+		ID: "note", WorkspaceID: "workspace", VideoID: "video", UserID: "author",
+		Type: model.TextAnnotationType, Message: "hi", StartTime: time.Second, EndTime: 2 * time.Second,
+	})
+	return s
+}
+
+func TestAnnotationOwnership(t *testing.T) {
+	message := "fixed"
+	mutations := map[string]func(ctx context.Context, c *Controller) error{
+		"UpdateAnnotation": func(ctx context.Context, c *Controller) error {
+			_, err := c.UpdateAnnotation(ctx, "note", &model.UpdateAnnotationParams{Message: &message})
+			return err
+		},
+		"DeleteAnnotation": func(ctx context.Context, c *Controller) error {
+			return c.DeleteAnnotation(ctx, "note")
+		},
+	}
+	callers := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{name: "author", ctx: callerContext("author", model.EditorRole, "workspace")},
+		{name: "video owner", ctx: callerContext("owner", model.EditorRole, "workspace")},
+		{name: "admin", ctx: callerContext("admin", model.AdminRole, "workspace")},
+		{
+			// annotators change only their own annotations
+			name:    "other annotator",
+			ctx:     callerContext("annotator", model.EditorRole, "workspace"),
+			wantErr: model.ErrForbidden,
+		},
+		{
+			name:    "video reader",
+			ctx:     callerContext("reader", model.EditorRole, "workspace"),
+			wantErr: model.ErrForbidden,
+		},
+		{
+			name:    "member of other workspace",
+			ctx:     callerContext("outsider", model.EditorRole, "workspace"),
+			wantErr: model.ErrForbidden,
+		},
+		{
+			name:    "annotation of other workspace",
+			ctx:     callerContext("outsider", model.EditorRole, "other"),
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name:    "anonymous",
+			ctx:     callerContext("", model.UnspecifiedRole, "workspace"),
+			wantErr: model.ErrForbidden,
+		},
+	}
+	for name, mutate := range mutations {
+		for _, caller := range callers {
+			t.Run(name+"/"+caller.name, func(t *testing.T) {
+				s := newOwnershipStorage()
+				err := mutate(caller.ctx, New(&Config{}, s))
+				if caller.wantErr == nil && err != nil {
+					t.Fatalf("error = %v, want nil", err)
+				}
+				if !errors.Is(err, caller.wantErr) {
+					t.Fatalf("error = %v, want %v", err, caller.wantErr)
+				}
+				if _, oErr := s.ownedAnnotation("workspace", "note", ""); caller.wantErr != nil && oErr != nil {
+					t.Error("annotation is deleted by caller who isn't allowed to")
+				}
+				if caller.wantErr != nil && s.annotations[len(s.annotations)-1].Message != "hi" {
+					t.Error("annotation is updated by caller who isn't allowed to")
+				}
+			})
+		}
+	}
+}
+
+func TestListAnnotationsInRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    time.Duration
+		to      time.Duration
+		want    []string
+		wantErr error
+	}{
+		{name: "at start", from: time.Second, to: time.Second, want: []string{"chapter", "note"}},
+		{name: "at end", from: 2 * time.Second, to: 2 * time.Second, want: []string{"note"}},
+		{name: "after end", from: 2001 * time.Millisecond, to: 2001 * time.Millisecond},
+		{name: "range", from: 1500 * time.Millisecond, to: time.Minute, want: []string{"note"}},
+		{name: "reversed range", from: 2 * time.Second, to: time.Second, wantErr: model.ErrInvalidArgument},
+		{name: "negative from", from: -time.Second, to: time.Second, wantErr: model.ErrInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations, err := New(&Config{}, newOwnershipStorage()).ListAnnotationsInRange(
+				callerContext("reader", model.ViewerRole, "workspace"), "video", tt.from, tt.to,
+			)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ListAnnotationsInRange() error = %v, want %v", err, tt.wantErr)
+			}
+			var ids []string
+			for _, a := range annotations {
+				ids = append(ids, a.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Errorf("ListAnnotationsInRange() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// ListAnnotationTypes returns built-in types followed by custom ones.
+func (c *Controller) ListAnnotationTypes(ctx context.Context) ([]*model.AnnotationTypeDef, error) {
+	custom, err := c.storage.ListAnnotationTypes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list annotation types: %w", err)
+	}
+	return append(model.BuiltinAnnotationTypes(), custom...), nil
+}
+
+type CreateAnnotationTypeParams struct {
+	Name           string                  `json:"name"`
+	RequiredFields []model.AnnotationField `json:"required_fields"`
+	AllowedFields  []model.AnnotationField `json:"allowed_fields"`
+	PayloadFields  []model.PayloadField    `json:"payload_fields"`
+}
+
+// CreateAnnotationType registers custom type, names of built-in types are reserved.
+func (c *Controller) CreateAnnotationType(ctx context.Context, p *CreateAnnotationTypeParams) error {
+	t := &model.AnnotationTypeDef{
+		Name:           model.AnnotationType(p.Name),
+		RequiredFields: p.RequiredFields,
+		AllowedFields:  p.AllowedFields,
+		PayloadFields:  p.PayloadFields,
+		CreatedAt:      time.Now(),
+	}
+	if vErr := t.Validate(); vErr != nil {
+		return fmt.Errorf("invalid annotation type: %w", vErr)
+	}
+	if model.BuiltinAnnotationType(t.Name) != nil {
+		return fmt.Errorf("type %s is built-in: %w", t.Name, model.ErrAlreadyExists)
+	}
+	if err := c.storage.InsertAnnotationType(ctx, t); err != nil {
+		return fmt.Errorf("failed to insert annotation type: %w", err)
+	}
+	return nil
+}
+
+// DeleteAnnotationType removes custom type which no annotation uses.
+func (c *Controller) DeleteAnnotationType(ctx context.Context, name string) error {
+	if name == "" {
+		return fmt.Errorf("empty type name: %w", model.ErrInvalidArgument)
+	}
+	if model.BuiltinAnnotationType(model.AnnotationType(name)) != nil {
+		return fmt.Errorf("type %s is built-in: %w", name, model.ErrForbidden)
+	}
+	if err := c.storage.DeleteAnnotationType(ctx, model.AnnotationType(name)); err != nil {
+		return fmt.Errorf("failed to delete annotation type: %w", err)
+	}
+	return nil
+}
+
+// annotationType returns definition of the type, unknown type is an invalid argument.
+func (c *Controller) annotationType(ctx context.Context, name model.AnnotationType) (*model.AnnotationTypeDef, error) {
+	if t := model.BuiltinAnnotationType(name); t != nil {
+		return t, nil
+	}
+	t, err := c.storage.GetAnnotationType(ctx, name)
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, fmt.Errorf("unknown type %q: %w", name, model.ErrInvalidArgument)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get annotation type: %w", err)
+	}
+	return t, nil
+}
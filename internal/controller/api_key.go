@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pborman/uuid"
+
+	"github.com/triabokon/gotagv/internal/auth"
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+type CreateAPIKeyParams struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (c *Controller) ListAPIKeys(ctx context.Context) ([]*model.APIKey, error) {
+	userID, cErr := callerID(ctx)
+	if cErr != nil {
+		return nil, cErr
+	}
+
+	keys, err := c.storage.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// CreateAPIKey returns id and the key itself, the key can't be retrieved later.
+func (c *Controller) CreateAPIKey(ctx context.Context, p *CreateAPIKeyParams) (string, string, error) {
+	if p.Name == "" {
+		return "", "", fmt.Errorf("empty api key name: %w", model.ErrInvalidArgument)
+	}
+	for _, s := range p.Scopes {
+		if !auth.Permission(s).Valid() {
+			return "", "", fmt.Errorf("invalid scope %q: %w", s, model.ErrInvalidArgument)
+		}
+	}
+	// times are stored without time zone in UTC
+	now := time.Now().UTC()
+	if p.ExpiresAt != nil && !p.ExpiresAt.After(now) {
+		return "", "", fmt.Errorf("api key expires in the past: %w", model.ErrInvalidArgument)
+	}
+	if p.ExpiresAt != nil {
+		expiresAt := p.ExpiresAt.UTC()
+		p.ExpiresAt = &expiresAt
+	}
+	userID, cErr := callerID(ctx)
+	if cErr != nil {
+		return "", "", cErr
+	}
+	if aErr := forbidAPIKeyCaller(ctx); aErr != nil {
+		return "", "", aErr
+	}
+
+	key, kErr := auth.NewAPIKey()
+	if kErr != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", kErr)
+	}
+	apiKey := &model.APIKey{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      p.Name,
+		KeyHash:   auth.HashToken(key),
+		Scopes:    p.Scopes,
+		ExpiresAt: p.ExpiresAt,
+		CreatedAt: now,
+	}
+	if apiKey.Scopes == nil {
+		apiKey.Scopes = []string{}
+	}
+	if err := c.storage.InsertAPIKey(ctx, apiKey); err != nil {
+		return "", "", fmt.Errorf("failed to insert api key: %w", err)
+	}
+	return apiKey.ID, key, nil
+}
+
+// DeleteAPIKey revokes the key, admins can revoke keys of any user.
+func (c *Controller) DeleteAPIKey(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("empty api key id: %w", model.ErrInvalidArgument)
+	}
+	userID, cErr := ownerScope(ctx)
+	if cErr != nil {
+		return cErr
+	}
+	if aErr := forbidAPIKeyCaller(ctx); aErr != nil {
+		return aErr
+	}
+
+	if err := c.storage.DeleteAPIKey(ctx, id, userID); err != nil {
+		return fmt.Errorf("failed to delete api key: %w", err)
+	}
+	return nil
+}
+
+// forbidAPIKeyCaller doesn't let API keys manage keys, so a key with limited scopes
+// can't be used to create a key without them.
+func forbidAPIKeyCaller(ctx context.Context) error {
+	claims, ok := ctx.Value(auth.ClaimsKey).(*auth.Claims)
+	if ok && claims.APIKeyID != "" {
+		return fmt.Errorf("api keys can't be managed with api key: %w", model.ErrForbidden)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/auth"
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// apiKeyContext is context of the request authenticated with API key of the user.
+func apiKeyContext(userID string, role model.Role) context.Context {
+	ctx := callerContext(userID, role, "workspace")
+	return context.WithValue(ctx, auth.ClaimsKey, &auth.Claims{UserID: userID, Role: role, APIKeyID: "key"})
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	inHour := time.Now().Add(time.Hour).In(time.FixedZone("east", 2*3600))
+	past := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		p       *CreateAPIKeyParams
+		wantErr error
+	}{
+		{
+			name: "without expiry", ctx: callerContext("user", model.EditorRole, "workspace"),
+			p: &CreateAPIKeyParams{Name: "ci"},
+		},
+		{
+			name: "with scopes and expiry", ctx: callerContext("user", model.ViewerRole, "workspace"),
+			p: &CreateAPIKeyParams{Name: "ci", Scopes: []string{"read"}, ExpiresAt: &inHour},
+		},
+		{
+			name: "empty name", ctx: callerContext("user", model.EditorRole, "workspace"),
+			p: &CreateAPIKeyParams{}, wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name: "invalid scope", ctx: callerContext("user", model.EditorRole, "workspace"),
+			p: &CreateAPIKeyParams{Name: "ci", Scopes: []string{"root"}}, wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name: "expired", ctx: callerContext("user", model.EditorRole, "workspace"),
+			p: &CreateAPIKeyParams{Name: "ci", ExpiresAt: &past}, wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name: "with api key", ctx: apiKeyContext("user", model.AdminRole),
+			p: &CreateAPIKeyParams{Name: "ci"}, wantErr: model.ErrForbidden,
+		},
+		{name: "anonymous", ctx: context.Background(), p: &CreateAPIKeyParams{Name: "ci"}, wantErr: model.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeStorage()
+			id, key, err := New(&Config{}, s).CreateAPIKey(tt.ctx, tt.p)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("CreateAPIKey() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			k := s.apiKeys[id]
+			if k == nil || k.UserID != "user" || k.KeyHash != auth.HashToken(key) || k.Scopes == nil {
+				t.Fatalf("stored key = %+v, want key of the user", k)
+			}
+			// expiry is stored without time zone
+			if tt.p.ExpiresAt != nil && (k.ExpiresAt.Location() != time.UTC || !k.ExpiresAt.Equal(inHour)) {
+				t.Errorf("expires at = %v, want %v in UTC", k.ExpiresAt, inHour)
+			}
+			if k.CreatedAt.Location() != time.UTC {
+				t.Errorf("created at = %v, want UTC", k.CreatedAt)
+			}
+		})
+	}
+}
+
+func TestDeleteAPIKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		id      string
+		wantErr error
+	}{
+		{name: "own key", ctx: callerContext("user", model.ViewerRole, "workspace"), id: "key"},
+		{name: "admin", ctx: callerContext("admin", model.AdminRole, "workspace"), id: "key"},
+		{
+			name: "key of other user", ctx: callerContext("other", model.EditorRole, "workspace"), id: "key",
+			wantErr: model.ErrForbidden,
+		},
+		{
+			name: "unknown key", ctx: callerContext("user", model.EditorRole, "workspace"), id: "unknown",
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name: "empty id", ctx: callerContext("user", model.EditorRole, "workspace"),
+			wantErr: model.ErrInvalidArgument,
+		},
+		{name: "with api key", ctx: apiKeyContext("user", model.EditorRole), id: "key", wantErr: model.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeStorage()
+			s.apiKeys["key"] = &model.APIKey{ID: "key", UserID: "user"}
+			err := New(&Config{}, s).DeleteAPIKey(tt.ctx, tt.id)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || s.apiKeys["key"] == nil {
+					t.Errorf("DeleteAPIKey() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || s.apiKeys["key"] != nil {
+				t.Errorf("DeleteAPIKey() error = %v, want deleted key", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// ListChapters returns chapters of the video ordered by start time. With fillGaps the chapters cover
+// the whole video: the first one starts at zero, every chapter ends where the next one starts
+// and the last one ends with the video. Filled chapters aren't stored.
+func (c *Controller) ListChapters(ctx context.Context, videoID string, fillGaps bool) ([]*model.Annotation, error) {
+	if videoID == "" {
+		return nil, fmt.Errorf("empty video id: %w", model.ErrInvalidArgument)
+	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return nil, wErr
+	}
+	video, _, vErr := c.requireVideoAccess(ctx, workspaceID, videoID, model.ReadMemberPermission)
+	if vErr != nil {
+		return nil, vErr
+	}
+	chapters, err := c.listChapters(ctx, workspaceID, videoID)
+	if err != nil {
+		return nil, err
+	}
+	if !fillGaps || len(chapters) == 0 {
+		return chapters, nil
+	}
+	chapters[0].StartTime = 0
+	for i := 0; i < len(chapters)-1; i++ {
+		chapters[i].EndTime = chapters[i+1].StartTime
+	}
+	chapters[len(chapters)-1].EndTime = video.Duration
+	return chapters, nil
+}
+
+// listChapters returns chapters of the video ordered by start time.
+func (c *Controller) listChapters(ctx context.Context, workspaceID, videoID string) ([]*model.Annotation, error) {
+	filter := &model.AnnotationFilter{Type: model.ChapterAnnotationType}
+	page := &model.Page{Sort: model.Sort{Field: model.StartTimeSortField}}
+	chapters, _, err := c.storage.ListAnnotations(ctx, workspaceID, videoID, filter, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chapters: %w", err)
+	}
+	return chapters, nil
+}
+
+// overlapCheck returns the check of annotation overlaps by policy of its type, chapters can't be empty.
+func (c *Controller) overlapCheck(t model.AnnotationType, start, end time.Duration) (*model.OverlapCheck, error) {
+	if t == model.ChapterAnnotationType && end <= start {
+		return nil, fmt.Errorf("chapter should end after it starts: %w", model.ErrInvalidArgument)
+	}
+	return &model.OverlapCheck{Policy: c.config.overlapPolicy(t), Type: t, StartTime: start, EndTime: end}, nil
+}
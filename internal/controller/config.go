@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/triabokon/gotagv/internal/flags"
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+type Config struct {
+	MaxFailedSignIns  int
+	LockoutDuration   time.Duration
+	MinPasswordLength int
+	// OverlapPolicies maps annotation types to policy applied when annotations of the type overlap,
+	// types missing here allow overlaps.
+	OverlapPolicies map[string]string
+}
+
+func (c *Config) Flags(prefix string) *pflag.FlagSet {
+	const name = "ControllerConfig"
+	f := pflag.NewFlagSet(name, pflag.PanicOnError)
+
+	f.IntVar(&c.MaxFailedSignIns, "max_failed_sign_ins", 5, "failed sign in attempts in a row before account is locked")
+	f.DurationVar(&c.LockoutDuration, "lockout_duration", 15*time.Minute, "how long account stays locked")
+	f.IntVar(&c.MinPasswordLength, "min_password_length", 8, "minimal length of user password")
+	f.StringToStringVar(
+		&c.OverlapPolicies, "overlap_policy",
+		map[string]string{string(model.TitleAnnotationType): string(model.RejectOverlapPolicy)},
+		"policy (allow, warn or reject) per annotation type for annotations of the type overlapping on a video",
+	)
+	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
+}
+
+func (c *Config) Validate() error {
+	for t, p := range c.OverlapPolicies {
+		if _, err := model.ToOverlapPolicy(p); err != nil {
+			return fmt.Errorf("invalid overlap policy of %s annotations: %w", t, err)
+		}
+	}
+	return nil
+}
+
+// overlapPolicy returns policy of the annotation type, chapters never overlap.
+func (c *Config) overlapPolicy(t model.AnnotationType) model.OverlapPolicy {
+	if t == model.ChapterAnnotationType {
+		return model.RejectOverlapPolicy
+	}
+	if p, ok := c.OverlapPolicies[string(t)]; ok {
+		return model.OverlapPolicy(p)
+	}
+	return model.AllowOverlapPolicy
+}
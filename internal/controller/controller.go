@@ -2,32 +2,163 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/auth"
 	"github.com/triabokon/gotagv/internal/model"
 )
 
 type Storage interface {
-	GetUser(ctx context.Context, id string) (string, error)
-	InsertUser(ctx context.Context, id string) error
+	GetUser(ctx context.Context, id string) (*model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	GetUserByOIDCSubject(ctx context.Context, issuer, subject string) (*model.User, error)
+	InsertUser(ctx context.Context, u *model.User, w *model.Workspace) error
+	UpdateUserRole(ctx context.Context, id string, role model.Role) error
+	RecordFailedSignIn(ctx context.Context, id string, maxAttempts int, lockUntil time.Time) error
+	ResetFailedSignIns(ctx context.Context, id string) error
+
+	ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error)
+	InsertAPIKey(ctx context.Context, k *model.APIKey) error
+	DeleteAPIKey(ctx context.Context, id, userID string) error
 
-	GetVideo(ctx context.Context, id string) (*model.Video, error)
-	ListVideos(ctx context.Context) ([]*model.Video, error)
+	GetWorkspace(ctx context.Context, id string) (*model.Workspace, error)
+	ListWorkspaces(ctx context.Context, userID string) ([]*model.Workspace, error)
+	InsertWorkspace(ctx context.Context, w *model.Workspace) error
+	GetWorkspaceMember(ctx context.Context, workspaceID, userID string) (*model.WorkspaceMember, error)
+	InsertWorkspaceMember(ctx context.Context, m *model.WorkspaceMember) error
+	DeleteWorkspaceMember(ctx context.Context, workspaceID, userID string) error
+
+	GetVideo(ctx context.Context, workspaceID, id string) (*model.Video, error)
+	ListVideos(
+		ctx context.Context, workspaceID string, f *model.VideoFilter, page *model.Page,
+	) ([]*model.Video, *model.Cursor, error)
 	InsertVideo(ctx context.Context, video *model.Video) error
-	DeleteVideo(ctx context.Context, id string) error
+	UpdateVideo(ctx context.Context, workspaceID, id string, p *model.UpdateVideoParams) error
+	DeleteVideo(ctx context.Context, workspaceID, id, userID string) error
+
+	GetAnnotationWithDuration(ctx context.Context, workspaceID, id string) (*model.Annotation, error)
+	ListAnnotations(
+		ctx context.Context, workspaceID, videoID string, f *model.AnnotationFilter, page *model.Page,
+	) ([]*model.Annotation, *model.Cursor, error)
+	InsertAnnotation(ctx context.Context, a *model.Annotation, check *model.OverlapCheck) ([]string, error)
+	UpdateAnnotation(
+		ctx context.Context, workspaceID, videoID, id, userID string, p *model.UpdateAnnotationParams,
+		overlapCheck model.OverlapCheckFunc,
+	) ([]string, error)
+	DeleteAnnotation(ctx context.Context, workspaceID, id, userID string) error
 
-	GetAnnotationWithDuration(ctx context.Context, id string) (*model.Annotation, error)
-	ListAnnotations(ctx context.Context, videoID string) ([]*model.Annotation, error)
-	InsertAnnotation(ctx context.Context, a *model.Annotation) error
-	UpdateAnnotation(ctx context.Context, id string, p *model.UpdateAnnotationParams) error
-	DeleteAnnotation(ctx context.Context, id string) error
+	ListAnnotationTypes(ctx context.Context) ([]*model.AnnotationTypeDef, error)
+	GetAnnotationType(ctx context.Context, name model.AnnotationType) (*model.AnnotationTypeDef, error)
+	InsertAnnotationType(ctx context.Context, t *model.AnnotationTypeDef) error
+	DeleteAnnotationType(ctx context.Context, name model.AnnotationType) error
+
+	GetVideoMember(ctx context.Context, workspaceID, videoID, userID string) (*model.VideoMember, error)
+	ListVideoMembers(ctx context.Context, workspaceID, videoID string) ([]*model.VideoMember, error)
+	InsertVideoMember(ctx context.Context, m *model.VideoMember) error
+	DeleteVideoMember(ctx context.Context, workspaceID, videoID, userID string) error
 }
 
+// maxPageLimit is the largest page clients may request.
+const maxPageLimit = 1000
+
 type Controller struct {
+	config  *Config
 	storage Storage
 }
 
-func New(s Storage) *Controller {
+func New(config *Config, s Storage) *Controller {
 	return &Controller{
+		config:  config,
 		storage: s,
 	}
 }
+
+// callerID returns id of the authenticated user who issued the request.
+func callerID(ctx context.Context) (string, error) {
+	userID, ok := ctx.Value(auth.UserIDKey).(string)
+	if !ok || userID == "" {
+		return "", fmt.Errorf("no user id in context: %w", model.ErrForbidden)
+	}
+	return userID, nil
+}
+
+// callerRole returns role of the authenticated user who issued the request.
+func callerRole(ctx context.Context) model.Role {
+	role, ok := ctx.Value(auth.RoleKey).(model.Role)
+	if !ok {
+		return model.UnspecifiedRole
+	}
+	return role
+}
+
+// ownerScope returns id of the user whose entities caller is allowed to change,
+// empty id means that caller may change entities of any user.
+func ownerScope(ctx context.Context) (string, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if callerRole(ctx) == model.AdminRole {
+		return "", nil
+	}
+	return userID, nil
+}
+
+// workspaceID returns workspace the request is scoped to,
+// caller has to be a member of the workspace unless caller is admin.
+func (c *Controller) workspaceID(ctx context.Context) (string, error) {
+	workspaceID, ok := ctx.Value(auth.WorkspaceIDKey).(string)
+	if !ok || workspaceID == "" {
+		return "", fmt.Errorf("empty workspace id: %w", model.ErrInvalidArgument)
+	}
+	if callerRole(ctx) == model.AdminRole {
+		return workspaceID, nil
+	}
+	userID, cErr := callerID(ctx)
+	if cErr != nil {
+		return "", cErr
+	}
+
+	_, err := c.storage.GetWorkspaceMember(ctx, workspaceID, userID)
+	if errors.Is(err, model.ErrNotFound) {
+		return "", fmt.Errorf("not a member of workspace %q: %w", workspaceID, model.ErrForbidden)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get workspace member: %w", err)
+	}
+	return workspaceID, nil
+}
+
+// toPage validates page params, empty cursor selects the first page and zero limit selects all items.
+func toPage(limit int, cursor, sortField, order string) (*model.Page, error) {
+	if limit < 0 || limit > maxPageLimit {
+		return nil, fmt.Errorf("limit should be between 0 and %d: %w", maxPageLimit, model.ErrInvalidArgument)
+	}
+	sort, sErr := model.ToSort(sortField, order)
+	if sErr != nil {
+		return nil, sErr
+	}
+	page := &model.Page{Limit: limit, Sort: sort}
+	if cursor != "" {
+		after, err := model.DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		if !after.Matches(sort) {
+			return nil, fmt.Errorf("cursor doesn't match sort: %w", model.ErrInvalidArgument)
+		}
+		page.After = after
+	}
+	return page, nil
+}
+
+// encodeCursor returns opaque cursor of the next page, empty for the last page.
+func encodeCursor(c *model.Cursor) string {
+	if c == nil {
+		return ""
+	}
+	return c.Encode()
+}
@@ -0,0 +1,279 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/triabokon/gotagv/internal/auth"
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// fakeStorage keeps users in memory, methods which tests don't use panic on the nil Storage.
+type fakeStorage struct {
+	Storage
+	users            map[string]*model.User
+	workspaces       map[string]*model.Workspace
+	workspaceMembers map[string]*model.WorkspaceMember
+	videos           map[string]*model.Video
+	videoMembers     []*model.VideoMember
+	annotations      []*model.Annotation
+	apiKeys          map[string]*model.APIKey
+}
+
+func newFakeStorage(users ...*model.User) *fakeStorage {
+	s := &fakeStorage{
+		users:            map[string]*model.User{},
+		workspaces:       map[string]*model.Workspace{},
+		workspaceMembers: map[string]*model.WorkspaceMember{},
+		videos:           map[string]*model.Video{},
+		apiKeys:          map[string]*model.APIKey{},
+	}
+	for _, u := range users {
+		s.users[u.ID] = u
+	}
+	return s
+}
+
+func (s *fakeStorage) GetUser(_ context.Context, id string) (*model.User, error) {
+	if u, ok := s.users[id]; ok {
+		return u, nil
+	}
+	return nil, fmt.Errorf("user %s: %w", id, model.ErrNotFound)
+}
+
+func (s *fakeStorage) GetUserByEmail(_ context.Context, email string) (*model.User, error) {
+	for _, u := range s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("user %s: %w", email, model.ErrNotFound)
+}
+
+func (s *fakeStorage) GetUserByOIDCSubject(_ context.Context, issuer, subject string) (*model.User, error) {
+	for _, u := range s.users {
+		if u.OIDCIssuer == issuer && u.OIDCSubject == subject {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("user %s: %w", subject, model.ErrNotFound)
+}
+
+func (s *fakeStorage) InsertUser(_ context.Context, u *model.User, w *model.Workspace) error {
+	for _, existing := range s.users {
+		if u.Email != "" && existing.Email == u.Email {
+			return fmt.Errorf("user %s: %w", u.Email, model.ErrAlreadyExists)
+		}
+	}
+	s.users[u.ID] = u
+	s.workspaces[w.ID] = w
+	return nil
+}
+
+func (s *fakeStorage) GetWorkspaceMember(
+	_ context.Context, workspaceID, userID string,
+) (*model.WorkspaceMember, error) {
+	if m, ok := s.workspaceMembers[userID]; ok && m.WorkspaceID == workspaceID {
+		return m, nil
+	}
+	return nil, fmt.Errorf("workspace member %s: %w", userID, model.ErrNotFound)
+}
+
+func (s *fakeStorage) GetVideo(_ context.Context, workspaceID, id string) (*model.Video, error) {
+	if v, ok := s.videos[id]; ok && v.WorkspaceID == workspaceID {
+		return v, nil
+	}
+	return nil, fmt.Errorf("video %s: %w", id, model.ErrNotFound)
+}
+
+func (s *fakeStorage) ListVideos(
+	_ context.Context, workspaceID string, _ *model.VideoFilter, _ *model.Page,
+) ([]*model.Video, *model.Cursor, error) {
+	var result []*model.Video
+	for _, v := range s.videos {
+		if v.WorkspaceID == workspaceID {
+			result = append(result, v)
+		}
+	}
+	return result, nil, nil
+}
+
+func (s *fakeStorage) GetVideoMember(_ context.Context, _, videoID, userID string) (*model.VideoMember, error) {
+	for _, m := range s.videoMembers {
+		if m.VideoID == videoID && m.UserID == userID {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("video member %s: %w", userID, model.ErrNotFound)
+}
+
+func (s *fakeStorage) GetAnnotationWithDuration(_ context.Context, workspaceID, id string) (*model.Annotation, error) {
+	for _, a := range s.annotations {
+		if a.ID == id && a.WorkspaceID == workspaceID {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("annotation %s: %w", id, model.ErrNotFound)
+}
+
+func (s *fakeStorage) ListAnnotations(
+	_ context.Context, workspaceID, videoID string, f *model.AnnotationFilter, _ *model.Page,
+) ([]*model.Annotation, *model.Cursor, error) {
+	var result []*model.Annotation
+	for _, a := range s.annotations {
+		if a.WorkspaceID == workspaceID && a.VideoID == videoID && (f.Type == "" || a.Type == f.Type) &&
+			(f.From == nil || a.EndTime >= *f.From) && (f.To == nil || a.StartTime <= *f.To) {
+			result = append(result, a)
+		}
+	}
+	return result, nil, nil
+}
+
+// callerContext returns context of request of the user to the workspace.
+func callerContext(userID string, role model.Role, workspaceID string) context.Context {
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, userID)
+	ctx = context.WithValue(ctx, auth.RoleKey, role)
+	return context.WithValue(ctx, auth.WorkspaceIDKey, workspaceID)
+}
+
+// RecordFailedSignIn keeps lock time the way timestamp without time zone column does,
+// with wall clock of the time and zone dropped.
+func (s *fakeStorage) RecordFailedSignIn(_ context.Context, id string, maxAttempts int, lockUntil time.Time) error {
+	u := s.users[id]
+	u.FailedSignIns++
+	if u.FailedSignIns >= maxAttempts {
+		u.FailedSignIns = 0
+		locked := time.Date(
+			lockUntil.Year(), lockUntil.Month(), lockUntil.Day(),
+			lockUntil.Hour(), lockUntil.Minute(), lockUntil.Second(), lockUntil.Nanosecond(), time.UTC,
+		)
+		u.LockedUntil = &locked
+	}
+	return nil
+}
+
+func (s *fakeStorage) ResetFailedSignIns(_ context.Context, id string) error {
+	s.users[id].FailedSignIns = 0
+	s.users[id].LockedUntil = nil
+	return nil
+}
+
+func (s *fakeStorage) InsertAPIKey(_ context.Context, k *model.APIKey) error {
+	s.apiKeys[k.ID] = k
+	return nil
+}
+
+func (s *fakeStorage) DeleteAPIKey(_ context.Context, id, userID string) error {
+	k, ok := s.apiKeys[id]
+	if !ok {
+		return fmt.Errorf("api key %s: %w", id, model.ErrNotFound)
+	}
+	if userID != "" && k.UserID != userID {
+		return fmt.Errorf("api key %s: %w", id, model.ErrForbidden)
+	}
+	delete(s.apiKeys, id)
+	return nil
+}
+
+// DeleteVideo deletes the video of userID the way storage restricts it in the same statement.
+func (s *fakeStorage) DeleteVideo(_ context.Context, workspaceID, id, userID string) error {
+	v, ok := s.videos[id]
+	if !ok || v.WorkspaceID != workspaceID {
+		return fmt.Errorf("video %s: %w", id, model.ErrNotFound)
+	}
+	if userID != "" && v.UserID != userID {
+		return fmt.Errorf("video %s: %w", id, model.ErrForbidden)
+	}
+	delete(s.videos, id)
+	return nil
+}
+
+// ownedAnnotation returns the annotation unless userID is set and it belongs to someone else.
+func (s *fakeStorage) ownedAnnotation(workspaceID, id, userID string) (int, error) {
+	for i, a := range s.annotations {
+		if a.ID != id || a.WorkspaceID != workspaceID {
+			continue
+		}
+		if userID != "" && a.UserID != userID {
+			return 0, fmt.Errorf("annotation %s: %w", id, model.ErrForbidden)
+		}
+		return i, nil
+	}
+	return 0, fmt.Errorf("annotation %s: %w", id, model.ErrNotFound)
+}
+
+func (s *fakeStorage) UpdateAnnotation(
+	_ context.Context, workspaceID, _, id, userID string, p *model.UpdateAnnotationParams, _ model.OverlapCheckFunc,
+) ([]string, error) {
+	i, err := s.ownedAnnotation(workspaceID, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Message != nil {
+		s.annotations[i].Message = *p.Message
+	}
+	return nil, nil
+}
+
+func (s *fakeStorage) DeleteAnnotation(_ context.Context, workspaceID, id, userID string) error {
+	i, err := s.ownedAnnotation(workspaceID, id, userID)
+	if err != nil {
+		return err
+	}
+	s.annotations = append(s.annotations[:i], s.annotations[i+1:]...)
+	return nil
+}
+
+func (s *fakeStorage) UpdateUserRole(_ context.Context, id string, role model.Role) error {
+	u, ok := s.users[id]
+	if !ok {
+		return fmt.Errorf("user %s: %w", id, model.ErrNotFound)
+	}
+	u.Role = role
+	return nil
+}
+
+func (s *fakeStorage) GetWorkspace(_ context.Context, id string) (*model.Workspace, error) {
+	if w, ok := s.workspaces[id]; ok {
+		return w, nil
+	}
+	return nil, fmt.Errorf("workspace %s: %w", id, model.ErrNotFound)
+}
+
+func (s *fakeStorage) InsertWorkspaceMember(_ context.Context, m *model.WorkspaceMember) error {
+	s.workspaceMembers[m.UserID] = m
+	return nil
+}
+
+func (s *fakeStorage) DeleteWorkspaceMember(_ context.Context, workspaceID, userID string) error {
+	if m, ok := s.workspaceMembers[userID]; !ok || m.WorkspaceID != workspaceID {
+		return fmt.Errorf("workspace member %s: %w", userID, model.ErrNotFound)
+	}
+	delete(s.workspaceMembers, userID)
+	return nil
+}
+
+// UpdateVideo rejects duration shorter than end of annotations of the video the way storage does.
+func (s *fakeStorage) UpdateVideo(_ context.Context, workspaceID, id string, p *model.UpdateVideoParams) error {
+	v, ok := s.videos[id]
+	if !ok || v.WorkspaceID != workspaceID {
+		return fmt.Errorf("video %s: %w", id, model.ErrNotFound)
+	}
+	if p.Duration != nil {
+		var ids []string
+		for _, a := range s.annotations {
+			if a.VideoID == id && a.EndTime > *p.Duration {
+				ids = append(ids, a.ID)
+			}
+		}
+		if len(ids) > 0 {
+			return &model.ConflictError{Reason: "annotations end after the new duration", AnnotationIDs: ids}
+		}
+		v.Duration = *p.Duration
+	}
+	if p.URL != nil {
+		v.URL = *p.URL
+	}
+	return nil
+}
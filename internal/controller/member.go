@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+type AddVideoMemberParams struct {
+	UserID     string                 `json:"user_id"`
+	Permission model.MemberPermission `json:"permission"`
+}
+
+func (p *AddVideoMemberParams) Validate() error {
+	if p.UserID == "" {
+		return fmt.Errorf("empty user id: %w", model.ErrInvalidArgument)
+	}
+	if p.Permission == model.UnspecifiedMemberPermission {
+		return fmt.Errorf("invalid permission: %w", model.ErrInvalidArgument)
+	}
+	return nil
+}
+
+func (c *Controller) ListVideoMembers(ctx context.Context, videoID string) ([]*model.VideoMember, error) {
+	if videoID == "" {
+		return nil, fmt.Errorf("empty video id: %w", model.ErrInvalidArgument)
+	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return nil, wErr
+	}
+	if _, _, aErr := c.requireVideoAccess(ctx, workspaceID, videoID, model.ReadMemberPermission); aErr != nil {
+		return nil, aErr
+	}
+
+	members, err := c.storage.ListVideoMembers(ctx, workspaceID, videoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list video members: %w", err)
+	}
+	return members, nil
+}
+
+func (c *Controller) AddVideoMember(ctx context.Context, videoID string, p *AddVideoMemberParams) error {
+	if videoID == "" {
+		return fmt.Errorf("empty video id: %w", model.ErrInvalidArgument)
+	}
+	if vErr := p.Validate(); vErr != nil {
+		return fmt.Errorf("invalid member params: %w", vErr)
+	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return wErr
+	}
+	video, _, aErr := c.requireVideoAccess(ctx, workspaceID, videoID, model.ManageMemberPermission)
+	if aErr != nil {
+		return aErr
+	}
+	if video.UserID == p.UserID {
+		return fmt.Errorf("video owner can't be added as member: %w", model.ErrInvalidArgument)
+	}
+	_, mErr := c.storage.GetWorkspaceMember(ctx, workspaceID, p.UserID)
+	if errors.Is(mErr, model.ErrNotFound) {
+		return fmt.Errorf("user isn't a member of the workspace: %w", model.ErrInvalidArgument)
+	}
+	if mErr != nil {
+		return fmt.Errorf("failed to get workspace member: %w", mErr)
+	}
+
+	member := &model.VideoMember{
+		WorkspaceID: workspaceID,
+		VideoID:     videoID,
+		UserID:      p.UserID,
+		Permission:  p.Permission,
+		CreatedAt:   time.Now(),
+	}
+	if err := c.storage.InsertVideoMember(ctx, member); err != nil {
+		return fmt.Errorf("failed to add video member: %w", err)
+	}
+	return nil
+}
+
+func (c *Controller) RemoveVideoMember(ctx context.Context, videoID, userID string) error {
+	if videoID == "" {
+		return fmt.Errorf("empty video id: %w", model.ErrInvalidArgument)
+	}
+	if userID == "" {
+		return fmt.Errorf("empty user id: %w", model.ErrInvalidArgument)
+	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return wErr
+	}
+	callerUserID, cErr := callerID(ctx)
+	if cErr != nil {
+		return cErr
+	}
+	// members are always allowed to leave the video
+	if callerUserID != userID {
+		if _, _, aErr := c.requireVideoAccess(ctx, workspaceID, videoID, model.ManageMemberPermission); aErr != nil {
+			return aErr
+		}
+	}
+
+	if err := c.storage.DeleteVideoMember(ctx, workspaceID, videoID, userID); err != nil {
+		return fmt.Errorf("failed to remove video member: %w", err)
+	}
+	return nil
+}
+
+// videoAccess returns permission caller has on the video, admins and video owner have full access to it.
+// Members of the workspace of the video read it unless they are granted more as members of the video,
+// callers are checked to be workspace members by workspaceID.
+func (c *Controller) videoAccess(ctx context.Context, video *model.Video) (model.MemberPermission, error) {
+	userID, cErr := callerID(ctx)
+	if cErr != nil {
+		return model.UnspecifiedMemberPermission, cErr
+	}
+	if callerRole(ctx) == model.AdminRole || video.UserID == userID {
+		return model.ManageMemberPermission, nil
+	}
+
+	member, err := c.storage.GetVideoMember(ctx, video.WorkspaceID, video.ID, userID)
+	if errors.Is(err, model.ErrNotFound) {
+		return model.ReadMemberPermission, nil
+	}
+	if err != nil {
+		return model.UnspecifiedMemberPermission, fmt.Errorf("failed to get video member: %w", err)
+	}
+	return member.Permission, nil
+}
+
+// requireVideoAccess returns the video and caller's permission on it
+// if caller is granted at least permission p.
+func (c *Controller) requireVideoAccess(
+	ctx context.Context, workspaceID, videoID string, p model.MemberPermission,
+) (*model.Video, model.MemberPermission, error) {
+	video, vErr := c.storage.GetVideo(ctx, workspaceID, videoID)
+	if vErr != nil {
+		return nil, model.UnspecifiedMemberPermission, fmt.Errorf("failed to get video: %w", vErr)
+	}
+	access, aErr := c.videoAccess(ctx, video)
+	if aErr != nil {
+		return nil, model.UnspecifiedMemberPermission, aErr
+	}
+	if !access.Includes(p) {
+		return nil, access, fmt.Errorf("%s permission on video is required: %w", p, model.ErrForbidden)
+	}
+	return video, access, nil
+}
@@ -3,29 +3,163 @@ package controller
 import (
 	"context"
 	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/triabokon/gotagv/internal/model"
 )
 
-func (c *Controller) GetUser(ctx context.Context, id string) error {
-	if id == "" {
-		return fmt.Errorf("empty user id: %w", model.ErrInvalidArgument)
+const (
+	personalWorkspaceName = "Personal"
+	// bcrypt ignores everything after 72 bytes of password.
+	maxPasswordLength = 72
+	// dummyPasswordHash is bcrypt hash of default cost compared with passwords of accounts which don't exist
+	// or have no password, so sign in takes as long as with wrong password and doesn't reveal emails.
+	dummyPasswordHash = "$2a$10$zCrBQhTNMI6CCeMJnhMLB.Xf/.bNtPt9N2XmJtfUCWhb2IxKkSoSy"
+)
+
+type SignUpParams struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (c *Controller) SignUp(ctx context.Context, p *SignUpParams) (*model.User, error) {
+	email, err := normalizeEmail(p.Email)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Password) < c.config.MinPasswordLength {
+		return nil, fmt.Errorf(
+			"password is shorter than %d characters: %w", c.config.MinPasswordLength, model.ErrInvalidArgument,
+		)
+	}
+	if len(p.Password) > maxPasswordLength {
+		return nil, fmt.Errorf(
+			"password is longer than %d characters: %w", maxPasswordLength, model.ErrInvalidArgument,
+		)
 	}
 
-	_, err := c.storage.GetUser(ctx, id)
+	hash, hErr := bcrypt.GenerateFromPassword([]byte(p.Password), bcrypt.DefaultCost)
+	if hErr != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", hErr)
+	}
+	return c.createUser(ctx, &model.User{ID: uuid.New(), Email: email, PasswordHash: string(hash)})
+}
+
+type SignInParams struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// SignIn checks credentials of the user. Unknown emails, wrong passwords and locked accounts
+// fail the same way, so responses don't reveal which emails are registered.
+func (c *Controller) SignIn(ctx context.Context, p *SignInParams) (*model.User, error) {
+	email, err := normalizeEmail(p.Email)
 	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+		return nil, fmt.Errorf("invalid credentials: %w", model.ErrUnauthenticated)
 	}
-	return nil
+
+	u, gErr := c.storage.GetUserByEmail(ctx, email)
+	if errors.Is(gErr, model.ErrNotFound) {
+		_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(p.Password))
+		return nil, fmt.Errorf("invalid credentials: %w", model.ErrUnauthenticated)
+	}
+	if gErr != nil {
+		return nil, fmt.Errorf("failed to get user: %w", gErr)
+	}
+	if u.PasswordHash == "" {
+		_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(p.Password))
+		return nil, fmt.Errorf("invalid credentials: %w", model.ErrUnauthenticated)
+	}
+
+	cErr := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(p.Password))
+	// lock time is stored without time zone in UTC
+	now := time.Now().UTC()
+	// even the right password is rejected while account is locked
+	if u.LockedUntil != nil && now.Before(*u.LockedUntil) {
+		return nil, fmt.Errorf("invalid credentials: %w", model.ErrUnauthenticated)
+	}
+	if cErr != nil {
+		if rErr := c.storage.RecordFailedSignIn(
+			ctx, u.ID, c.config.MaxFailedSignIns, now.Add(c.config.LockoutDuration),
+		); rErr != nil {
+			return nil, fmt.Errorf("failed to record failed sign in: %w", rErr)
+		}
+		return nil, fmt.Errorf("invalid credentials: %w", model.ErrUnauthenticated)
+	}
+
+	if u.FailedSignIns > 0 || u.LockedUntil != nil {
+		if rErr := c.storage.ResetFailedSignIns(ctx, u.ID); rErr != nil {
+			return nil, fmt.Errorf("failed to reset failed sign ins: %w", rErr)
+		}
+	}
+	return u, nil
+}
+
+// SignInOIDC returns user signed up with the identity, the user is created on the first sign in.
+func (c *Controller) SignInOIDC(ctx context.Context, identity *model.OIDCIdentity) (*model.User, error) {
+	if identity.Issuer == "" || identity.Subject == "" {
+		return nil, fmt.Errorf("empty oidc issuer or subject: %w", model.ErrInvalidArgument)
+	}
+
+	u, err := c.storage.GetUserByOIDCSubject(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		return u, nil
+	}
+	if !errors.Is(err, model.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	u = &model.User{ID: uuid.New(), OIDCIssuer: identity.Issuer, OIDCSubject: identity.Subject}
+	if identity.Email != "" {
+		// accounts aren't linked by email, so the email of existing account fails the sign up
+		if u.Email, err = normalizeEmail(identity.Email); err != nil {
+			return nil, err
+		}
+	}
+	return c.createUser(ctx, u)
+}
+
+// createUser stores new editor user together with personal workspace.
+func (c *Controller) createUser(ctx context.Context, u *model.User) (*model.User, error) {
+	// every user gets a personal workspace to sign in to
+	w := &model.Workspace{
+		ID:        uuid.New(),
+		Name:      personalWorkspaceName,
+		OwnerID:   u.ID,
+		CreatedAt: time.Now(),
+	}
+	u.Role = model.EditorRole
+	u.WorkspaceID = w.ID
+	if err := c.storage.InsertUser(ctx, u, w); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return u, nil
 }
 
-func (c *Controller) CreateUser(ctx context.Context, id string) error {
+func normalizeEmail(email string) (string, error) {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != strings.TrimSpace(email) {
+		return "", fmt.Errorf("invalid email %q: %w", email, model.ErrInvalidArgument)
+	}
+	return strings.ToLower(addr.Address), nil
+}
+
+func (c *Controller) UpdateUserRole(ctx context.Context, id string, role model.Role) error {
 	if id == "" {
 		return fmt.Errorf("empty user id: %w", model.ErrInvalidArgument)
 	}
+	if role == model.UnspecifiedRole {
+		return fmt.Errorf("invalid role: %w", model.ErrInvalidArgument)
+	}
 
-	if err := c.storage.InsertUser(ctx, id); err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+	if err := c.storage.UpdateUserRole(ctx, id, role); err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,247 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+func TestSignInOIDC(t *testing.T) {
+	const issuer = "https://idp.example.com"
+	existing := &model.User{
+		ID: "existing", Role: model.ViewerRole, WorkspaceID: "workspace",
+		OIDCIssuer: issuer, OIDCSubject: "existing-subject",
+	}
+	taken := &model.User{ID: "taken", Email: "taken@example.com", Role: model.EditorRole, WorkspaceID: "workspace"}
+
+	t.Run("existing user is reused", func(t *testing.T) {
+		s := newFakeStorage(existing, taken)
+		c := New(&Config{}, s)
+		// email of the identity isn't updated, accounts are found by subject
+		u, err := c.SignInOIDC(context.Background(), &model.OIDCIdentity{
+			Issuer: issuer, Subject: "existing-subject", Email: "new@example.com",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u != existing || len(s.users) != 2 || len(s.workspaces) != 0 {
+			t.Errorf("SignInOIDC() = %+v, users %d, workspaces %d, want existing user", u, len(s.users), len(s.workspaces))
+		}
+	})
+
+	t.Run("new user is provisioned", func(t *testing.T) {
+		s := newFakeStorage(existing, taken)
+		c := New(&Config{}, s)
+		u, err := c.SignInOIDC(context.Background(), &model.OIDCIdentity{
+			Issuer: issuer, Subject: "new-subject", Email: "New@Example.com",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.ID == "" || u.Email != "new@example.com" || u.Role != model.EditorRole || u.PasswordHash != "" ||
+			u.OIDCIssuer != issuer || u.OIDCSubject != "new-subject" {
+			t.Errorf("SignInOIDC() = %+v, want new editor with the identity", u)
+		}
+		if s.users[u.ID] != u {
+			t.Errorf("user %s isn't stored", u.ID)
+		}
+		if w := s.workspaces[u.WorkspaceID]; w == nil || w.OwnerID != u.ID {
+			t.Errorf("personal workspace %s isn't stored", u.WorkspaceID)
+		}
+
+		// the second sign in finds the provisioned user
+		again, aErr := c.SignInOIDC(context.Background(), &model.OIDCIdentity{Issuer: issuer, Subject: "new-subject"})
+		if aErr != nil {
+			t.Fatal(aErr)
+		}
+		if again != u || len(s.users) != 3 {
+			t.Errorf("SignInOIDC() = %+v, want provisioned user %s", again, u.ID)
+		}
+	})
+
+	t.Run("same subject of another issuer is a new user", func(t *testing.T) {
+		s := newFakeStorage(existing)
+		u, err := New(&Config{}, s).SignInOIDC(context.Background(), &model.OIDCIdentity{
+			Issuer: "https://other.example.com", Subject: "existing-subject",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.ID == existing.ID || len(s.users) != 2 {
+			t.Errorf("SignInOIDC() = %+v, want new user", u)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		identity *model.OIDCIdentity
+		wantErr  error
+	}{
+		{
+			name:     "email of another account",
+			identity: &model.OIDCIdentity{Issuer: issuer, Subject: "new-subject", Email: "taken@example.com"},
+			wantErr:  model.ErrAlreadyExists,
+		},
+		{
+			name:     "invalid email",
+			identity: &model.OIDCIdentity{Issuer: issuer, Subject: "new-subject", Email: "not an email"},
+			wantErr:  model.ErrInvalidArgument,
+		},
+		{
+			name:     "empty subject",
+			identity: &model.OIDCIdentity{Issuer: issuer},
+			wantErr:  model.ErrInvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeStorage(existing, taken)
+			_, err := New(&Config{}, s).SignInOIDC(context.Background(), tt.identity)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("SignInOIDC() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(s.users) != 2 {
+				t.Errorf("%d users are stored, want 2", len(s.users))
+			}
+		})
+	}
+}
+
+func TestDummyPasswordHash(t *testing.T) {
+	// sign in of unknown email takes as long as of existing one
+	if cost, err := bcrypt.Cost([]byte(dummyPasswordHash)); err != nil || cost != bcrypt.DefaultCost {
+		t.Errorf("cost of dummy hash = %d, %v, want %d", cost, err, bcrypt.DefaultCost)
+	}
+}
+
+func TestSignIn(t *testing.T) {
+	hash, hErr := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if hErr != nil {
+		t.Fatal(hErr)
+	}
+	newStorage := func() *fakeStorage {
+		return newFakeStorage(
+			&model.User{ID: "user", Email: "user@example.com", PasswordHash: string(hash)},
+			&model.User{ID: "oidc", Email: "oidc@example.com", OIDCIssuer: "https://idp.example.com"},
+		)
+	}
+	config := &Config{MaxFailedSignIns: 3, LockoutDuration: time.Minute}
+
+	t.Run("valid credentials", func(t *testing.T) {
+		s := newStorage()
+		s.users["user"].FailedSignIns = 2
+		u, err := New(config, s).SignIn(context.Background(), &SignInParams{Email: "User@example.com", Password: "password"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.ID != "user" || u.FailedSignIns != 0 {
+			t.Errorf("SignIn() = %+v, want user with reset failures", u)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		email string
+	}{
+		{name: "unknown email", email: "unknown@example.com"},
+		{name: "invalid email", email: "not an email"},
+		{name: "account without password", email: "oidc@example.com"},
+		{name: "wrong password", email: "user@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(config, newStorage()).SignIn(
+				context.Background(), &SignInParams{Email: tt.email, Password: "wrong"},
+			)
+			if !errors.Is(err, model.ErrUnauthenticated) || err.Error() != "invalid credentials: unauthenticated" {
+				t.Errorf("SignIn() error = %v, want invalid credentials", err)
+			}
+		})
+	}
+
+	t.Run("locked account", func(t *testing.T) {
+		s := newStorage()
+		c := New(config, s)
+		for i := 0; i < config.MaxFailedSignIns; i++ {
+			_, err := c.SignIn(context.Background(), &SignInParams{Email: "user@example.com", Password: "wrong"})
+			if err == nil || err.Error() != "invalid credentials: unauthenticated" {
+				t.Fatalf("attempt %d: SignIn() error = %v, want invalid credentials", i, err)
+			}
+		}
+		if s.users["user"].LockedUntil == nil {
+			t.Fatal("account isn't locked")
+		}
+		// locked account fails as wrong password even with the right one
+		_, err := c.SignIn(context.Background(), &SignInParams{Email: "user@example.com", Password: "password"})
+		if err == nil || err.Error() != "invalid credentials: unauthenticated" {
+			t.Errorf("SignIn() error = %v, want invalid credentials", err)
+		}
+
+		past := time.Now().Add(-time.Second)
+		s.users["user"].LockedUntil = &past
+		if _, err = c.SignIn(
+			context.Background(), &SignInParams{Email: "user@example.com", Password: "password"},
+		); err != nil || s.users["user"].LockedUntil != nil {
+			t.Errorf("SignIn() error = %v, want sign in after lockout", err)
+		}
+	})
+
+	t.Run("lockout in local time zone", func(t *testing.T) {
+		local := time.Local
+		defer func() { time.Local = local }()
+
+		for _, zone := range []*time.Location{time.FixedZone("east", 3*3600), time.FixedZone("west", -5*3600)} {
+			time.Local = zone
+			s := newStorage()
+			c := New(config, s)
+			for i := 0; i < config.MaxFailedSignIns; i++ {
+				_, _ = c.SignIn(context.Background(), &SignInParams{Email: "user@example.com", Password: "wrong"})
+			}
+			lockedFor := time.Until(*s.users["user"].LockedUntil)
+			if lockedFor <= 0 || lockedFor > config.LockoutDuration {
+				t.Errorf("%s: account locked for %v, want %v", zone, lockedFor, config.LockoutDuration)
+			}
+			if _, err := c.SignIn(
+				context.Background(), &SignInParams{Email: "user@example.com", Password: "password"},
+			); err == nil {
+				t.Errorf("%s: SignIn() of locked account succeeded", zone)
+			}
+		}
+	})
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		role    model.Role
+		wantErr error
+	}{
+		{name: "promote", id: "user", role: model.AdminRole},
+		{name: "demote", id: "user", role: model.ViewerRole},
+		{name: "empty id", role: model.EditorRole, wantErr: model.ErrInvalidArgument},
+		{name: "unknown role", id: "user", role: model.ToRole("root"), wantErr: model.ErrInvalidArgument},
+		{name: "unknown user", id: "missing", role: model.EditorRole, wantErr: model.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeStorage(&model.User{ID: "user", Role: model.EditorRole})
+			err := New(&Config{}, s).UpdateUserRole(context.Background(), tt.id, tt.role)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateUserRole() error = %v, want %v", err, tt.wantErr)
+			}
+			want := tt.role
+			if tt.wantErr != nil {
+				want = model.EditorRole
+			}
+			if got := s.users["user"].Role; got != want {
+				t.Errorf("role = %s, want %s", got, want)
+			}
+		})
+	}
+}
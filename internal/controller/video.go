@@ -8,20 +8,61 @@ import (
 	"github.com/pborman/uuid"
 
 	"github.com/triabokon/gotagv/internal/model"
+	"github.com/triabokon/gotagv/internal/timecode"
 )
 
-func (c *Controller) ListVideos(ctx context.Context) ([]*model.Video, error) {
-	videos, err := c.storage.ListVideos(ctx)
+type ListVideosParams struct {
+	Limit         int        `json:"limit"`
+	Cursor        string     `json:"cursor"`
+	Sort          string     `json:"sort"`
+	Order         string     `json:"order"`
+	CreatedAfter  *time.Time `json:"created_after"`
+	CreatedBefore *time.Time `json:"created_before"`
+}
+
+// ListVideos returns page of videos and cursor of the next page, empty for the last one.
+func (c *Controller) ListVideos(ctx context.Context, p *ListVideosParams) ([]*model.Video, string, error) {
+	page, pErr := toPage(p.Limit, p.Cursor, p.Sort, p.Order)
+	if pErr != nil {
+		return nil, "", pErr
+	}
+	filter := &model.VideoFilter{CreatedAfter: p.CreatedAfter, CreatedBefore: p.CreatedBefore}
+	if fErr := filter.Validate(); fErr != nil {
+		return nil, "", fErr
+	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return nil, "", wErr
+	}
+	videos, next, err := c.storage.ListVideos(ctx, workspaceID, filter, page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list videos: %w", err)
+		return nil, "", fmt.Errorf("failed to list videos: %w", err)
 	}
-	return videos, nil
+	return videos, encodeCursor(next), nil
+}
+
+func (c *Controller) GetVideo(ctx context.Context, id string) (*model.Video, error) {
+	if id == "" {
+		return nil, fmt.Errorf("empty video id: %w", model.ErrInvalidArgument)
+	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return nil, wErr
+	}
+
+	video, _, err := c.requireVideoAccess(ctx, workspaceID, id, model.ReadMemberPermission)
+	if err != nil {
+		return nil, err
+	}
+	return video, nil
 }
 
 type CreateVideoParams struct {
-	UserID   string        `json:"user_id"`
-	URL      string        `json:"url"`
-	Duration time.Duration `json:"duration"`
+	UserID    string        `json:"user_id"`
+	URL       string        `json:"url"`
+	Duration  time.Duration `json:"duration"`
+	FPS       float64       `json:"fps"`
+	DropFrame bool          `json:"drop_frame"`
 }
 
 func (p *CreateVideoParams) Validate() error {
@@ -34,22 +75,29 @@ func (p *CreateVideoParams) Validate() error {
 	if p.Duration <= 0 {
 		return fmt.Errorf("duration should be above 0: %w", model.ErrInvalidArgument)
 	}
-	return nil
+	return model.ValidateRate(timecode.Rate{FPS: p.FPS, DropFrame: p.DropFrame})
 }
 
 func (c *Controller) CreateVideo(ctx context.Context, p *CreateVideoParams) (string, error) {
 	if vErr := p.Validate(); vErr != nil {
 		return "", fmt.Errorf("invalid video params: %w", vErr)
 	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return "", wErr
+	}
 
 	videoID := uuid.New()
 	video := &model.Video{
-		ID:        videoID,
-		UserID:    p.UserID,
-		URL:       p.URL,
-		Duration:  p.Duration,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:          videoID,
+		WorkspaceID: workspaceID,
+		UserID:      p.UserID,
+		URL:         p.URL,
+		Duration:    p.Duration,
+		FPS:         p.FPS,
+		DropFrame:   p.DropFrame,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
 	}
 	if err := c.storage.InsertVideo(ctx, video); err != nil {
 		return "", fmt.Errorf("failed to insert video: %w", err)
@@ -57,12 +105,56 @@ func (c *Controller) CreateVideo(ctx context.Context, p *CreateVideoParams) (str
 	return videoID, nil
 }
 
+// UpdateVideo changes the video, it requires manage permission on the video.
+func (c *Controller) UpdateVideo(ctx context.Context, id string, p *model.UpdateVideoParams) error {
+	if id == "" {
+		return fmt.Errorf("empty video id: %w", model.ErrInvalidArgument)
+	}
+	if p.NoUpdates() {
+		return fmt.Errorf("no updates: %w", model.ErrInvalidArgument)
+	}
+	if vErr := p.Validate(); vErr != nil {
+		return fmt.Errorf("invalid update video params: %w", vErr)
+	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return wErr
+	}
+	video, _, aErr := c.requireVideoAccess(ctx, workspaceID, id, model.ManageMemberPermission)
+	if aErr != nil {
+		return aErr
+	}
+	rate := video.Rate()
+	if p.FPS != nil {
+		rate.FPS = *p.FPS
+	}
+	if p.DropFrame != nil {
+		rate.DropFrame = *p.DropFrame
+	}
+	if rErr := model.ValidateRate(rate); rErr != nil {
+		return fmt.Errorf("invalid update video params: %w", rErr)
+	}
+
+	if err := c.storage.UpdateVideo(ctx, workspaceID, id, p); err != nil {
+		return fmt.Errorf("failed to update video: %w", err)
+	}
+	return nil
+}
+
 func (c *Controller) DeleteVideo(ctx context.Context, id string) error {
 	if id == "" {
 		return fmt.Errorf("empty video id: %w", model.ErrInvalidArgument)
 	}
+	workspaceID, wErr := c.workspaceID(ctx)
+	if wErr != nil {
+		return wErr
+	}
+	userID, cErr := ownerScope(ctx)
+	if cErr != nil {
+		return cErr
+	}
 
-	if err := c.storage.DeleteVideo(ctx, id); err != nil {
+	if err := c.storage.DeleteVideo(ctx, workspaceID, id, userID); err != nil {
 		return fmt.Errorf("failed to delete video: %w", err)
 	}
 	return nil
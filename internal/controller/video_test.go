@@ -0,0 +1,231 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// newReadAccessStorage returns workspace with video of the owner shared with the reader,
+// the viewer and the member belong to the workspace only, the outsider belongs to the other workspace.
+func newReadAccessStorage() *fakeStorage {
+	s := newFakeStorage()
+	for _, userID := range []string{"owner", "reader", "viewer", "member"} {
+		s.workspaceMembers[userID] = &model.WorkspaceMember{WorkspaceID: "workspace", UserID: userID}
+	}
+	s.workspaceMembers["outsider"] = &model.WorkspaceMember{WorkspaceID: "other", UserID: "outsider"}
+	s.videos["video"] = &model.Video{ID: "video", WorkspaceID: "workspace", UserID: "owner", Duration: time.Minute}
+	s.videos["other"] = &model.Video{ID: "other", WorkspaceID: "other", UserID: "outsider", Duration: time.Minute}
+	s.videoMembers = []*model.VideoMember{
+		{WorkspaceID: "workspace", VideoID: "video", UserID: "reader", Permission: model.ReadMemberPermission},
+	}
+	s.annotations = []*model.Annotation{{
+		ID: "chapter", WorkspaceID: "workspace", VideoID: "video", UserID: "owner",
+		Type: model.ChapterAnnotationType, Title: "Intro", EndTime: time.Second,
+	}}
+	return s
+}
+
+func TestReadAccess(t *testing.T) {
+	reads := map[string]func(ctx context.Context, c *Controller) error{
+		"GetVideo": func(ctx context.Context, c *Controller) error {
+			_, err := c.GetVideo(ctx, "video")
+			return err
+		},
+		"ListAnnotations": func(ctx context.Context, c *Controller) error {
+			_, _, err := c.ListAnnotations(ctx, &ListAnnotationsParams{VideoID: "video"})
+			return err
+		},
+		"ListAnnotationsInRange": func(ctx context.Context, c *Controller) error {
+			_, err := c.ListAnnotationsInRange(ctx, "video", 0, time.Second)
+			return err
+		},
+		"GetAnnotation": func(ctx context.Context, c *Controller) error {
+			_, err := c.GetAnnotation(ctx, "chapter")
+			return err
+		},
+		"ListChapters": func(ctx context.Context, c *Controller) error {
+			_, err := c.ListChapters(ctx, "video", true)
+			return err
+		},
+	}
+	callers := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{name: "owner", ctx: callerContext("owner", model.EditorRole, "workspace")},
+		{name: "video member", ctx: callerContext("reader", model.ViewerRole, "workspace")},
+		{name: "admin", ctx: callerContext("admin", model.AdminRole, "workspace")},
+		// workspace members read its videos without being members of them
+		{name: "workspace viewer", ctx: callerContext("viewer", model.ViewerRole, "workspace")},
+		{name: "workspace editor", ctx: callerContext("member", model.EditorRole, "workspace")},
+		{
+			name:    "member of other workspace",
+			ctx:     callerContext("outsider", model.EditorRole, "workspace"),
+			wantErr: model.ErrForbidden,
+		},
+		{
+			name:    "video of other workspace",
+			ctx:     callerContext("owner", model.EditorRole, "other"),
+			wantErr: model.ErrForbidden,
+		},
+	}
+	for name, read := range reads {
+		for _, caller := range callers {
+			t.Run(name+"/"+caller.name, func(t *testing.T) {
+				err := read(caller.ctx, New(&Config{}, newReadAccessStorage()))
+				if caller.wantErr == nil && err != nil {
+					t.Fatalf("error = %v, want nil", err)
+				}
+				if !errors.Is(err, caller.wantErr) {
+					t.Errorf("error = %v, want %v", err, caller.wantErr)
+				}
+			})
+		}
+	}
+}
+
+func TestListVideos(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		want    []string
+		wantErr error
+	}{
+		{name: "workspace viewer", ctx: callerContext("viewer", model.ViewerRole, "workspace"), want: []string{"video"}},
+		{name: "admin", ctx: callerContext("admin", model.AdminRole, "other"), want: []string{"other"}},
+		{
+			name:    "member of other workspace",
+			ctx:     callerContext("outsider", model.EditorRole, "workspace"),
+			wantErr: model.ErrForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			videos, _, err := New(&Config{}, newReadAccessStorage()).ListVideos(tt.ctx, &ListVideosParams{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ListVideos() error = %v, want %v", err, tt.wantErr)
+			}
+			var ids []string
+			for _, v := range videos {
+				ids = append(ids, v.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Errorf("ListVideos() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteVideoOwnership(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{name: "owner", ctx: callerContext("owner", model.EditorRole, "workspace")},
+		{name: "admin", ctx: callerContext("admin", model.AdminRole, "workspace")},
+		{
+			name:    "video member",
+			ctx:     callerContext("reader", model.EditorRole, "workspace"),
+			wantErr: model.ErrForbidden,
+		},
+		{
+			name:    "member of other workspace",
+			ctx:     callerContext("outsider", model.EditorRole, "workspace"),
+			wantErr: model.ErrForbidden,
+		},
+		{
+			name:    "video of other workspace",
+			ctx:     callerContext("outsider", model.EditorRole, "other"),
+			wantErr: model.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newReadAccessStorage()
+			err := New(&Config{}, s).DeleteVideo(tt.ctx, "video")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteVideo() error = %v, want %v", err, tt.wantErr)
+			}
+			if _, ok := s.videos["video"]; ok == (tt.wantErr == nil) {
+				t.Errorf("video kept = %t, want %t", ok, tt.wantErr != nil)
+			}
+		})
+	}
+}
+
+func TestUpdateVideo(t *testing.T) {
+	url := "https://example.com/video.mp4"
+	empty := ""
+	negative := -1.0
+	shorter, tooShort, zero := 30*time.Second, time.Second/2, time.Duration(0)
+	owner := callerContext("owner", model.EditorRole, "workspace")
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		p            *model.UpdateVideoParams
+		wantErr      error
+		wantConflict []string
+	}{
+		{name: "owner", ctx: owner, p: &model.UpdateVideoParams{URL: &url}},
+		{name: "admin", ctx: callerContext("admin", model.AdminRole, "workspace"), p: &model.UpdateVideoParams{URL: &url}},
+		{
+			name: "manager",
+			ctx:  callerContext("manager", model.EditorRole, "workspace"),
+			p:    &model.UpdateVideoParams{Duration: &shorter},
+		},
+		{
+			name:    "video member with read permission",
+			ctx:     callerContext("reader", model.EditorRole, "workspace"),
+			p:       &model.UpdateVideoParams{URL: &url},
+			wantErr: model.ErrForbidden,
+		},
+		{
+			name:    "workspace member",
+			ctx:     callerContext("member", model.EditorRole, "workspace"),
+			p:       &model.UpdateVideoParams{URL: &url},
+			wantErr: model.ErrForbidden,
+		},
+		{name: "no updates", ctx: owner, p: &model.UpdateVideoParams{}, wantErr: model.ErrInvalidArgument},
+		{name: "empty url", ctx: owner, p: &model.UpdateVideoParams{URL: &empty}, wantErr: model.ErrInvalidArgument},
+		{name: "zero duration", ctx: owner, p: &model.UpdateVideoParams{Duration: &zero}, wantErr: model.ErrInvalidArgument},
+		{name: "negative fps", ctx: owner, p: &model.UpdateVideoParams{FPS: &negative}, wantErr: model.ErrInvalidArgument},
+		{
+			name: "duration before end of annotation", ctx: owner, p: &model.UpdateVideoParams{Duration: &tooShort},
+			wantConflict: []string{"chapter"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newReadAccessStorage()
+			s.workspaceMembers["manager"] = &model.WorkspaceMember{WorkspaceID: "workspace", UserID: "manager"}
+			s.videoMembers = append(s.videoMembers, &model.VideoMember{
+				WorkspaceID: "workspace", VideoID: "video", UserID: "manager", Permission: model.ManageMemberPermission,
+			})
+			err := New(&Config{}, s).UpdateVideo(tt.ctx, "video", tt.p)
+			if tt.wantConflict != nil {
+				var conflict *model.ConflictError
+				if !errors.As(err, &conflict) || fmt.Sprint(conflict.AnnotationIDs) != fmt.Sprint(tt.wantConflict) {
+					t.Fatalf("UpdateVideo() error = %v, want conflict with %v", err, tt.wantConflict)
+				}
+				if s.videos["video"].Duration != time.Minute {
+					t.Error("duration is changed despite the conflict")
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateVideo() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && s.videos["video"].URL != "" {
+				t.Error("video is updated by caller who isn't allowed to")
+			}
+		})
+	}
+}
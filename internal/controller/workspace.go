@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pborman/uuid"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+func (c *Controller) ListWorkspaces(ctx context.Context) ([]*model.Workspace, error) {
+	userID, cErr := callerID(ctx)
+	if cErr != nil {
+		return nil, cErr
+	}
+
+	workspaces, err := c.storage.ListWorkspaces(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+	return workspaces, nil
+}
+
+func (c *Controller) CreateWorkspace(ctx context.Context, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("empty workspace name: %w", model.ErrInvalidArgument)
+	}
+	userID, cErr := callerID(ctx)
+	if cErr != nil {
+		return "", cErr
+	}
+
+	workspace := &model.Workspace{
+		ID:        uuid.New(),
+		Name:      name,
+		OwnerID:   userID,
+		CreatedAt: time.Now(),
+	}
+	if err := c.storage.InsertWorkspace(ctx, workspace); err != nil {
+		return "", fmt.Errorf("failed to insert workspace: %w", err)
+	}
+	return workspace.ID, nil
+}
+
+func (c *Controller) AddWorkspaceMember(ctx context.Context, workspaceID, userID string) error {
+	if workspaceID == "" {
+		return fmt.Errorf("empty workspace id: %w", model.ErrInvalidArgument)
+	}
+	if userID == "" {
+		return fmt.Errorf("empty user id: %w", model.ErrInvalidArgument)
+	}
+	if _, wErr := c.requireWorkspaceOwner(ctx, workspaceID); wErr != nil {
+		return wErr
+	}
+
+	member := &model.WorkspaceMember{
+		WorkspaceID: workspaceID,
+		UserID:      userID,
+		CreatedAt:   time.Now(),
+	}
+	if err := c.storage.InsertWorkspaceMember(ctx, member); err != nil {
+		return fmt.Errorf("failed to add workspace member: %w", err)
+	}
+	return nil
+}
+
+func (c *Controller) RemoveWorkspaceMember(ctx context.Context, workspaceID, userID string) error {
+	if workspaceID == "" {
+		return fmt.Errorf("empty workspace id: %w", model.ErrInvalidArgument)
+	}
+	if userID == "" {
+		return fmt.Errorf("empty user id: %w", model.ErrInvalidArgument)
+	}
+	workspace, wErr := c.requireWorkspaceOwner(ctx, workspaceID)
+	if wErr != nil {
+		return wErr
+	}
+	if workspace.OwnerID == userID {
+		return fmt.Errorf("workspace owner can't be removed: %w", model.ErrInvalidArgument)
+	}
+
+	if err := c.storage.DeleteWorkspaceMember(ctx, workspaceID, userID); err != nil {
+		return fmt.Errorf("failed to remove workspace member: %w", err)
+	}
+	return nil
+}
+
+// requireWorkspaceOwner returns the workspace if caller is its owner or admin.
+func (c *Controller) requireWorkspaceOwner(ctx context.Context, workspaceID string) (*model.Workspace, error) {
+	userID, cErr := callerID(ctx)
+	if cErr != nil {
+		return nil, cErr
+	}
+	workspace, err := c.storage.GetWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+	if workspace.OwnerID != userID && callerRole(ctx) != model.AdminRole {
+		return nil, fmt.Errorf("only workspace owner can manage its members: %w", model.ErrForbidden)
+	}
+	return workspace, nil
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// newWorkspaceStorage returns workspace of the owner with the member, the outsider belongs to the other workspace.
+func newWorkspaceStorage() *fakeStorage {
+	s := newReadAccessStorage()
+	s.workspaces["workspace"] = &model.Workspace{ID: "workspace", OwnerID: "owner"}
+	s.workspaces["other"] = &model.Workspace{ID: "other", OwnerID: "outsider"}
+	return s
+}
+
+func TestWorkspaceID(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{name: "member", ctx: callerContext("member", model.ViewerRole, "workspace")},
+		{name: "admin of any workspace", ctx: callerContext("admin", model.AdminRole, "unknown")},
+		{name: "no workspace", ctx: callerContext("member", model.EditorRole, ""), wantErr: model.ErrInvalidArgument},
+		{
+			name:    "member of other workspace",
+			ctx:     callerContext("outsider", model.EditorRole, "workspace"),
+			wantErr: model.ErrForbidden,
+		},
+		{name: "anonymous", ctx: callerContext("", model.ViewerRole, "workspace"), wantErr: model.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&Config{}, newWorkspaceStorage()).workspaceID(tt.ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("workspaceID() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWorkspaceMembers(t *testing.T) {
+	changes := map[string]func(ctx context.Context, c *Controller) error{
+		"AddWorkspaceMember": func(ctx context.Context, c *Controller) error {
+			return c.AddWorkspaceMember(ctx, "workspace", "newcomer")
+		},
+		"RemoveWorkspaceMember": func(ctx context.Context, c *Controller) error {
+			return c.RemoveWorkspaceMember(ctx, "workspace", "member")
+		},
+	}
+	callers := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{name: "owner", ctx: callerContext("owner", model.EditorRole, "workspace")},
+		{name: "admin", ctx: callerContext("admin", model.AdminRole, "other")},
+		{name: "member", ctx: callerContext("member", model.EditorRole, "workspace"), wantErr: model.ErrForbidden},
+		{name: "outsider", ctx: callerContext("outsider", model.EditorRole, "other"), wantErr: model.ErrForbidden},
+	}
+	for name, change := range changes {
+		for _, caller := range callers {
+			t.Run(name+"/"+caller.name, func(t *testing.T) {
+				s := newWorkspaceStorage()
+				err := change(caller.ctx, New(&Config{}, s))
+				if !errors.Is(err, caller.wantErr) {
+					t.Fatalf("error = %v, want %v", err, caller.wantErr)
+				}
+				_, added := s.workspaceMembers["newcomer"]
+				_, kept := s.workspaceMembers["member"]
+				if caller.wantErr != nil && (added || !kept) {
+					t.Error("members are changed by caller who isn't allowed to")
+				}
+			})
+		}
+	}
+}
+
+func TestRemoveWorkspaceOwner(t *testing.T) {
+	err := New(&Config{}, newWorkspaceStorage()).RemoveWorkspaceMember(
+		callerContext("admin", model.AdminRole, "workspace"), "workspace", "owner",
+	)
+	if !errors.Is(err, model.ErrInvalidArgument) {
+		t.Errorf("RemoveWorkspaceMember() error = %v, want %v", err, model.ErrInvalidArgument)
+	}
+}
+
+func TestAddMemberOfUnknownWorkspace(t *testing.T) {
+	err := New(&Config{}, newWorkspaceStorage()).AddWorkspaceMember(
+		callerContext("owner", model.EditorRole, "workspace"), "unknown", "newcomer",
+	)
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("AddWorkspaceMember() error = %v, want %v", err, model.ErrNotFound)
+	}
+}
@@ -0,0 +1,15 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- Table: Rate limit buckets shared by server instances
+CREATE TABLE IF NOT EXISTS rate_limit_buckets
+(
+    key character varying(255) NOT NULL primary key,
+    tokens double precision NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+CREATE INDEX IF NOT EXISTS rate_limit_buckets_updated_at_idx ON rate_limit_buckets (updated_at);
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS rate_limit_buckets CASCADE;
@@ -0,0 +1,11 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- lists are paginated in (updated_at, id) order
+CREATE INDEX IF NOT EXISTS videos_workspace_id_updated_at_idx ON videos (workspace_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS annotations_video_id_updated_at_idx ON annotations (workspace_id, video_id, updated_at, id);
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS annotations_video_id_updated_at_idx;
+DROP INDEX IF EXISTS videos_workspace_id_updated_at_idx;
@@ -0,0 +1,13 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- lists can be sorted by created_at, annotations also by start_time
+CREATE INDEX IF NOT EXISTS videos_workspace_id_created_at_idx ON videos (workspace_id, created_at, id);
+CREATE INDEX IF NOT EXISTS annotations_video_id_created_at_idx ON annotations (workspace_id, video_id, created_at, id);
+CREATE INDEX IF NOT EXISTS annotations_video_id_start_time_idx ON annotations (workspace_id, video_id, start_time, id);
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS annotations_video_id_start_time_idx;
+DROP INDEX IF EXISTS annotations_video_id_created_at_idx;
+DROP INDEX IF EXISTS videos_workspace_id_created_at_idx;
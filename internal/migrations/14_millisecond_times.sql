@@ -0,0 +1,13 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- times are stored in milliseconds instead of whole seconds
+ALTER TABLE videos ALTER COLUMN duration TYPE bigint USING duration::bigint * 1000;
+ALTER TABLE annotations ALTER COLUMN start_time TYPE bigint USING start_time::bigint * 1000;
+ALTER TABLE annotations ALTER COLUMN end_time TYPE bigint USING end_time::bigint * 1000;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE annotations ALTER COLUMN end_time TYPE integer USING end_time / 1000;
+ALTER TABLE annotations ALTER COLUMN start_time TYPE integer USING start_time / 1000;
+ALTER TABLE videos ALTER COLUMN duration TYPE integer USING duration / 1000;
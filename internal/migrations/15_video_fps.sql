@@ -0,0 +1,9 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- frame rate is optional, it's required only for SMPTE timecode
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS fps double precision;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE videos DROP COLUMN IF EXISTS fps;
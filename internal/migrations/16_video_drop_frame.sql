@@ -0,0 +1,9 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- drop-frame timecode of NTSC rates, see timecode.Rate
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS drop_frame boolean NOT NULL DEFAULT false;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE videos DROP COLUMN IF EXISTS drop_frame;
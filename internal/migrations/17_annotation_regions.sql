@@ -0,0 +1,9 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- optional on-screen region of the annotation with keyframes, see model.Region
+ALTER TABLE annotations ADD COLUMN IF NOT EXISTS region jsonb;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE annotations DROP COLUMN IF EXISTS region;
@@ -0,0 +1,24 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- Table: custom annotation types registered by admins, built-in ones are defined in code
+CREATE TABLE IF NOT EXISTS annotation_types
+(
+    name character varying(255) NOT NULL primary key,
+    required_fields character varying(255)[] NOT NULL DEFAULT '{}',
+    allowed_fields character varying(255)[] NOT NULL DEFAULT '{}',
+    payload_fields jsonb,
+    created_at timestamp without time zone NOT NULL DEFAULT now()
+);
+
+-- extra data of the annotation declared by its type
+ALTER TABLE annotations ADD COLUMN IF NOT EXISTS payload jsonb;
+
+-- deleting custom type checks that no annotation across workspaces uses it
+CREATE INDEX IF NOT EXISTS annotations_type_idx ON annotations (type);
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS annotations_type_idx;
+ALTER TABLE annotations DROP COLUMN IF EXISTS payload;
+DROP TABLE IF EXISTS annotation_types CASCADE;
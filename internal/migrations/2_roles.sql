@@ -0,0 +1,9 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- existing users keep being able to create content
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role character varying(255) NOT NULL DEFAULT 'editor';
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,16 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- Table: Video members
+CREATE TABLE IF NOT EXISTS video_members
+(
+    video_id character varying(255) NOT NULL references videos(id) on delete cascade,
+    user_id character varying(255) NOT NULL references users(id) on delete cascade,
+    permission character varying(255) NOT NULL,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    primary key (video_id, user_id)
+);
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS video_members CASCADE;
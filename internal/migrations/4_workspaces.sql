@@ -0,0 +1,51 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- Table: Workspaces
+CREATE TABLE IF NOT EXISTS workspaces
+(
+    id character varying(255) NOT NULL primary key,
+    name character varying(255) NOT NULL,
+    owner_id character varying(255) NOT NULL,
+    created_at timestamp without time zone NOT NULL DEFAULT now()
+);
+
+-- Table: Workspace members
+CREATE TABLE IF NOT EXISTS workspace_members
+(
+    workspace_id character varying(255) NOT NULL references workspaces(id) on delete cascade,
+    user_id character varying(255) NOT NULL references users(id) on delete cascade,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    primary key (workspace_id, user_id)
+);
+
+-- all existing data is moved to the default workspace shared by existing users
+INSERT INTO workspaces (id, name, owner_id) VALUES ('default', 'Default', '') ON CONFLICT DO NOTHING;
+INSERT INTO workspace_members (workspace_id, user_id) SELECT 'default', id FROM users ON CONFLICT DO NOTHING;
+
+-- workspace user signs in to when request doesn't specify one
+ALTER TABLE users ADD COLUMN IF NOT EXISTS workspace_id character varying(255) NOT NULL DEFAULT 'default';
+ALTER TABLE users ALTER COLUMN workspace_id DROP DEFAULT;
+
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS workspace_id character varying(255) NOT NULL DEFAULT 'default'
+    references workspaces(id) on delete cascade;
+ALTER TABLE videos ALTER COLUMN workspace_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS videos_workspace_id_idx ON videos (workspace_id);
+
+ALTER TABLE annotations ADD COLUMN IF NOT EXISTS workspace_id character varying(255) NOT NULL DEFAULT 'default'
+    references workspaces(id) on delete cascade;
+ALTER TABLE annotations ALTER COLUMN workspace_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS annotations_workspace_id_video_id_idx ON annotations (workspace_id, video_id);
+
+ALTER TABLE video_members ADD COLUMN IF NOT EXISTS workspace_id character varying(255) NOT NULL DEFAULT 'default'
+    references workspaces(id) on delete cascade;
+ALTER TABLE video_members ALTER COLUMN workspace_id DROP DEFAULT;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE video_members DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE annotations DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE videos DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE users DROP COLUMN IF EXISTS workspace_id;
+DROP TABLE IF EXISTS workspace_members CASCADE;
+DROP TABLE IF EXISTS workspaces CASCADE;
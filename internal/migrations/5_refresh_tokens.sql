@@ -0,0 +1,20 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- Table: Refresh tokens
+CREATE TABLE IF NOT EXISTS refresh_tokens
+(
+    id character varying(255) NOT NULL primary key,
+    user_id character varying(255) NOT NULL references users(id) on delete cascade,
+    family_id character varying(255) NOT NULL,
+    token_hash character varying(255) NOT NULL unique,
+    expires_at timestamp without time zone NOT NULL,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    used_at timestamp without time zone,
+    revoked_at timestamp without time zone
+);
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS refresh_tokens CASCADE;
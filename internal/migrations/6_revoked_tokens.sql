@@ -0,0 +1,22 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- Table: Revoked tokens
+CREATE TABLE IF NOT EXISTS revoked_tokens
+(
+    jti character varying(255) NOT NULL primary key,
+    user_id character varying(255) NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    revoked_at timestamp without time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS revoked_tokens_expires_at_idx ON revoked_tokens (expires_at);
+CREATE INDEX IF NOT EXISTS refresh_tokens_expires_at_idx ON refresh_tokens (expires_at);
+
+-- tokens issued before this moment are rejected
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_revoked_at timestamp without time zone;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE users DROP COLUMN IF EXISTS tokens_revoked_at;
+DROP INDEX IF EXISTS refresh_tokens_expires_at_idx;
+DROP TABLE IF EXISTS revoked_tokens CASCADE;
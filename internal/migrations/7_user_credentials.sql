@@ -0,0 +1,17 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- users created before credentials were introduced have no email and can't sign in with password
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email character varying(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash character varying(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_sign_ins integer NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until timestamp without time zone;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_idx ON users (email);
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS users_email_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE users DROP COLUMN IF EXISTS failed_sign_ins;
+ALTER TABLE users DROP COLUMN IF EXISTS password_hash;
+ALTER TABLE users DROP COLUMN IF EXISTS email;
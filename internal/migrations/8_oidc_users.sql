@@ -0,0 +1,12 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_issuer character varying(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_subject character varying(255);
+CREATE UNIQUE INDEX IF NOT EXISTS users_oidc_subject_idx ON users (oidc_issuer, oidc_subject);
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS users_oidc_subject_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS oidc_subject;
+ALTER TABLE users DROP COLUMN IF EXISTS oidc_issuer;
@@ -0,0 +1,20 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- Table: API keys
+CREATE TABLE IF NOT EXISTS api_keys
+(
+    id character varying(255) NOT NULL primary key,
+    user_id character varying(255) NOT NULL references users(id) on delete cascade,
+    name character varying(255) NOT NULL,
+    key_hash character varying(255) NOT NULL unique,
+    scopes character varying(255)[] NOT NULL DEFAULT '{}',
+    expires_at timestamp without time zone,
+    last_used_at timestamp without time zone,
+    created_at timestamp without time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS api_keys CASCADE;
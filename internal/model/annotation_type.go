@@ -0,0 +1,272 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// AnnotationField is an optional field of annotation content which types can require or allow.
+type AnnotationField string
+
+const (
+	MessageAnnotationField AnnotationField = "message"
+	URLAnnotationField     AnnotationField = "url"
+	TitleAnnotationField   AnnotationField = "title"
+	PayloadAnnotationField AnnotationField = "payload"
+)
+
+func (f AnnotationField) Valid() bool {
+	switch f {
+	case MessageAnnotationField, URLAnnotationField, TitleAnnotationField, PayloadAnnotationField:
+		return true
+	default:
+		return false
+	}
+}
+
+// PayloadFieldType is JSON type of payload field.
+type PayloadFieldType string
+
+const (
+	StringPayloadFieldType  PayloadFieldType = "string"
+	NumberPayloadFieldType  PayloadFieldType = "number"
+	BooleanPayloadFieldType PayloadFieldType = "boolean"
+	ObjectPayloadFieldType  PayloadFieldType = "object"
+	ArrayPayloadFieldType   PayloadFieldType = "array"
+)
+
+// matches reports whether the JSON value is of the type.
+func (t PayloadFieldType) matches(v interface{}) bool {
+	switch v.(type) {
+	case string:
+		return t == StringPayloadFieldType
+	case float64:
+		return t == NumberPayloadFieldType
+	case bool:
+		return t == BooleanPayloadFieldType
+	case map[string]interface{}:
+		return t == ObjectPayloadFieldType
+	case []interface{}:
+		return t == ArrayPayloadFieldType
+	default:
+		return false
+	}
+}
+
+// PayloadField declares a field of JSON payload of the annotation type.
+type PayloadField struct {
+	Name     string           `json:"name"`
+	Type     PayloadFieldType `json:"type"`
+	Required bool             `json:"required,omitempty"`
+}
+
+// AnnotationContent is the part of annotation validated by its type.
+type AnnotationContent struct {
+	Message string
+	URL     string
+	Title   string
+	Payload json.RawMessage
+}
+
+func (c *AnnotationContent) has(f AnnotationField) bool {
+	switch f {
+	case MessageAnnotationField:
+		return c.Message != ""
+	case URLAnnotationField:
+		return c.URL != ""
+	case TitleAnnotationField:
+		return c.Title != ""
+	case PayloadAnnotationField:
+		return len(c.Payload) > 0 && !bytes.Equal(c.Payload, []byte("null"))
+	default:
+		return false
+	}
+}
+
+// AnnotationTypeDef declares fields annotations of the type require and allow, fields which are
+// neither required nor allowed must be empty. Payload of the type is a JSON object with PayloadFields,
+// built-in types have additional validators in code, custom ones are registered by admins.
+type AnnotationTypeDef struct {
+	Name           AnnotationType    `json:"name"`
+	RequiredFields []AnnotationField `json:"required_fields"`
+	AllowedFields  []AnnotationField `json:"allowed_fields"`
+	PayloadFields  []PayloadField    `json:"payload_fields,omitempty"`
+	Builtin        bool              `json:"builtin"`
+	CreatedAt      time.Time         `json:"created_at,omitempty"`
+
+	validator func(c *AnnotationContent) error
+}
+
+var annotationTypeNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// Validate checks the definition itself.
+func (d *AnnotationTypeDef) Validate() error {
+	if !annotationTypeNameRe.MatchString(string(d.Name)) {
+		return fmt.Errorf("type name should be lowercase letters, digits and underscores: %w", ErrInvalidArgument)
+	}
+	for _, f := range append(append([]AnnotationField{}, d.RequiredFields...), d.AllowedFields...) {
+		if !f.Valid() {
+			return fmt.Errorf("unknown field %q: %w", f, ErrInvalidArgument)
+		}
+	}
+	if len(d.PayloadFields) > 0 && !d.permits(PayloadAnnotationField) {
+		return fmt.Errorf("payload fields are declared, but payload isn't allowed: %w", ErrInvalidArgument)
+	}
+	names := make(map[string]bool, len(d.PayloadFields))
+	for _, f := range d.PayloadFields {
+		if f.Name == "" || names[f.Name] {
+			return fmt.Errorf("payload field names should be unique and not empty: %w", ErrInvalidArgument)
+		}
+		names[f.Name] = true
+		switch f.Type {
+		case StringPayloadFieldType, NumberPayloadFieldType, BooleanPayloadFieldType,
+			ObjectPayloadFieldType, ArrayPayloadFieldType:
+		default:
+			return fmt.Errorf("unknown type %q of payload field %q: %w", f.Type, f.Name, ErrInvalidArgument)
+		}
+	}
+	return nil
+}
+
+func (d *AnnotationTypeDef) permits(f AnnotationField) bool {
+	for _, allowed := range append(append([]AnnotationField{}, d.RequiredFields...), d.AllowedFields...) {
+		if allowed == f {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateContent checks content of annotation of the type.
+func (d *AnnotationTypeDef) ValidateContent(c *AnnotationContent) error {
+	for _, f := range d.RequiredFields {
+		if !c.has(f) {
+			return fmt.Errorf("%s annotation requires %s: %w", d.Name, f, ErrInvalidArgument)
+		}
+	}
+	for _, f := range []AnnotationField{
+		MessageAnnotationField, URLAnnotationField, TitleAnnotationField, PayloadAnnotationField,
+	} {
+		if c.has(f) && !d.permits(f) {
+			return fmt.Errorf("%s annotation can't have %s: %w", d.Name, f, ErrInvalidArgument)
+		}
+	}
+	if c.has(PayloadAnnotationField) {
+		if err := d.validatePayload(c.Payload); err != nil {
+			return err
+		}
+	}
+	if d.validator != nil {
+		return d.validator(c)
+	}
+	return nil
+}
+
+// validatePayload checks that payload is an object of declared fields, when no fields are declared
+// any object is accepted.
+func (d *AnnotationTypeDef) validatePayload(payload json.RawMessage) error {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(payload, &fields); err != nil || fields == nil {
+		return fmt.Errorf("payload should be a JSON object: %w", ErrInvalidArgument)
+	}
+	if len(d.PayloadFields) == 0 {
+		return nil
+	}
+	declared := make(map[string]bool, len(d.PayloadFields))
+	for _, f := range d.PayloadFields {
+		declared[f.Name] = true
+		v, ok := fields[f.Name]
+		if !ok || v == nil {
+			if f.Required {
+				return fmt.Errorf("payload requires %q: %w", f.Name, ErrInvalidArgument)
+			}
+			continue
+		}
+		if !f.Type.matches(v) {
+			return fmt.Errorf("payload field %q should be %s: %w", f.Name, f.Type, ErrInvalidArgument)
+		}
+	}
+	for name := range fields {
+		if !declared[name] {
+			return fmt.Errorf("unknown payload field %q: %w", name, ErrInvalidArgument)
+		}
+	}
+	return nil
+}
+
+// BuiltinAnnotationTypes returns types defined in code, they can't be changed or removed.
+func BuiltinAnnotationTypes() []*AnnotationTypeDef {
+	all := []AnnotationField{MessageAnnotationField, URLAnnotationField, TitleAnnotationField}
+	types := []*AnnotationTypeDef{
+		{Name: TextAnnotationType, RequiredFields: []AnnotationField{MessageAnnotationField}, AllowedFields: all},
+		{Name: CommentaryAnnotationType, RequiredFields: []AnnotationField{MessageAnnotationField}, AllowedFields: all},
+		{
+			Name:           LinkAnnotationType,
+			RequiredFields: []AnnotationField{URLAnnotationField},
+			AllowedFields:  all,
+			validator:      validateLink,
+		},
+		{Name: TitleAnnotationType, RequiredFields: []AnnotationField{TitleAnnotationField}, AllowedFields: all},
+		{
+			Name:           ChapterAnnotationType,
+			RequiredFields: []AnnotationField{TitleAnnotationField},
+			AllowedFields:  []AnnotationField{MessageAnnotationField},
+		},
+	}
+	for _, t := range types {
+		t.Builtin = true
+	}
+	return types
+}
+
+// BuiltinAnnotationType returns the built-in type with the name or nil.
+func BuiltinAnnotationType(name AnnotationType) *AnnotationTypeDef {
+	for _, t := range BuiltinAnnotationTypes() {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func validateLink(c *AnnotationContent) error {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("link annotation requires absolute http url: %w", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// OverlapPolicy decides what happens when annotation overlaps annotations of the same type on the video.
+type OverlapPolicy string
+
+const (
+	AllowOverlapPolicy  OverlapPolicy = "allow"
+	WarnOverlapPolicy   OverlapPolicy = "warn"
+	RejectOverlapPolicy OverlapPolicy = "reject"
+)
+
+func ToOverlapPolicy(p string) (OverlapPolicy, error) {
+	switch OverlapPolicy(p) {
+	case AllowOverlapPolicy, WarnOverlapPolicy, RejectOverlapPolicy:
+		return OverlapPolicy(p), nil
+	default:
+		return "", fmt.Errorf("unknown overlap policy %q: %w", p, ErrInvalidArgument)
+	}
+}
+
+// OverlapCheck is the annotation checked against annotations of the same type on the video,
+// annotations overlap if one starts before the other ends, so adjacent ones don't.
+type OverlapCheck struct {
+	Policy    OverlapPolicy
+	Type      AnnotationType
+	StartTime time.Duration
+	EndTime   time.Duration
+}
+
+// OverlapCheckFunc returns the overlap check of annotation of type t from start to end.
+type OverlapCheckFunc func(t AnnotationType, start, end time.Duration) (*OverlapCheck, error)
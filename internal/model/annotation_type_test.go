@@ -0,0 +1,104 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestValidateContent(t *testing.T) {
+	custom := &AnnotationTypeDef{
+		Name:           "quiz",
+		RequiredFields: []AnnotationField{TitleAnnotationField},
+		AllowedFields:  []AnnotationField{PayloadAnnotationField},
+		PayloadFields: []PayloadField{
+			{Name: "question", Type: StringPayloadFieldType, Required: true},
+			{Name: "points", Type: NumberPayloadFieldType},
+		},
+	}
+	free := &AnnotationTypeDef{Name: "note", AllowedFields: []AnnotationField{PayloadAnnotationField}}
+	tests := []struct {
+		name    string
+		t       *AnnotationTypeDef
+		c       AnnotationContent
+		wantErr bool
+	}{
+		{name: "text", t: BuiltinAnnotationType(TextAnnotationType), c: AnnotationContent{Message: "hi"}},
+		{name: "text without message", t: BuiltinAnnotationType(TextAnnotationType), c: AnnotationContent{}, wantErr: true},
+		{
+			name: "text with payload", t: BuiltinAnnotationType(TextAnnotationType),
+			c: AnnotationContent{Message: "hi", Payload: json.RawMessage(`{}`)}, wantErr: true,
+		},
+		{name: "commentary", t: BuiltinAnnotationType(CommentaryAnnotationType), c: AnnotationContent{Message: "hi"}},
+		{
+			name: "commentary without message", t: BuiltinAnnotationType(CommentaryAnnotationType),
+			c: AnnotationContent{Title: "hi"}, wantErr: true,
+		},
+		{name: "link", t: BuiltinAnnotationType(LinkAnnotationType), c: AnnotationContent{URL: "https://example.com/a"}},
+		{
+			name: "relative link", t: BuiltinAnnotationType(LinkAnnotationType),
+			c: AnnotationContent{URL: "/a"}, wantErr: true,
+		},
+		{
+			name: "javascript link", t: BuiltinAnnotationType(LinkAnnotationType),
+			c: AnnotationContent{URL: "javascript:alert(1)"}, wantErr: true,
+		},
+		{name: "link without url", t: BuiltinAnnotationType(LinkAnnotationType), wantErr: true},
+		{name: "title", t: BuiltinAnnotationType(TitleAnnotationType), c: AnnotationContent{Title: "Intro"}},
+		{name: "title without title", t: BuiltinAnnotationType(TitleAnnotationType), wantErr: true},
+		{
+			name: "chapter", t: BuiltinAnnotationType(ChapterAnnotationType),
+			c: AnnotationContent{Title: "Intro", Message: "about"},
+		},
+		{
+			name: "chapter with url", t: BuiltinAnnotationType(ChapterAnnotationType),
+			c: AnnotationContent{Title: "Intro", URL: "https://example.com"}, wantErr: true,
+		},
+		{
+			name: "custom", t: custom,
+			c: AnnotationContent{Title: "Q1", Payload: json.RawMessage(`{"question":"why?","points":2}`)},
+		},
+		{
+			name: "custom without optional payload field", t: custom,
+			c: AnnotationContent{Title: "Q1", Payload: json.RawMessage(`{"question":"why?"}`)},
+		},
+		{
+			name: "custom without required payload field", t: custom,
+			c: AnnotationContent{Title: "Q1", Payload: json.RawMessage(`{"points":2}`)}, wantErr: true,
+		},
+		{
+			name: "custom with payload field of wrong type", t: custom,
+			c: AnnotationContent{Title: "Q1", Payload: json.RawMessage(`{"question":"why?","points":"2"}`)}, wantErr: true,
+		},
+		{
+			name: "custom with unknown payload field", t: custom,
+			c: AnnotationContent{Title: "Q1", Payload: json.RawMessage(`{"question":"why?","answer":"no"}`)}, wantErr: true,
+		},
+		{
+			name: "custom with field not allowed", t: custom,
+			c: AnnotationContent{Title: "Q1", Message: "hi", Payload: json.RawMessage(`{"question":"why?"}`)}, wantErr: true,
+		},
+		{
+			name: "custom without required field", t: custom,
+			c: AnnotationContent{Payload: json.RawMessage(`{"question":"why?"}`)}, wantErr: true,
+		},
+		{name: "free payload", t: free, c: AnnotationContent{Payload: json.RawMessage(`{"any":[1,2]}`)}},
+		{name: "null payload", t: free, c: AnnotationContent{Payload: json.RawMessage(`null`)}},
+		{name: "array payload", t: free, c: AnnotationContent{Payload: json.RawMessage(`[1]`)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.t.ValidateContent(&tt.c)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidArgument) {
+					t.Errorf("ValidateContent() error = %v, want %v", err, ErrInvalidArgument)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ValidateContent() error = %v", err)
+			}
+		})
+	}
+}
@@ -7,4 +7,22 @@ var (
 
 	ErrNotFound      = fmt.Errorf("entity not found")
 	ErrAlreadyExists = fmt.Errorf("entity already exists")
+	ErrConflict      = fmt.Errorf("conflict")
+
+	ErrUnauthenticated = fmt.Errorf("unauthenticated")
+	ErrForbidden       = fmt.Errorf("forbidden")
 )
+
+// ConflictError is returned when change would break annotations listed in AnnotationIDs.
+type ConflictError struct {
+	Reason        string
+	AnnotationIDs []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: %d annotations conflict", e.Reason, len(e.AnnotationIDs))
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
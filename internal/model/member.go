@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+type MemberPermission string
+
+const (
+	UnspecifiedMemberPermission MemberPermission = "unspecified"
+	ReadMemberPermission        MemberPermission = "read"
+	AnnotateMemberPermission    MemberPermission = "annotate"
+	ManageMemberPermission      MemberPermission = "manage"
+)
+
+type VideoMember struct {
+	WorkspaceID string           `json:"workspace_id"`
+	VideoID     string           `json:"video_id"`
+	UserID      string           `json:"user_id"`
+	Permission  MemberPermission `json:"permission"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// Includes reports whether permission p grants everything that other does,
+// permissions are ordered as read < annotate < manage.
+func (p MemberPermission) Includes(other MemberPermission) bool {
+	return p.level() >= other.level()
+}
+
+func (p MemberPermission) level() int {
+	switch p {
+	case ReadMemberPermission:
+		return 1
+	case AnnotateMemberPermission:
+		return 2
+	case ManageMemberPermission:
+		return 3
+	default:
+		return 0
+	}
+}
+
+func ToMemberPermission(p string) MemberPermission {
+	switch MemberPermission(p) {
+	case ReadMemberPermission, AnnotateMemberPermission, ManageMemberPermission:
+		return MemberPermission(p)
+	default:
+		return UnspecifiedMemberPermission
+	}
+}
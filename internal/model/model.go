@@ -1,53 +1,190 @@
 package model
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/triabokon/gotagv/internal/timecode"
 )
 
 type AnnotationType string
 
 const (
-	UnspecifiedAnnotationType AnnotationType = "unspecified"
-	TextAnnotationType        AnnotationType = "text"
-	CommentaryAnnotationType  AnnotationType = "commentary"
-	LinkAnnotationType        AnnotationType = "link"
-	TitleAnnotationType       AnnotationType = "title"
+	TextAnnotationType       AnnotationType = "text"
+	CommentaryAnnotationType AnnotationType = "commentary"
+	LinkAnnotationType       AnnotationType = "link"
+	TitleAnnotationType      AnnotationType = "title"
+	// ChapterAnnotationType splits the video into chapters, chapters of the video don't overlap.
+	ChapterAnnotationType AnnotationType = "chapter"
 )
 
+// StartsAtZero reports whether annotations of the type may start at the very beginning of the video,
+// the first chapter always does.
+func (t AnnotationType) StartsAtZero() bool {
+	return t == ChapterAnnotationType
+}
+
 type Video struct {
-	ID        string        `json:"id"`
-	UserID    string        `json:"user_id"`
-	URL       string        `json:"url"`
-	Duration  time.Duration `json:"duration"`
-	CreatedAt time.Time     `json:"created_at"`
-	UpdatedAt time.Time     `json:"updated_at"`
+	ID          string        `json:"id"`
+	WorkspaceID string        `json:"workspace_id"`
+	UserID      string        `json:"user_id"`
+	URL         string        `json:"url"`
+	Duration    time.Duration `json:"duration"`
+	FPS         float64       `json:"fps,omitempty"`
+	DropFrame   bool          `json:"drop_frame,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// MaxFPS limits frame rate of videos.
+const MaxFPS = 1000
+
+// Rate returns frame rate of the video.
+func (v *Video) Rate() timecode.Rate {
+	return timecode.Rate{FPS: v.FPS, DropFrame: v.DropFrame}
+}
+
+// ValidateRate checks the frame rate, it's shared by create and update.
+func ValidateRate(rate timecode.Rate) error {
+	if rate.FPS > MaxFPS {
+		return fmt.Errorf("fps should be less than %d: %w", MaxFPS, ErrInvalidArgument)
+	}
+	if err := rate.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", err, ErrInvalidArgument)
+	}
+	return nil
+}
+
+type UpdateVideoParams struct {
+	URL      *string        `json:"url,omitempty"`
+	Duration *time.Duration `json:"duration,omitempty"`
+	// FPS equal to zero clears frame rate of the video.
+	FPS       *float64 `json:"fps,omitempty"`
+	DropFrame *bool    `json:"drop_frame,omitempty"`
+}
+
+func (p *UpdateVideoParams) NoUpdates() bool {
+	return p.URL == nil && p.Duration == nil && p.FPS == nil && p.DropFrame == nil
+}
+
+func (p *UpdateVideoParams) Validate() error {
+	if p.URL != nil && *p.URL == "" {
+		return fmt.Errorf("empty url: %w", ErrInvalidArgument)
+	}
+	if p.Duration != nil && *p.Duration <= 0 {
+		return fmt.Errorf("duration should be above 0: %w", ErrInvalidArgument)
+	}
+	if p.FPS != nil && *p.FPS < 0 {
+		return fmt.Errorf("fps should not be negative: %w", ErrInvalidArgument)
+	}
+	return nil
 }
 
 type Annotation struct {
-	ID            string         `json:"id"`
-	VideoID       string         `json:"video_id"`
-	UserID        string         `json:"user_id"`
-	StartTime     time.Duration  `json:"start_time"`
-	EndTime       time.Duration  `json:"end_time"`
-	Type          AnnotationType `json:"type"`
-	Message       string         `json:"message,omitempty"`
-	URL           string         `json:"url,omitempty"`
-	Title         string         `json:"title,omitempty"`
-	VideoDuration time.Duration  `json:"video_duration,omitempty"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
+	ID          string         `json:"id"`
+	WorkspaceID string         `json:"workspace_id"`
+	VideoID     string         `json:"video_id"`
+	UserID      string         `json:"user_id"`
+	StartTime   time.Duration  `json:"start_time"`
+	EndTime     time.Duration  `json:"end_time"`
+	Type        AnnotationType `json:"type"`
+	Message     string         `json:"message,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Title       string         `json:"title,omitempty"`
+	// Payload is extra data of the type, a JSON object.
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	Region        *Region         `json:"region,omitempty"`
+	VideoDuration time.Duration   `json:"video_duration,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
 }
 
 type CreateAnnotationParams struct {
-	VideoID   string         `json:"video_id"`
-	UserID    string         `json:"user_id"`
-	StartTime time.Duration  `json:"start_time"`
-	EndTime   time.Duration  `json:"end_time"`
-	Type      AnnotationType `json:"type"`
-	Message   string         `json:"message"`
-	URL       string         `json:"url"`
-	Title     string         `json:"title"`
+	VideoID   string          `json:"video_id"`
+	UserID    string          `json:"user_id"`
+	StartTime time.Duration   `json:"start_time"`
+	EndTime   time.Duration   `json:"end_time"`
+	Type      AnnotationType  `json:"type"`
+	Message   string          `json:"message"`
+	URL       string          `json:"url"`
+	Title     string          `json:"title"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Region    *Region         `json:"region,omitempty"`
+
+	FrameParams
+}
+
+// FrameParams aligns annotation to frames of the video, start and end can be given as frame numbers
+// instead of times, and times can be moved to the start of their frames.
+type FrameParams struct {
+	StartFrame   *int64 `json:"start_frame,omitempty"`
+	EndFrame     *int64 `json:"end_frame,omitempty"`
+	SnapToFrames bool   `json:"snap_to_frames,omitempty"`
+}
+
+// UsesFrames reports whether frame rate of the video is required.
+func (p *FrameParams) UsesFrames() bool {
+	return p.StartFrame != nil || p.EndFrame != nil || p.SnapToFrames
+}
+
+func (p *FrameParams) Validate() error {
+	if p.StartFrame != nil && *p.StartFrame < 0 {
+		return fmt.Errorf("start frame should not be negative: %w", ErrInvalidArgument)
+	}
+	if p.EndFrame != nil && *p.EndFrame < 0 {
+		return fmt.Errorf("end frame should not be negative: %w", ErrInvalidArgument)
+	}
+	if p.StartFrame != nil && p.EndFrame != nil && *p.EndFrame < *p.StartFrame {
+		return fmt.Errorf("start frame should be less or equal than end frame: %w", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// alignTime converts frame number into time or snaps given time to its frame if requested.
+func (p *FrameParams) alignTime(t *time.Duration, frame *int64, rate timecode.Rate) (*time.Duration, error) {
+	if frame == nil && (t == nil || !p.SnapToFrames) {
+		return t, nil
+	}
+	var number int64
+	if frame != nil {
+		number = *frame
+	} else {
+		number = timecode.DurationToFrames(*t, rate)
+	}
+	// start of the frame may overflow for the largest times
+	if !timecode.FramesFit(number, rate) {
+		return nil, fmt.Errorf("frame %d is out of range: %w", number, ErrInvalidArgument)
+	}
+	d := timecode.FramesToDuration(number, rate)
+	return &d, nil
+}
+
+func requireRate(rate timecode.Rate) error {
+	if !rate.Known() {
+		return fmt.Errorf("video has no frame rate: %w", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// Align sets times from frame numbers and snaps them to frames of the video if requested.
+func (p *CreateAnnotationParams) Align(rate timecode.Rate) error {
+	if !p.UsesFrames() {
+		return nil
+	}
+	if err := requireRate(rate); err != nil {
+		return err
+	}
+	start, sErr := p.alignTime(&p.StartTime, p.StartFrame, rate)
+	if sErr != nil {
+		return sErr
+	}
+	end, eErr := p.alignTime(&p.EndTime, p.EndFrame, rate)
+	if eErr != nil {
+		return eErr
+	}
+	p.StartTime, p.EndTime = *start, *end
+	return nil
 }
 
 func (p *CreateAnnotationParams) Validate() error {
@@ -57,7 +194,34 @@ func (p *CreateAnnotationParams) Validate() error {
 	if p.VideoID == "" {
 		return fmt.Errorf("empty video id: %w", ErrInvalidArgument)
 	}
-	if p.StartTime <= 0 {
+	if fErr := p.FrameParams.Validate(); fErr != nil {
+		return fErr
+	}
+	if p.StartFrame != nil && p.StartTime != 0 || p.EndFrame != nil && p.EndTime != 0 {
+		return fmt.Errorf("time and frame are mutually exclusive: %w", ErrInvalidArgument)
+	}
+	// zero times are checked by ValidateAligned, frames and snapping may turn times into zero
+	if p.StartTime < 0 || p.EndTime < 0 {
+		return fmt.Errorf("time should not be negative: %w", ErrInvalidArgument)
+	}
+	// with frames times are known after Align
+	if p.Region != nil && !p.UsesFrames() {
+		if err := p.Region.Validate(p.StartTime, p.EndTime); err != nil {
+			return fmt.Errorf("invalid region: %w", err)
+		}
+	}
+	if p.Type == "" {
+		return fmt.Errorf("empty type: %w", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// ValidateAligned checks times resolved by Align.
+func (p *CreateAnnotationParams) ValidateAligned() error {
+	if p.StartTime < 0 || p.EndTime < 0 {
+		return fmt.Errorf("time should not be negative: %w", ErrInvalidArgument)
+	}
+	if p.StartTime == 0 && !p.Type.StartsAtZero() {
 		return fmt.Errorf("start time should be above 0: %w", ErrInvalidArgument)
 	}
 	if p.EndTime <= 0 {
@@ -66,12 +230,14 @@ func (p *CreateAnnotationParams) Validate() error {
 	if p.EndTime < p.StartTime {
 		return fmt.Errorf("start time should be less or equal than end time: %w", ErrInvalidArgument)
 	}
-	if p.Type == UnspecifiedAnnotationType {
-		return fmt.Errorf("invalid type: %w", ErrInvalidArgument)
-	}
 	return nil
 }
 
+// Content returns the part of annotation validated by its type.
+func (p *CreateAnnotationParams) Content() *AnnotationContent {
+	return &AnnotationContent{Message: p.Message, URL: p.URL, Title: p.Title, Payload: p.Payload}
+}
+
 type UpdateAnnotationParams struct {
 	StartTime *time.Duration  `json:"start_time"`
 	EndTime   *time.Duration  `json:"end_time"`
@@ -79,20 +245,89 @@ type UpdateAnnotationParams struct {
 	Message   *string         `json:"message,omitempty"`
 	URL       *string         `json:"url,omitempty"`
 	Title     *string         `json:"title,omitempty"`
+	// Payload replaces payload of the annotation, JSON null removes it.
+	Payload json.RawMessage `json:"payload,omitempty"`
+	// Region with empty shape removes region of the annotation.
+	Region *Region `json:"region,omitempty"`
+
+	FrameParams
 }
 
 func (p *UpdateAnnotationParams) NoUpdates() bool {
 	return p.StartTime == nil &&
 		p.EndTime == nil &&
+		p.StartFrame == nil &&
+		p.EndFrame == nil &&
 		p.Type == nil &&
 		p.Message == nil &&
 		p.URL == nil &&
-		p.Title == nil
+		p.Title == nil &&
+		p.Payload == nil &&
+		p.Region == nil
+}
+
+// Align sets times from frame numbers and snaps them to frames of the video if requested.
+func (p *UpdateAnnotationParams) Align(rate timecode.Rate) error {
+	if !p.UsesFrames() {
+		return nil
+	}
+	if err := requireRate(rate); err != nil {
+		return err
+	}
+	start, sErr := p.alignTime(p.StartTime, p.StartFrame, rate)
+	if sErr != nil {
+		return sErr
+	}
+	end, eErr := p.alignTime(p.EndTime, p.EndFrame, rate)
+	if eErr != nil {
+		return eErr
+	}
+	p.StartTime, p.EndTime = start, end
+	return nil
+}
+
+// Moves reports whether the update changes times or type of the annotation, so its overlaps may change.
+func (p *UpdateAnnotationParams) Moves() bool {
+	return p.StartTime != nil || p.EndTime != nil || p.Type != nil
+}
+
+// Bounds returns type, start and end time of the annotation after update.
+func (p *UpdateAnnotationParams) Bounds(current *Annotation) (AnnotationType, time.Duration, time.Duration) {
+	t, start, end := current.Type, current.StartTime, current.EndTime
+	if p.Type != nil {
+		t = *p.Type
+	}
+	if p.StartTime != nil {
+		start = *p.StartTime
+	}
+	if p.EndTime != nil {
+		end = *p.EndTime
+	}
+	return t, start, end
+}
+
+// ValidateRegion checks region of the annotation after update, either new or current one.
+func (p *UpdateAnnotationParams) ValidateRegion(current *Annotation) error {
+	region := current.Region
+	if p.Region != nil {
+		region = p.Region
+	}
+	if region == nil || region.Empty() && len(region.Keyframes) == 0 {
+		return nil
+	}
+	_, start, end := p.Bounds(current)
+	if err := region.Validate(start, end); err != nil {
+		return fmt.Errorf("invalid region: %w", err)
+	}
+	return nil
 }
 
 func (p *UpdateAnnotationParams) Validate() error {
-	if p.StartTime != nil && *p.StartTime == 0 {
-		return fmt.Errorf("empty start time: %w", ErrInvalidArgument)
+	if fErr := p.FrameParams.Validate(); fErr != nil {
+		return fErr
+	}
+	if p.StartFrame != nil && p.StartTime != nil || p.EndFrame != nil && p.EndTime != nil {
+		return fmt.Errorf("time and frame are mutually exclusive: %w", ErrInvalidArgument)
 	}
 	if p.EndTime != nil && *p.EndTime == 0 {
 		return fmt.Errorf("empty end time: %w", ErrInvalidArgument)
@@ -100,17 +335,73 @@ func (p *UpdateAnnotationParams) Validate() error {
 	if p.EndTime != nil && p.StartTime != nil && *p.EndTime < *p.StartTime {
 		return fmt.Errorf("start time should be less or equal than end time: %w", ErrInvalidArgument)
 	}
-	if p.Type != nil && *p.Type == UnspecifiedAnnotationType {
-		return fmt.Errorf("invalid type: %w", ErrInvalidArgument)
+	if p.Type != nil && *p.Type == "" {
+		return fmt.Errorf("empty type: %w", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// ValidateAligned checks times resolved by Align for the annotation of type t after update.
+func (p *UpdateAnnotationParams) ValidateAligned(t AnnotationType) error {
+	if p.StartTime != nil && *p.StartTime < 0 || p.EndTime != nil && *p.EndTime < 0 {
+		return fmt.Errorf("time should not be negative: %w", ErrInvalidArgument)
+	}
+	if p.StartTime != nil && *p.StartTime == 0 && !t.StartsAtZero() {
+		return fmt.Errorf("empty start time: %w", ErrInvalidArgument)
+	}
+	if p.EndTime != nil && *p.EndTime == 0 {
+		return fmt.Errorf("empty end time: %w", ErrInvalidArgument)
+	}
+	if p.EndTime != nil && p.StartTime != nil && *p.EndTime < *p.StartTime {
+		return fmt.Errorf("start time should be less or equal than end time: %w", ErrInvalidArgument)
 	}
 	return nil
 }
 
-func ToAnnotationType(t string) AnnotationType {
-	switch AnnotationType(t) {
-	case TextAnnotationType, CommentaryAnnotationType, LinkAnnotationType, TitleAnnotationType:
-		return AnnotationType(t)
+// Content returns content of the annotation after update, so it can be validated by its type.
+func (p *UpdateAnnotationParams) Content(current *Annotation) *AnnotationContent {
+	c := current.Content()
+	if p.Message != nil {
+		c.Message = *p.Message
+	}
+	if p.URL != nil {
+		c.URL = *p.URL
+	}
+	if p.Title != nil {
+		c.Title = *p.Title
+	}
+	if p.Payload != nil {
+		c.Payload = p.Payload
+	}
+	return c
+}
+
+// Content returns the part of annotation validated by its type.
+func (a *Annotation) Content() *AnnotationContent {
+	return &AnnotationContent{Message: a.Message, URL: a.URL, Title: a.Title, Payload: a.Payload}
+}
+
+// Cursor returns cursor pointing to the video in the sort order.
+func (v *Video) Cursor(s Sort) *Cursor {
+	c := &Cursor{Field: s.Field, Desc: s.Desc, ID: v.ID}
+	if s.Field == CreatedAtSortField {
+		c.Time = v.CreatedAt
+	} else {
+		c.Time = v.UpdatedAt
+	}
+	return c
+}
+
+// Cursor returns cursor pointing to the annotation in the sort order.
+func (a *Annotation) Cursor(s Sort) *Cursor {
+	c := &Cursor{Field: s.Field, Desc: s.Desc, ID: a.ID}
+	switch s.Field {
+	case StartTimeSortField:
+		c.Offset = a.StartTime
+	case CreatedAtSortField:
+		c.Time = a.CreatedAt
 	default:
-		return UnspecifiedAnnotationType
+		c.Time = a.UpdatedAt
 	}
+	return c
 }
@@ -0,0 +1,223 @@
+package model
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/timecode"
+)
+
+func frame(n int64) *int64 {
+	return &n
+}
+
+// frames returns params with start and end frames.
+func frames(start, end int64) FrameParams {
+	return FrameParams{StartFrame: frame(start), EndFrame: frame(end)}
+}
+
+func TestCreateAnnotationParamsAlign(t *testing.T) {
+	pal := timecode.Rate{FPS: 25}
+	dropFrame := timecode.Rate{FPS: 29.97, DropFrame: true}
+	tests := []struct {
+		name      string
+		p         *CreateAnnotationParams
+		rate      timecode.Rate
+		wantStart time.Duration
+		wantEnd   time.Duration
+		wantErr   error
+	}{
+		{
+			name:      "times without frames",
+			p:         &CreateAnnotationParams{Type: TextAnnotationType, StartTime: 10, EndTime: 20},
+			wantStart: 10, wantEnd: 20,
+		},
+		{
+			name:      "frames",
+			p:         &CreateAnnotationParams{Type: TextAnnotationType, FrameParams: frames(25, 50)},
+			rate:      pal,
+			wantStart: time.Second, wantEnd: 2 * time.Second,
+		},
+		{
+			// the first frame after the dropped numbers of the first minute, 00:01:00;02
+			name: "drop-frame frames",
+			p: &CreateAnnotationParams{
+				Type: TextAnnotationType, FrameParams: frames(1800, 17982),
+			},
+			rate:      dropFrame,
+			wantStart: 60060 * time.Millisecond, wantEnd: 600 * time.Second,
+		},
+		{
+			name: "drop-frame snapping",
+			p: &CreateAnnotationParams{
+				Type: TextAnnotationType, StartTime: 60070 * time.Millisecond, EndTime: 60100 * time.Millisecond,
+				FrameParams: FrameParams{SnapToFrames: true},
+			},
+			rate:      dropFrame,
+			wantStart: 60060 * time.Millisecond, wantEnd: 60094 * time.Millisecond,
+		},
+		{
+			name: "snapping keeps frame starts",
+			p: &CreateAnnotationParams{
+				Type: TextAnnotationType, StartTime: 40 * time.Millisecond, EndTime: 79 * time.Millisecond,
+				FrameParams: FrameParams{SnapToFrames: true},
+			},
+			rate:      pal,
+			wantStart: 40 * time.Millisecond, wantEnd: 40 * time.Millisecond,
+		},
+		{
+			name: "frame with time",
+			p: &CreateAnnotationParams{
+				Type: TextAnnotationType, StartTime: 60060 * time.Millisecond,
+				EndTime: 70 * time.Second, FrameParams: FrameParams{EndFrame: frame(2000)},
+			},
+			rate:    dropFrame,
+			wantErr: ErrInvalidArgument,
+		},
+		{
+			name:    "frames without rate",
+			p:       &CreateAnnotationParams{Type: TextAnnotationType, FrameParams: frames(1, 2)},
+			wantErr: ErrInvalidArgument,
+		},
+		{
+			name:    "zero start frame",
+			p:       &CreateAnnotationParams{Type: TextAnnotationType, FrameParams: frames(0, 25)},
+			rate:    pal,
+			wantErr: ErrInvalidArgument,
+		},
+		{
+			name: "start snapped to zero",
+			p: &CreateAnnotationParams{
+				Type: TextAnnotationType, StartTime: 10 * time.Millisecond, EndTime: time.Second,
+				FrameParams: FrameParams{SnapToFrames: true},
+			},
+			rate:    pal,
+			wantErr: ErrInvalidArgument,
+		},
+		{
+			name:      "zero start frame of chapter",
+			p:         &CreateAnnotationParams{Type: ChapterAnnotationType, FrameParams: frames(0, 25)},
+			rate:      pal,
+			wantStart: 0, wantEnd: time.Second,
+		},
+		{
+			name:    "zero start time",
+			p:       &CreateAnnotationParams{Type: TextAnnotationType, EndTime: time.Second},
+			wantErr: ErrInvalidArgument,
+		},
+		{
+			name:    "zero end frame",
+			p:       &CreateAnnotationParams{Type: ChapterAnnotationType, FrameParams: frames(0, 0)},
+			rate:    pal,
+			wantErr: ErrInvalidArgument,
+		},
+		{
+			// start time of the frame overflows into negative duration
+			name:    "huge frames",
+			p:       &CreateAnnotationParams{Type: TextAnnotationType, FrameParams: frames(400000000000, 400000000000)},
+			rate:    pal,
+			wantErr: ErrInvalidArgument,
+		},
+		{
+			name:    "huge end frame",
+			p:       &CreateAnnotationParams{Type: TextAnnotationType, FrameParams: frames(25, math.MaxInt64)},
+			rate:    dropFrame,
+			wantErr: ErrInvalidArgument,
+		},
+		{
+			name: "end frame before start time",
+			p: &CreateAnnotationParams{
+				Type: TextAnnotationType, StartTime: 2 * time.Second, FrameParams: FrameParams{EndFrame: frame(25)},
+			},
+			rate:    pal,
+			wantErr: ErrInvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.p.UserID, tt.p.VideoID = "user", "video"
+			err := tt.p.Validate()
+			if err == nil {
+				err = tt.p.Align(tt.rate)
+			}
+			if err == nil {
+				err = tt.p.ValidateAligned()
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.p.StartTime != tt.wantStart || tt.p.EndTime != tt.wantEnd {
+				t.Errorf("times = %v, %v, want %v, %v", tt.p.StartTime, tt.p.EndTime, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestUpdateAnnotationParamsValidateAligned(t *testing.T) {
+	pal := timecode.Rate{FPS: 25}
+	tests := []struct {
+		name    string
+		p       *UpdateAnnotationParams
+		t       AnnotationType
+		wantErr bool
+	}{
+		{
+			name: "start frame", p: &UpdateAnnotationParams{FrameParams: FrameParams{StartFrame: frame(1)}},
+			t: TextAnnotationType,
+		},
+		{
+			name: "zero start frame", p: &UpdateAnnotationParams{FrameParams: FrameParams{StartFrame: frame(0)}},
+			t: TextAnnotationType, wantErr: true,
+		},
+		{
+			name: "zero start frame of chapter", p: &UpdateAnnotationParams{FrameParams: FrameParams{StartFrame: frame(0)}},
+			t: ChapterAnnotationType,
+		},
+		{
+			name: "zero end frame", p: &UpdateAnnotationParams{FrameParams: FrameParams{EndFrame: frame(0)}},
+			t: ChapterAnnotationType, wantErr: true,
+		},
+		{
+			name: "end frame before start time",
+			p: &UpdateAnnotationParams{
+				StartTime: durationPtr(2 * time.Second), FrameParams: FrameParams{EndFrame: frame(25)},
+			},
+			t: TextAnnotationType, wantErr: true,
+		},
+		{
+			name: "huge start frame", p: &UpdateAnnotationParams{FrameParams: FrameParams{StartFrame: frame(400000000000)}},
+			t: TextAnnotationType, wantErr: true,
+		},
+		{
+			name: "huge end frame", p: &UpdateAnnotationParams{FrameParams: FrameParams{EndFrame: frame(math.MaxInt64)}},
+			t: TextAnnotationType, wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.p.Validate()
+			if err == nil {
+				err = tt.p.Align(pal)
+			}
+			if err == nil {
+				err = tt.p.ValidateAligned(tt.t)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type SortField string
+
+const (
+	StartTimeSortField SortField = "start_time"
+	CreatedAtSortField SortField = "created_at"
+	UpdatedAtSortField SortField = "updated_at"
+)
+
+const (
+	AscOrder  = "asc"
+	DescOrder = "desc"
+)
+
+// Sort orders list by the field and then by id in the same direction.
+type Sort struct {
+	Field SortField
+	Desc  bool
+}
+
+// ToSort parses sort field and order, empty field sorts by updated_at and empty order is ascending.
+func ToSort(field, order string) (Sort, error) {
+	s := Sort{Field: UpdatedAtSortField}
+	switch SortField(field) {
+	case "":
+	case StartTimeSortField, CreatedAtSortField, UpdatedAtSortField:
+		s.Field = SortField(field)
+	default:
+		return Sort{}, fmt.Errorf("invalid sort field %q: %w", field, ErrInvalidArgument)
+	}
+	switch order {
+	case "", AscOrder:
+	case DescOrder:
+		s.Desc = true
+	default:
+		return Sort{}, fmt.Errorf("invalid sort order %q: %w", order, ErrInvalidArgument)
+	}
+	return s, nil
+}
+
+// Cursor points to the last item of the page, the next page starts after it in the order of the sort.
+// Sort value is kept in Time for timestamps and in Offset for positions within the video.
+type Cursor struct {
+	Field  SortField     `json:"f"`
+	Desc   bool          `json:"d,omitempty"`
+	Time   time.Time     `json:"t,omitempty"`
+	Offset time.Duration `json:"o,omitempty"`
+	ID     string        `json:"i"`
+}
+
+// Encode returns opaque representation of the cursor for clients.
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c) //nolint:errchkjson // cursor contains only marshalable fields
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", ErrInvalidArgument)
+	}
+	c := &Cursor{}
+	if uErr := json.Unmarshal(data, c); uErr != nil || c.ID == "" {
+		return nil, fmt.Errorf("invalid cursor: %w", ErrInvalidArgument)
+	}
+	return c, nil
+}
+
+// Matches reports whether the cursor was issued for the same sort.
+func (c *Cursor) Matches(s Sort) bool {
+	return c.Field == s.Field && c.Desc == s.Desc
+}
+
+// Page limits list to Limit items after the cursor, zero Limit means all items.
+type Page struct {
+	Limit int
+	Sort  Sort
+	After *Cursor
+}
+
+// VideoFilter selects videos created within the optional range.
+type VideoFilter struct {
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+func (f *VideoFilter) Validate() error {
+	if f.CreatedAfter != nil && f.CreatedBefore != nil && f.CreatedBefore.Before(*f.CreatedAfter) {
+		return fmt.Errorf("created_before should be after created_after: %w", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// AnnotationFilter selects annotations by type, author, creation time and time window,
+// annotation matches the window if it overlaps [From, To].
+type AnnotationFilter struct {
+	Type          AnnotationType
+	UserID        string
+	From          *time.Duration
+	To            *time.Duration
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+func (f *AnnotationFilter) Validate() error {
+	if f.From != nil && *f.From < 0 {
+		return fmt.Errorf("from should not be negative: %w", ErrInvalidArgument)
+	}
+	if f.From != nil && f.To != nil && *f.To < *f.From {
+		return fmt.Errorf("from should be less or equal than to: %w", ErrInvalidArgument)
+	}
+	if f.CreatedAfter != nil && f.CreatedBefore != nil && f.CreatedBefore.Before(*f.CreatedAfter) {
+		return fmt.Errorf("created_before should be after created_after: %w", ErrInvalidArgument)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestToSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		order   string
+		want    Sort
+		wantErr bool
+	}{
+		{name: "defaults", want: Sort{Field: UpdatedAtSortField}},
+		{name: "start time", field: "start_time", want: Sort{Field: StartTimeSortField}},
+		{name: "created at desc", field: "created_at", order: "desc", want: Sort{Field: CreatedAtSortField, Desc: true}},
+		{name: "asc", field: "updated_at", order: "asc", want: Sort{Field: UpdatedAtSortField}},
+		{name: "unknown field", field: "title", wantErr: true},
+		{name: "injected field", field: "id; DROP TABLE video", wantErr: true},
+		{name: "unknown order", order: "up", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToSort(tt.field, tt.order)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidArgument) {
+					t.Errorf("ToSort() error = %v, want %v", err, ErrInvalidArgument)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ToSort() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		c    *Cursor
+	}{
+		{
+			name: "time",
+			c: &Cursor{
+				Field: UpdatedAtSortField, Time: time.Date(2023, 5, 1, 10, 0, 0, 123456000, time.UTC), ID: "a",
+			},
+		},
+		{name: "offset desc", c: &Cursor{Field: StartTimeSortField, Desc: true, Offset: 1500 * time.Millisecond, ID: "b"}},
+		{name: "zero offset", c: &Cursor{Field: StartTimeSortField, ID: "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(tt.c.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Field != tt.c.Field || got.Desc != tt.c.Desc || !got.Time.Equal(tt.c.Time) ||
+				got.Offset != tt.c.Offset || got.ID != tt.c.ID {
+				t.Errorf("DecodeCursor() = %+v, want %+v", got, tt.c)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	for name, s := range map[string]string{
+		"not base64": "%%%",
+		"not json":   "bm90IGpzb24",
+		"empty id":   (&Cursor{Field: UpdatedAtSortField}).Encode(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodeCursor(s); !errors.Is(err, ErrInvalidArgument) {
+				t.Errorf("DecodeCursor() error = %v, want %v", err, ErrInvalidArgument)
+			}
+		})
+	}
+}
+
+func TestCursorMatches(t *testing.T) {
+	c := &Cursor{Field: StartTimeSortField, ID: "a"}
+	if !c.Matches(Sort{Field: StartTimeSortField}) {
+		t.Error("cursor doesn't match its sort")
+	}
+	if c.Matches(Sort{Field: StartTimeSortField, Desc: true}) {
+		t.Error("cursor matches sort in other direction")
+	}
+	if c.Matches(Sort{Field: CreatedAtSortField}) {
+		t.Error("cursor matches sort by other field")
+	}
+}
+
+func TestAnnotationCursor(t *testing.T) {
+	created := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	a := &Annotation{ID: "a", StartTime: 2 * time.Second, CreatedAt: created, UpdatedAt: created.Add(time.Hour)}
+	if c := a.Cursor(Sort{Field: StartTimeSortField, Desc: true}); c.Offset != 2*time.Second || c.ID != "a" || !c.Desc {
+		t.Errorf("start time cursor = %+v", c)
+	}
+	if c := a.Cursor(Sort{Field: CreatedAtSortField}); !c.Time.Equal(created) || c.ID != "a" {
+		t.Errorf("created at cursor = %+v", c)
+	}
+	if c := a.Cursor(Sort{Field: UpdatedAtSortField}); !c.Time.Equal(a.UpdatedAt) || c.ID != "a" {
+		t.Errorf("updated at cursor = %+v", c)
+	}
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// RateLimitBucket is a token bucket refilled with rate tokens per second up to burst tokens.
+type RateLimitBucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Take refills the bucket up to now and takes a token from it if there is one.
+func (b *RateLimitBucket) Take(rate float64, burst int, now time.Time) bool {
+	if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(float64(burst), b.Tokens+elapsed*rate)
+	}
+	b.UpdatedAt = now
+	if b.Tokens < 1 {
+		return false
+	}
+	b.Tokens--
+	return true
+}
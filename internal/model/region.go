@@ -0,0 +1,141 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxPolygonPoints limits size of polygons stored with annotations.
+const maxPolygonPoints = 64
+
+// epsilon absorbs float rounding when box edge is checked against the frame edge.
+const epsilon = 1e-9
+
+// Point is a position on the screen normalized to the frame size,
+// (0, 0) is the top-left corner and (1, 1) is the bottom-right one.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Box is a rectangle with the top-left corner at (X, Y) normalized to the frame size.
+type Box struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+// Shape is either a box or a polygon.
+type Shape struct {
+	Box     *Box    `json:"box,omitempty"`
+	Polygon []Point `json:"polygon,omitempty"`
+}
+
+func (s *Shape) Empty() bool {
+	return s.Box == nil && len(s.Polygon) == 0
+}
+
+func (s *Shape) Validate() error {
+	if s.Box != nil && len(s.Polygon) > 0 {
+		return fmt.Errorf("box and polygon are mutually exclusive: %w", ErrInvalidArgument)
+	}
+	if s.Box != nil {
+		b := s.Box
+		if !normalized(b.X) || !normalized(b.Y) || b.W <= 0 || b.H <= 0 || b.X+b.W > 1+epsilon || b.Y+b.H > 1+epsilon {
+			return fmt.Errorf("box should have positive size and fit into 0-1 range: %w", ErrInvalidArgument)
+		}
+		return nil
+	}
+	if len(s.Polygon) < 3 || len(s.Polygon) > maxPolygonPoints {
+		return fmt.Errorf("polygon should have from 3 to %d points: %w", maxPolygonPoints, ErrInvalidArgument)
+	}
+	for _, p := range s.Polygon {
+		if !normalized(p.X) || !normalized(p.Y) {
+			return fmt.Errorf("polygon points should be in 0-1 range: %w", ErrInvalidArgument)
+		}
+	}
+	return nil
+}
+
+// sameKind reports whether shapes can be interpolated into each other.
+func (s *Shape) sameKind(o *Shape) bool {
+	return (s.Box != nil) == (o.Box != nil) && len(s.Polygon) == len(o.Polygon)
+}
+
+func normalized(v float64) bool {
+	return v >= 0 && v <= 1
+}
+
+// Keyframe is the shape of the region at the time of the video.
+type Keyframe struct {
+	Time time.Duration `json:"time"`
+	Shape
+}
+
+// Region is the area of the screen the annotation points to, e.g. a clickable hotspot.
+// Shape is the area at the start of the annotation, keyframes move it linearly to their shapes at their times,
+// after the last keyframe the region stays in place.
+type Region struct {
+	Shape
+	Keyframes []Keyframe `json:"keyframes,omitempty"`
+}
+
+// Validate checks the region of annotation shown from start to end.
+func (r *Region) Validate(start, end time.Duration) error {
+	if err := r.Shape.Validate(); err != nil {
+		return err
+	}
+	prev := start
+	for i := range r.Keyframes {
+		k := &r.Keyframes[i]
+		if err := k.Shape.Validate(); err != nil {
+			return fmt.Errorf("keyframe %d: %w", i, err)
+		}
+		if !k.sameKind(&r.Shape) {
+			return fmt.Errorf("keyframe %d should have the same kind of shape as region: %w", i, ErrInvalidArgument)
+		}
+		if k.Time <= prev || k.Time > end {
+			return fmt.Errorf(
+				"keyframe %d should be after the previous one and within annotation: %w", i, ErrInvalidArgument,
+			)
+		}
+		prev = k.Time
+	}
+	return nil
+}
+
+// At returns the shape of the region at t of annotation starting at start.
+func (r *Region) At(start, t time.Duration) *Shape {
+	from, fromTime := &r.Shape, start
+	for i := range r.Keyframes {
+		k := &r.Keyframes[i]
+		if t < k.Time {
+			if t <= fromTime {
+				return from
+			}
+			return interpolate(from, &k.Shape, float64(t-fromTime)/float64(k.Time-fromTime))
+		}
+		from, fromTime = &k.Shape, k.Time
+	}
+	return from
+}
+
+func interpolate(from, to *Shape, ratio float64) *Shape {
+	lerp := func(a, b float64) float64 {
+		return a + (b-a)*ratio
+	}
+	if from.Box != nil {
+		return &Shape{Box: &Box{
+			X: lerp(from.Box.X, to.Box.X),
+			Y: lerp(from.Box.Y, to.Box.Y),
+			W: lerp(from.Box.W, to.Box.W),
+			H: lerp(from.Box.H, to.Box.H),
+		}}
+	}
+	polygon := make([]Point, len(from.Polygon))
+	for i := range from.Polygon {
+		polygon[i] = Point{X: lerp(from.Polygon[i].X, to.Polygon[i].X), Y: lerp(from.Polygon[i].Y, to.Polygon[i].Y)}
+	}
+	return &Shape{Polygon: polygon}
+}
@@ -0,0 +1,178 @@
+package model
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func box(x, y, w, h float64) Shape {
+	return Shape{Box: &Box{X: x, Y: y, W: w, H: h}}
+}
+
+func triangle(dx float64) Shape {
+	return Shape{Polygon: []Point{{X: dx, Y: 0}, {X: dx + 0.5, Y: 0}, {X: dx, Y: 0.5}}}
+}
+
+func TestRegionValidate(t *testing.T) {
+	start, end := time.Second, 5*time.Second
+	tests := []struct {
+		name    string
+		r       Region
+		wantErr bool
+	}{
+		{name: "box", r: Region{Shape: box(0, 0, 1, 1)}},
+		{name: "polygon", r: Region{Shape: triangle(0)}},
+		{
+			name: "keyframes",
+			r: Region{Shape: box(0, 0, 0.1, 0.1), Keyframes: []Keyframe{
+				{Time: 2 * time.Second, Shape: box(0.5, 0.5, 0.1, 0.1)},
+				{Time: end, Shape: box(0.9, 0.9, 0.1, 0.1)},
+			}},
+		},
+		{name: "empty", r: Region{}, wantErr: true},
+		{name: "negative x", r: Region{Shape: box(-0.1, 0, 0.5, 0.5)}, wantErr: true},
+		{name: "box beyond frame", r: Region{Shape: box(0.6, 0, 0.5, 0.5)}, wantErr: true},
+		{name: "zero size box", r: Region{Shape: box(0, 0, 0, 0.5)}, wantErr: true},
+		{name: "nan box", r: Region{Shape: box(math.NaN(), 0, 0.5, 0.5)}, wantErr: true},
+		{
+			name:    "polygon point out of range",
+			r:       Region{Shape: Shape{Polygon: []Point{{X: 0, Y: 0}, {X: 1.5, Y: 0}, {X: 0, Y: 1}}}},
+			wantErr: true,
+		},
+		{name: "two point polygon", r: Region{Shape: Shape{Polygon: []Point{{}, {X: 1}}}}, wantErr: true},
+		{
+			name:    "box and polygon",
+			r:       Region{Shape: Shape{Box: &Box{W: 1, H: 1}, Polygon: triangle(0).Polygon}},
+			wantErr: true,
+		},
+		{
+			name: "keyframe at start",
+			r: Region{Shape: box(0, 0, 0.1, 0.1), Keyframes: []Keyframe{
+				{Time: start, Shape: box(0.5, 0.5, 0.1, 0.1)},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "keyframe after end",
+			r: Region{Shape: box(0, 0, 0.1, 0.1), Keyframes: []Keyframe{
+				{Time: end + time.Millisecond, Shape: box(0.5, 0.5, 0.1, 0.1)},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "keyframes out of order",
+			r: Region{Shape: box(0, 0, 0.1, 0.1), Keyframes: []Keyframe{
+				{Time: 3 * time.Second, Shape: box(0.5, 0.5, 0.1, 0.1)},
+				{Time: 2 * time.Second, Shape: box(0.9, 0.9, 0.1, 0.1)},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "keyframes at the same time",
+			r: Region{Shape: box(0, 0, 0.1, 0.1), Keyframes: []Keyframe{
+				{Time: 3 * time.Second, Shape: box(0.5, 0.5, 0.1, 0.1)},
+				{Time: 3 * time.Second, Shape: box(0.9, 0.9, 0.1, 0.1)},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "keyframe of other kind",
+			r:       Region{Shape: box(0, 0, 0.1, 0.1), Keyframes: []Keyframe{{Time: 2 * time.Second, Shape: triangle(0)}}},
+			wantErr: true,
+		},
+		{
+			name: "keyframe with other number of points",
+			r: Region{Shape: triangle(0), Keyframes: []Keyframe{{
+				Time: 2 * time.Second, Shape: Shape{Polygon: []Point{{}, {X: 1}, {X: 1, Y: 1}, {Y: 1}}},
+			}}},
+			wantErr: true,
+		},
+		{
+			name: "invalid keyframe shape",
+			r: Region{Shape: box(0, 0, 0.1, 0.1), Keyframes: []Keyframe{
+				{Time: 2 * time.Second, Shape: box(1, 1, 0.1, 0.1)},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.r.Validate(start, end)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidArgument) {
+					t.Errorf("Validate() error = %v, want %v", err, ErrInvalidArgument)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRegionAt(t *testing.T) {
+	start := time.Second
+	moving := Region{Shape: box(0, 0, 0.2, 0.2), Keyframes: []Keyframe{
+		{Time: 3 * time.Second, Shape: box(0.4, 0.2, 0.4, 0.2)},
+		{Time: 4 * time.Second, Shape: box(0.8, 0.8, 0.2, 0.2)},
+	}}
+	tests := []struct {
+		name string
+		r    Region
+		t    time.Duration
+		want Shape
+	}{
+		{name: "static", r: Region{Shape: box(0.1, 0.1, 0.2, 0.2)}, t: 10 * time.Second, want: box(0.1, 0.1, 0.2, 0.2)},
+		{name: "before start", r: moving, t: 0, want: box(0, 0, 0.2, 0.2)},
+		{name: "at start", r: moving, t: start, want: box(0, 0, 0.2, 0.2)},
+		{name: "between start and keyframe", r: moving, t: 2 * time.Second, want: box(0.2, 0.1, 0.3, 0.2)},
+		{name: "at keyframe", r: moving, t: 3 * time.Second, want: box(0.4, 0.2, 0.4, 0.2)},
+		{name: "between keyframes", r: moving, t: 3500 * time.Millisecond, want: box(0.6, 0.5, 0.3, 0.2)},
+		{name: "at last keyframe", r: moving, t: 4 * time.Second, want: box(0.8, 0.8, 0.2, 0.2)},
+		{name: "after last keyframe", r: moving, t: time.Minute, want: box(0.8, 0.8, 0.2, 0.2)},
+		{
+			name: "polygon",
+			r:    Region{Shape: triangle(0), Keyframes: []Keyframe{{Time: 3 * time.Second, Shape: triangle(0.4)}}},
+			t:    2 * time.Second,
+			want: triangle(0.2),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.r.At(start, tt.t)
+			if !shapesClose(got, &tt.want) {
+				t.Errorf("At(%v) = %+v, want %+v", tt.t, shapeString(got), shapeString(&tt.want))
+			}
+		})
+	}
+}
+
+func shapesClose(a, b *Shape) bool {
+	near := func(x, y float64) bool {
+		return math.Abs(x-y) < epsilon
+	}
+	if (a.Box != nil) != (b.Box != nil) || len(a.Polygon) != len(b.Polygon) {
+		return false
+	}
+	if a.Box != nil && !(near(a.Box.X, b.Box.X) && near(a.Box.Y, b.Box.Y) &&
+		near(a.Box.W, b.Box.W) && near(a.Box.H, b.Box.H)) {
+		return false
+	}
+	for i := range a.Polygon {
+		if !near(a.Polygon[i].X, b.Polygon[i].X) || !near(a.Polygon[i].Y, b.Polygon[i].Y) {
+			return false
+		}
+	}
+	return true
+}
+
+func shapeString(s *Shape) interface{} {
+	if s.Box != nil {
+		return *s.Box
+	}
+	return s.Polygon
+}
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+type Role string
+
+const (
+	UnspecifiedRole Role = "unspecified"
+	ViewerRole      Role = "viewer"
+	EditorRole      Role = "editor"
+	AdminRole       Role = "admin"
+)
+
+type User struct {
+	ID            string     `json:"id"`
+	Email         string     `json:"email,omitempty"`
+	Role          Role       `json:"role"`
+	WorkspaceID   string     `json:"workspace_id"`
+	PasswordHash  string     `json:"-"`
+	FailedSignIns int        `json:"-"`
+	LockedUntil   *time.Time `json:"-"`
+	// OIDCIssuer and OIDCSubject identify users signed up through external identity provider.
+	OIDCIssuer  string `json:"-"`
+	OIDCSubject string `json:"-"`
+}
+
+// OIDCIdentity is a user authenticated by external identity provider,
+// Email is set only when provider verified it.
+type OIDCIdentity struct {
+	Issuer  string
+	Subject string
+	Email   string
+}
+
+func ToRole(r string) Role {
+	switch Role(r) {
+	case ViewerRole, EditorRole, AdminRole:
+		return Role(r)
+	default:
+		return UnspecifiedRole
+	}
+}
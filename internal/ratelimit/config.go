@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/triabokon/gotagv/internal/flags"
+)
+
+type Config struct {
+	Postgres          bool
+	TrustForwardedFor bool
+	PruneInterval     time.Duration
+
+	AuthPerMinute  int
+	AuthBurst      int
+	ReadPerMinute  int
+	ReadBurst      int
+	WritePerMinute int
+	WriteBurst     int
+	// ClientPerMinute limits requests from client ip to all authenticated routes, it should allow
+	// read and write limits of several users sharing the ip.
+	ClientPerMinute int
+	ClientBurst     int
+}
+
+func (c *Config) Flags(prefix string) *pflag.FlagSet {
+	const name = "RateLimitConfig"
+	f := pflag.NewFlagSet(name, pflag.PanicOnError)
+
+	f.BoolVar(&c.Postgres, "postgres", false, "keep buckets in postgres to share limits between server instances")
+	f.BoolVar(
+		&c.TrustForwardedFor, "trust_forwarded_for", false,
+		"take client ip from X-Forwarded-For header, enable only behind a proxy setting it",
+	)
+	f.DurationVar(&c.PruneInterval, "prune_interval", 10*time.Minute, "how often unused buckets are deleted")
+
+	f.IntVar(&c.AuthPerMinute, "auth_per_minute", 10, "sign up and sign in requests per minute, 0 disables the limit")
+	f.IntVar(&c.AuthBurst, "auth_burst", 10, "sign up and sign in requests allowed at once")
+	f.IntVar(&c.ReadPerMinute, "read_per_minute", 600, "read requests per minute, 0 disables the limit")
+	f.IntVar(&c.ReadBurst, "read_burst", 100, "read requests allowed at once")
+	f.IntVar(&c.WritePerMinute, "write_per_minute", 120, "write requests per minute, 0 disables the limit")
+	f.IntVar(&c.WriteBurst, "write_burst", 30, "write requests allowed at once")
+	f.IntVar(
+		&c.ClientPerMinute, "client_per_minute", 3000,
+		"requests per minute from client ip to authenticated routes, including rejected ones, 0 disables the limit",
+	)
+	f.IntVar(&c.ClientBurst, "client_burst", 300, "requests from client ip to authenticated routes allowed at once")
+	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
+}
+
+// limits returns limits of route groups, groups without limit are omitted.
+func (c *Config) limits() map[Group]limit {
+	limits := make(map[Group]limit)
+	for g, l := range map[Group]limit{
+		AuthGroup:   {perMinute: c.AuthPerMinute, burst: c.AuthBurst},
+		ReadGroup:   {perMinute: c.ReadPerMinute, burst: c.ReadBurst},
+		WriteGroup:  {perMinute: c.WritePerMinute, burst: c.WriteBurst},
+		ClientGroup: {perMinute: c.ClientPerMinute, burst: c.ClientBurst},
+	} {
+		if l.perMinute > 0 {
+			if l.burst < 1 {
+				l.burst = 1
+			}
+			limits[g] = l
+		}
+	}
+	return limits
+}
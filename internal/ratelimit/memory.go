@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// memoryStore keeps buckets of a single server instance.
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*model.RateLimitBucket
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{buckets: make(map[string]*model.RateLimitBucket)}
+}
+
+func (s *memoryStore) TakeRateLimitToken(
+	_ context.Context, key string, rate float64, burst int, now time.Time,
+) (*model.RateLimitBucket, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &model.RateLimitBucket{Tokens: float64(burst), UpdatedAt: now}
+		s.buckets[key] = b
+	}
+	allowed := b.Take(rate, burst, now)
+	bucket := *b
+	return &bucket, allowed, nil
+}
+
+func (s *memoryStore) PruneRateLimitBuckets(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pruned int64
+	for key, b := range s.buckets {
+		if b.UpdatedAt.Before(before) {
+			delete(s.buckets, key)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
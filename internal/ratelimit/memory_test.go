@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	const (
+		rate  = 2.0 // tokens per second
+		burst = 3
+	)
+	start := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		key         string
+		after       time.Duration
+		wantAllowed bool
+		wantTokens  float64
+	}{
+		{name: "full bucket", key: "a", wantAllowed: true, wantTokens: 2},
+		{name: "second token", key: "a", wantAllowed: true, wantTokens: 1},
+		{name: "last token", key: "a", wantAllowed: true, wantTokens: 0},
+		{name: "empty bucket", key: "a", wantAllowed: false, wantTokens: 0},
+		{name: "other key has own bucket", key: "b", wantAllowed: true, wantTokens: 2},
+		{name: "half token refilled", key: "a", after: 250 * time.Millisecond, wantAllowed: false, wantTokens: 0.5},
+		{name: "token refilled", key: "a", after: 250 * time.Millisecond, wantAllowed: true, wantTokens: 0},
+		{name: "refilled up to burst", key: "a", after: time.Hour, wantAllowed: true, wantTokens: 2},
+	}
+	s := newMemoryStore()
+	now := start
+	for _, tt := range tests {
+		now = now.Add(tt.after)
+		b, allowed, err := s.TakeRateLimitToken(context.Background(), tt.key, rate, burst, now)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if allowed != tt.wantAllowed || b.Tokens != tt.wantTokens || !b.UpdatedAt.Equal(now) {
+			t.Errorf("%s: TakeRateLimitToken() = %+v, %t, want %v tokens, %t",
+				tt.name, b, allowed, tt.wantTokens, tt.wantAllowed)
+		}
+	}
+}
+
+func TestMemoryStorePrune(t *testing.T) {
+	start := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := newMemoryStore()
+	for i, key := range []string{"old", "new"} {
+		now := start.Add(time.Duration(i) * time.Hour)
+		if _, _, err := s.TakeRateLimitToken(context.Background(), key, 1, 1, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pruned, err := s.PruneRateLimitBuckets(context.Background(), start.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.buckets["new"]; pruned != 1 || len(s.buckets) != 1 || !ok {
+		t.Errorf("PruneRateLimitBuckets() = %d, buckets %v, want old one pruned", pruned, s.buckets)
+	}
+}
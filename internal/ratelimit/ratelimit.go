@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/triabokon/gotagv/internal/auth"
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// Group is a set of routes sharing the same limit, every client has its own bucket per group.
+type Group string
+
+const (
+	AuthGroup  Group = "auth"
+	ReadGroup  Group = "read"
+	WriteGroup Group = "write"
+	// ClientGroup limits all requests to authenticated routes by client ip before they are authenticated,
+	// so requests with invalid tokens and API keys are counted too.
+	ClientGroup Group = "client"
+)
+
+type Store interface {
+	TakeRateLimitToken(
+		ctx context.Context, key string, rate float64, burst int, now time.Time,
+	) (*model.RateLimitBucket, bool, error)
+	PruneRateLimitBuckets(ctx context.Context, before time.Time) (int64, error)
+}
+
+type limit struct {
+	perMinute int
+	burst     int
+}
+
+// rate returns tokens added to the bucket per second.
+func (l limit) rate() float64 {
+	return float64(l.perMinute) / float64(time.Minute/time.Second)
+}
+
+// fillTime returns how long it takes to refill empty bucket.
+func (l limit) fillTime() time.Duration {
+	return time.Duration(float64(l.burst) / l.rate() * float64(time.Second))
+}
+
+type Limiter struct {
+	config *Config
+	store  Store
+	logger *zap.Logger
+	limits map[Group]limit
+}
+
+// New creates limiter keeping buckets in store if postgres mode is enabled and in memory otherwise.
+func New(config *Config, store Store, logger *zap.Logger) *Limiter {
+	if !config.Postgres {
+		store = newMemoryStore()
+	}
+	return &Limiter{
+		config: config,
+		store:  store,
+		logger: logger,
+		limits: config.limits(),
+	}
+}
+
+// Limit rejects requests of the client exceeding limit of group g with 429.
+// Clients are identified by authenticated user, so it has to be called after auth.HandleAuth
+// to limit users, requests which aren't authenticated yet are limited by client ip.
+func (l *Limiter) Limit(g Group, next http.HandlerFunc) http.HandlerFunc {
+	lim, ok := l.limits[g]
+	if !ok {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		// buckets are stored in columns without time zone
+		now := time.Now().UTC()
+		key := string(g) + ":" + l.clientKey(r)
+		b, allowed, err := l.store.TakeRateLimitToken(r.Context(), key, lim.rate(), lim.burst, now)
+		if err != nil {
+			// limiter must not take the service down with its store
+			l.logger.Error("failed to take rate limit token", zap.String("key", key), zap.Error(err))
+			next(w, r)
+			return
+		}
+
+		reset := time.Duration((float64(lim.burst) - b.Tokens) / lim.rate() * float64(time.Second))
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(lim.burst))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(math.Floor(b.Tokens))))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+		if !allowed {
+			retryAfter := time.Duration((1 - b.Tokens) / lim.rate() * float64(time.Second))
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// clientKey identifies client by authenticated user and falls back to client ip.
+func (l *Limiter) clientKey(r *http.Request) string {
+	if userID, ok := r.Context().Value(auth.UserIDKey).(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	if l.config.TrustForwardedFor {
+		// the first address is the client, the rest are proxies
+		forwarded, _, _ := strings.Cut(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := strings.TrimSpace(forwarded); ip != "" {
+			return "ip:" + ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// RunPruner periodically deletes buckets which have been refilled, until ctx is done.
+func (l *Limiter) RunPruner(ctx context.Context) {
+	if l.config.PruneInterval <= 0 || len(l.limits) == 0 {
+		return
+	}
+	var maxFillTime time.Duration
+	for _, lim := range l.limits {
+		if t := lim.fillTime(); t > maxFillTime {
+			maxFillTime = t
+		}
+	}
+	ticker := time.NewTicker(l.config.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pruned, err := l.store.PruneRateLimitBuckets(ctx, time.Now().UTC().Add(-maxFillTime))
+			if err != nil {
+				l.logger.Error("failed to prune rate limit buckets", zap.Error(err))
+				continue
+			}
+			l.logger.Info("rate limit buckets pruned", zap.Int64("prunedCount", pruned))
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/triabokon/gotagv/internal/auth"
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+func TestLimit(t *testing.T) {
+	// a token per 10 seconds, 2 at once
+	l := New(&Config{ReadPerMinute: 6, ReadBurst: 2}, nil, zap.NewNop())
+	handler := l.Limit(ReadGroup, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		wantCode      int
+		wantRemaining string
+		wantReset     string
+		wantRetry     string
+	}{
+		{name: "first", remoteAddr: "10.0.0.1:1000", wantCode: http.StatusNoContent, wantRemaining: "1", wantReset: "10"},
+		// port changes with every connection
+		{name: "second", remoteAddr: "10.0.0.1:2000", wantCode: http.StatusNoContent, wantRemaining: "0", wantReset: "20"},
+		{
+			name: "exceeded", remoteAddr: "10.0.0.1:3000",
+			wantCode: http.StatusTooManyRequests, wantRemaining: "0", wantReset: "20", wantRetry: "10",
+		},
+		{name: "other ip", remoteAddr: "10.0.0.2:1000", wantCode: http.StatusNoContent, wantRemaining: "1", wantReset: "10"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/v1/videos", http.NoBody)
+		r.RemoteAddr = tt.remoteAddr
+		w := httptest.NewRecorder()
+		handler(w, r)
+
+		if w.Code != tt.wantCode {
+			t.Errorf("%s: code = %d, want %d", tt.name, w.Code, tt.wantCode)
+		}
+		// reset and retry are rounded up, the bucket refills while the test runs
+		for header, want := range map[string]string{
+			"X-RateLimit-Limit":     "2",
+			"X-RateLimit-Remaining": tt.wantRemaining,
+			"X-RateLimit-Reset":     tt.wantReset,
+			"Retry-After":           tt.wantRetry,
+		} {
+			if got := w.Header().Get(header); got != want {
+				t.Errorf("%s: %s = %q, want %q", tt.name, header, got, want)
+			}
+		}
+	}
+}
+
+func TestLimitUnlimitedGroup(t *testing.T) {
+	l := New(&Config{WritePerMinute: 0}, nil, zap.NewNop())
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		l.Limit(WriteGroup, func(w http.ResponseWriter, r *http.Request) {})(
+			w, httptest.NewRequest(http.MethodPost, "/v1/videos", http.NoBody),
+		)
+		if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("request %d: code = %d, headers %v, want no limit", i, w.Code, w.Header())
+		}
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	tests := []struct {
+		name              string
+		trustForwardedFor bool
+		userID            string
+		forwardedFor      string
+		want              string
+	}{
+		{name: "user", userID: "user", want: "user:user"},
+		{name: "ip", want: "ip:10.0.0.1"},
+		{name: "untrusted forwarded for", forwardedFor: "10.0.0.2", want: "ip:10.0.0.1"},
+		{name: "forwarded for", trustForwardedFor: true, forwardedFor: "10.0.0.2, 10.0.0.3", want: "ip:10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(&Config{TrustForwardedFor: tt.trustForwardedFor}, nil, zap.NewNop())
+			r := httptest.NewRequest(http.MethodGet, "/v1/videos", http.NoBody)
+			r.RemoteAddr = "10.0.0.1:1000"
+			if tt.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if tt.userID != "" {
+				r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, tt.userID))
+			}
+			if got := l.clientKey(r); got != tt.want {
+				t.Errorf("clientKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// timestampStore keeps buckets the way timestamp without time zone columns do,
+// with wall clock of the time and zone dropped.
+type timestampStore struct {
+	buckets map[string]*model.RateLimitBucket
+}
+
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+func (s *timestampStore) TakeRateLimitToken(
+	_ context.Context, key string, rate float64, burst int, now time.Time,
+) (*model.RateLimitBucket, bool, error) {
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &model.RateLimitBucket{Tokens: float64(burst), UpdatedAt: wallClock(now)}
+		s.buckets[key] = b
+	}
+	allowed := b.Take(rate, burst, now)
+	b.UpdatedAt = wallClock(b.UpdatedAt)
+	bucket := *b
+	return &bucket, allowed, nil
+}
+
+func (s *timestampStore) PruneRateLimitBuckets(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestLimitRefillsInLocalTimeZone(t *testing.T) {
+	local := time.Local
+	defer func() { time.Local = local }()
+
+	// a token per 100ms
+	config := &Config{Postgres: true, ReadPerMinute: 600, ReadBurst: 1}
+	for _, zone := range []*time.Location{time.FixedZone("east", 3*3600), time.FixedZone("west", -5*3600)} {
+		time.Local = zone
+		l := New(config, &timestampStore{buckets: make(map[string]*model.RateLimitBucket)}, zap.NewNop())
+		handler := l.Limit(ReadGroup, func(w http.ResponseWriter, r *http.Request) {})
+		for i, want := range []int{http.StatusOK, http.StatusTooManyRequests, 0, http.StatusOK} {
+			if want == 0 {
+				time.Sleep(150 * time.Millisecond)
+				continue
+			}
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(http.MethodGet, "/v1/videos", http.NoBody))
+			if w.Code != want {
+				t.Errorf("%s: request %d code = %d, want %d", zone, i, w.Code, want)
+			}
+		}
+	}
+}
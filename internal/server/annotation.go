@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -21,30 +24,93 @@ type CreateAnnotationRequest struct {
 	Message   string `json:"message"`
 	URL       string `json:"url"`
 	Title     string `json:"title"`
+	// Payload is extra data declared by the type.
+	Payload json.RawMessage `json:"payload,omitempty"`
+
+	Region *RegionRequest `json:"region,omitempty"`
+	model.FrameParams
+}
+
+// RegionRequest is model.Region with keyframe times in any notation accepted by parseDuration.
+type RegionRequest struct {
+	model.Shape
+	Keyframes []*KeyframeRequest `json:"keyframes,omitempty"`
+}
+
+type KeyframeRequest struct {
+	Time string `json:"time"`
+	model.Shape
+}
+
+func toRegion(r *RegionRequest, frameRate frameRateFunc) (*model.Region, error) {
+	if r == nil {
+		return nil, nil
+	}
+	region := &model.Region{Shape: r.Shape}
+	for i, k := range r.Keyframes {
+		t, pErr := parseDuration(k.Time, frameRate)
+		if pErr != nil {
+			return nil, fmt.Errorf("failed to parse time of keyframe %d: %w", i, pErr)
+		}
+		region.Keyframes = append(region.Keyframes, model.Keyframe{Time: t, Shape: k.Shape})
+	}
+	return region, nil
+}
+
+// OverlapWarning lists annotations of the same type overlapping the changed one, when policy of the type
+// is to warn about overlaps.
+type OverlapWarning struct {
+	Warning                  string   `json:"warning,omitempty"`
+	OverlappingAnnotationIDs []string `json:"overlapping_annotation_ids,omitempty"`
+}
+
+func toOverlapWarning(overlapping []string) OverlapWarning {
+	if len(overlapping) == 0 {
+		return OverlapWarning{}
+	}
+	return OverlapWarning{
+		Warning:                  fmt.Sprintf("annotation overlaps %d annotations of the same type", len(overlapping)),
+		OverlappingAnnotationIDs: overlapping,
+	}
 }
 
 type CreateAnnotationResponse struct {
 	AnnotationID string `json:"annotation_id"`
+	OverlapWarning
 }
 
-func toCreateAnnotationParams(r *CreateAnnotationRequest, userID string) (*model.CreateAnnotationParams, error) {
-	startTime, pErr := parseDuration(r.StartTime)
-	if pErr != nil {
-		return nil, fmt.Errorf("failed to parse start time: %w", pErr)
+type UpdateAnnotationResponse struct {
+	Message string `json:"message"`
+	OverlapWarning
+}
+
+func toCreateAnnotationParams(
+	r *CreateAnnotationRequest, userID string, frameRate frameRateFunc,
+) (*model.CreateAnnotationParams, error) {
+	p := &model.CreateAnnotationParams{
+		VideoID:     r.VideoID,
+		UserID:      userID,
+		Type:        model.AnnotationType(r.Type),
+		Message:     r.Message,
+		URL:         r.URL,
+		Title:       r.Title,
+		Payload:     r.Payload,
+		FrameParams: r.FrameParams,
 	}
-	endTime, pErr := parseDuration(r.EndTime)
-	if pErr != nil {
-		return nil, fmt.Errorf("failed to parse end time: %w", pErr)
+	var pErr error
+	if p.Region, pErr = toRegion(r.Region, frameRate); pErr != nil {
+		return nil, pErr
 	}
-	p := &model.CreateAnnotationParams{
-		VideoID:   r.VideoID,
-		UserID:    userID,
-		StartTime: startTime,
-		EndTime:   endTime,
-		Type:      model.ToAnnotationType(r.Type),
-		Message:   r.Message,
-		URL:       r.URL,
-		Title:     r.Title,
+	// start and end may be given as frame numbers instead
+	if r.StartTime != "" || r.StartFrame == nil {
+		if p.StartTime, pErr = parseDuration(r.StartTime, frameRate); pErr != nil {
+			return nil, fmt.Errorf("failed to parse start time: %w", pErr)
+		}
+	}
+	if r.EndTime != "" || r.EndFrame == nil {
+		if p.EndTime, pErr = parseDuration(r.EndTime, frameRate); pErr != nil {
+			return nil, fmt.Errorf("failed to parse end time: %w", pErr)
+		}
 	}
 	return p, nil
 }
@@ -60,16 +126,29 @@ func (s *Server) CreateAnnotation(w http.ResponseWriter, r *http.Request) {
 		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
 		return
 	}
-	p, pErr := toCreateAnnotationParams(req, userID)
+	// video id comes from the path in v1 api and from the body in deprecated one
+	if videoID, ok := mux.Vars(r)[entityIDKey]; ok {
+		req.VideoID = videoID
+	}
+	p, pErr := toCreateAnnotationParams(req, userID, s.videoFrameRate(r.Context(), req.VideoID))
 	if pErr != nil {
 		s.ErrorResponse(w, pErr, http.StatusBadRequest)
 		return
 	}
-	annotationID, err := s.controller.CreateAnnotation(r.Context(), p)
+	annotationID, overlapping, err := s.controller.CreateAnnotation(r.Context(), p)
+	var conflict *model.ConflictError
+	if errors.As(err, &conflict) {
+		s.ConflictResponse(w, fmt.Errorf("failed to create annotation: %w", err), conflict)
+		return
+	}
 	if errors.Is(err, model.ErrInvalidArgument) || errors.Is(err, model.ErrAlreadyExists) {
 		s.ErrorResponse(w, fmt.Errorf("failed to create annotation: %w", err), http.StatusBadRequest)
 		return
 	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to create annotation: %w", err), http.StatusForbidden)
+		return
+	}
 	if errors.Is(err, model.ErrNotFound) {
 		s.ErrorResponse(w, fmt.Errorf("failed to create annotation: %w", err), http.StatusNotFound)
 		return
@@ -78,7 +157,9 @@ func (s *Server) CreateAnnotation(w http.ResponseWriter, r *http.Request) {
 		s.ErrorResponse(w, fmt.Errorf("failed to create annotation: %w", err), http.StatusInternalServerError)
 		return
 	}
-	s.SuccessResponse(w, CreateAnnotationResponse{AnnotationID: annotationID})
+	s.SuccessResponse(w, CreateAnnotationResponse{
+		AnnotationID: annotationID, OverlapWarning: toOverlapWarning(overlapping),
+	})
 }
 
 type UpdateAnnotationRequest struct {
@@ -88,26 +169,42 @@ type UpdateAnnotationRequest struct {
 	Message   *string `json:"message,omitempty"`
 	URL       *string `json:"url,omitempty"`
 	Title     *string `json:"title,omitempty"`
+	// Payload replaces payload of the annotation, null removes it.
+	Payload json.RawMessage `json:"payload,omitempty"`
+
+	Region *RegionRequest `json:"region,omitempty"`
+	model.FrameParams
 }
 
-func toUpdateAnnotationParams(r *UpdateAnnotationRequest) (*model.UpdateAnnotationParams, error) {
+func toUpdateAnnotationParams(
+	r *UpdateAnnotationRequest, frameRate frameRateFunc,
+) (*model.UpdateAnnotationParams, error) {
 	var aType *model.AnnotationType
 	if r.Type != nil {
-		t := model.ToAnnotationType(*r.Type)
+		t := model.AnnotationType(*r.Type)
 		aType = &t
 	}
 	p := &model.UpdateAnnotationParams{
-		Type: aType, Message: r.Message, URL: r.URL, Title: r.Title,
+		Type:        aType,
+		Message:     r.Message,
+		URL:         r.URL,
+		Title:       r.Title,
+		Payload:     r.Payload,
+		FrameParams: r.FrameParams,
+	}
+	var rErr error
+	if p.Region, rErr = toRegion(r.Region, frameRate); rErr != nil {
+		return nil, rErr
 	}
 	if r.StartTime != nil {
-		startTime, pErr := parseDuration(*r.StartTime)
+		startTime, pErr := parseDuration(*r.StartTime, frameRate)
 		if pErr != nil {
 			return nil, fmt.Errorf("failed to parse start time: %w", pErr)
 		}
 		p.StartTime = &startTime
 	}
 	if r.EndTime != nil {
-		endTime, pErr := parseDuration(*r.EndTime)
+		endTime, pErr := parseDuration(*r.EndTime, frameRate)
 		if pErr != nil {
 			return nil, fmt.Errorf("failed to parse end time: %w", pErr)
 		}
@@ -122,16 +219,26 @@ func (s *Server) UpdateAnnotation(w http.ResponseWriter, r *http.Request) {
 		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
 		return
 	}
-	p, pErr := toUpdateAnnotationParams(req)
+	id := mux.Vars(r)[entityIDKey]
+	p, pErr := toUpdateAnnotationParams(req, s.annotationFrameRate(r.Context(), id))
 	if pErr != nil {
 		s.ErrorResponse(w, pErr, http.StatusBadRequest)
 		return
 	}
-	err := s.controller.UpdateAnnotation(r.Context(), mux.Vars(r)[entityIDKey], p)
+	overlapping, err := s.controller.UpdateAnnotation(r.Context(), id, p)
+	var conflict *model.ConflictError
+	if errors.As(err, &conflict) {
+		s.ConflictResponse(w, fmt.Errorf("failed to update annotation: %w", err), conflict)
+		return
+	}
 	if errors.Is(err, model.ErrInvalidArgument) || errors.Is(err, model.ErrAlreadyExists) {
 		s.ErrorResponse(w, fmt.Errorf("failed to update annotation: %w", err), http.StatusBadRequest)
 		return
 	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to update annotation: %w", err), http.StatusForbidden)
+		return
+	}
 	if errors.Is(err, model.ErrNotFound) {
 		s.ErrorResponse(w, fmt.Errorf("failed to update annotation: %w", err), http.StatusNotFound)
 		return
@@ -140,25 +247,231 @@ func (s *Server) UpdateAnnotation(w http.ResponseWriter, r *http.Request) {
 		s.ErrorResponse(w, fmt.Errorf("failed to update annotation: %w", err), http.StatusInternalServerError)
 		return
 	}
-	s.SuccessResponse(w, Response{Message: "annotation updated successfully"})
+	s.SuccessResponse(w, UpdateAnnotationResponse{
+		Message: "annotation updated successfully", OverlapWarning: toOverlapWarning(overlapping),
+	})
 }
 
 type ListAnnotationsResponse struct {
-	Annotations []*model.Annotation `json:"annotations"`
+	Annotations []*AnnotationResponse `json:"annotations"`
+	NextCursor  string                `json:"next_cursor,omitempty"`
+}
+
+func toListAnnotationsParams(query url.Values, p *controller.ListAnnotationsParams) error {
+	var err error
+	if p.Limit, err = queryInt(query, "limit"); err != nil {
+		return err
+	}
+	if p.From, err = queryDuration(query, "from"); err != nil {
+		return err
+	}
+	if p.To, err = queryDuration(query, "to"); err != nil {
+		return err
+	}
+	if p.CreatedAfter, err = queryTime(query, "created_after"); err != nil {
+		return err
+	}
+	if p.CreatedBefore, err = queryTime(query, "created_before"); err != nil {
+		return err
+	}
+	p.Cursor = query.Get("cursor")
+	p.Sort = query.Get("sort")
+	p.Order = query.Get("order")
+	p.Type = query.Get("type")
+	p.UserID = query.Get("user_id")
+	return nil
 }
 
 func (s *Server) ListAnnotations(w http.ResponseWriter, r *http.Request) {
+	formatter, tErr := s.timeFormatter(r)
+	if tErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", tErr), http.StatusBadRequest)
+		return
+	}
 	req := &controller.ListAnnotationsParams{}
-	if dErr := json.NewDecoder(r.Body).Decode(req); dErr != nil {
+	if videoID, ok := mux.Vars(r)[entityIDKey]; ok {
+		if qErr := toListAnnotationsParams(r.URL.Query(), req); qErr != nil {
+			s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", qErr), http.StatusBadRequest)
+			return
+		}
+		req.VideoID = videoID
+	} else if dErr := json.NewDecoder(r.Body).Decode(req); dErr != nil {
 		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
 		return
 	}
-	annotations, err := s.controller.ListAnnotations(r.Context(), req)
+	annotations, next, err := s.controller.ListAnnotations(r.Context(), req)
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list annotations: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list annotations: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list annotations: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to list annotations: %w", err), http.StatusInternalServerError)
+		return
+	}
+	resp, fErr := formatter.annotations(annotations)
+	if fErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to format annotations: %w", fErr), http.StatusBadRequest)
+		return
+	}
+	s.SuccessResponse(w, &ListAnnotationsResponse{Annotations: resp, NextCursor: next})
+}
+
+// ListAnnotationsAt responds with annotations shown at the playhead t.
+func (s *Server) ListAnnotationsAt(w http.ResponseWriter, r *http.Request) {
+	t, qErr := queryDuration(r.URL.Query(), "t")
+	if qErr == nil && t == nil {
+		qErr = errors.New("missing t")
+	}
+	if qErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", qErr), http.StatusBadRequest)
+		return
+	}
+	s.listAnnotationsInRange(w, r, *t, *t)
+}
+
+// ListAnnotationsInRange responds with annotations intersecting [from, to], e.g. for prefetching.
+func (s *Server) ListAnnotationsInRange(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, fErr := queryDuration(query, "from")
+	if fErr == nil && from == nil {
+		fErr = errors.New("missing from")
+	}
+	if fErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", fErr), http.StatusBadRequest)
+		return
+	}
+	to, tErr := queryDuration(query, "to")
+	if tErr == nil && to == nil {
+		tErr = errors.New("missing to")
+	}
+	if tErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", tErr), http.StatusBadRequest)
+		return
+	}
+	s.listAnnotationsInRange(w, r, *from, *to)
+}
+
+func (s *Server) listAnnotationsInRange(w http.ResponseWriter, r *http.Request, from, to time.Duration) {
+	formatter, tErr := s.timeFormatter(r)
+	if tErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", tErr), http.StatusBadRequest)
+		return
+	}
+	annotations, err := s.controller.ListAnnotationsInRange(r.Context(), mux.Vars(r)[entityIDKey], from, to)
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list annotations: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list annotations: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list annotations: %w", err), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		s.ErrorResponse(w, fmt.Errorf("failed to list annotations: %w", err), http.StatusInternalServerError)
 		return
 	}
-	s.SuccessResponse(w, &ListAnnotationsResponse{Annotations: annotations})
+	resp, fErr := formatter.annotations(annotations)
+	if fErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to format annotations: %w", fErr), http.StatusBadRequest)
+		return
+	}
+	// players asking for a moment get regions already moved along keyframes
+	if from == to {
+		for i, a := range annotations {
+			if a.Region != nil {
+				resp[i].RegionAt = a.Region.At(a.StartTime, from)
+			}
+		}
+	}
+	s.SuccessResponse(w, &ListAnnotationsResponse{Annotations: resp})
+}
+
+const expandVideo = "video"
+
+type GetAnnotationResponse struct {
+	*AnnotationResponse
+	Video *VideoResponse `json:"video,omitempty"`
+}
+
+// GetAnnotation responds with the annotation, ?expand=video embeds the video of the annotation.
+func (s *Server) GetAnnotation(w http.ResponseWriter, r *http.Request) {
+	formatter, tErr := s.timeFormatter(r)
+	if tErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", tErr), http.StatusBadRequest)
+		return
+	}
+	var withVideo bool
+	if expand := r.URL.Query().Get("expand"); expand != "" {
+		for _, e := range strings.Split(expand, ",") {
+			if e != expandVideo {
+				s.ErrorResponse(w, fmt.Errorf("unknown expand %q", e), http.StatusBadRequest)
+				return
+			}
+			withVideo = true
+		}
+	}
+
+	annotation, err := s.controller.GetAnnotation(r.Context(), mux.Vars(r)[entityIDKey])
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to get annotation: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to get annotation: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to get annotation: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to get annotation: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	var video *model.Video
+	if withVideo {
+		video, err = s.controller.GetVideo(r.Context(), annotation.VideoID)
+		if errors.Is(err, model.ErrForbidden) {
+			s.ErrorResponse(w, fmt.Errorf("failed to get video: %w", err), http.StatusForbidden)
+			return
+		}
+		// video is deleted meanwhile
+		if errors.Is(err, model.ErrNotFound) {
+			s.ErrorResponse(w, fmt.Errorf("failed to get video: %w", err), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			s.ErrorResponse(w, fmt.Errorf("failed to get video: %w", err), http.StatusInternalServerError)
+			return
+		}
+		formatter.rates[video.ID] = video.Rate()
+	}
+	resp := &GetAnnotationResponse{}
+	var fErr error
+	if resp.AnnotationResponse, fErr = formatter.annotation(annotation); fErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to format annotation: %w", fErr), http.StatusBadRequest)
+		return
+	}
+	if video != nil {
+		if resp.Video, fErr = formatter.video(video); fErr != nil {
+			s.ErrorResponse(w, fmt.Errorf("failed to format video: %w", fErr), http.StatusBadRequest)
+			return
+		}
+	}
+	s.SuccessResponse(w, resp)
 }
 
 func (s *Server) DeleteAnnotation(w http.ResponseWriter, r *http.Request) {
@@ -167,6 +480,14 @@ func (s *Server) DeleteAnnotation(w http.ResponseWriter, r *http.Request) {
 		s.ErrorResponse(w, fmt.Errorf("failed to delete annotation: %w", err), http.StatusBadRequest)
 		return
 	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to delete annotation: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to delete annotation: %w", err), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		s.ErrorResponse(w, fmt.Errorf("failed to delete annotation: %w", err), http.StatusInternalServerError)
 		return
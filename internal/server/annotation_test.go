@@ -0,0 +1,223 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// fakeVideoController returns the annotation of the video and fails to get the video with videoErr.
+type fakeVideoController struct {
+	Controller
+	videoErr error
+}
+
+func (c *fakeVideoController) GetAnnotation(_ context.Context, id string) (*model.Annotation, error) {
+	return &model.Annotation{
+		ID: id, VideoID: "video", Type: model.TextAnnotationType, Message: "text", EndTime: time.Second,
+	}, nil
+}
+
+func (c *fakeVideoController) GetVideo(_ context.Context, id string) (*model.Video, error) {
+	if c.videoErr != nil {
+		return nil, c.videoErr
+	}
+	return &model.Video{ID: id, Duration: time.Minute}, nil
+}
+
+func TestGetAnnotationExpandVideo(t *testing.T) {
+	tests := []struct {
+		name     string
+		videoErr error
+		wantCode int
+	}{
+		{name: "video", wantCode: http.StatusOK},
+		{name: "forbidden", videoErr: fmt.Errorf("read permission: %w", model.ErrForbidden), wantCode: http.StatusForbidden},
+		{name: "deleted", videoErr: fmt.Errorf("video: %w", model.ErrNotFound), wantCode: http.StatusNotFound},
+		{name: "storage error", videoErr: fmt.Errorf("connection refused"), wantCode: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(zap.NewNop(), &Config{}, nil, nil, &fakeVideoController{videoErr: tt.videoErr})
+			r := httptest.NewRequest(http.MethodGet, "/v1/annotations/annotation?expand=video", http.NoBody)
+			r = mux.SetURLVars(r, map[string]string{entityIDKey: "annotation"})
+			w := httptest.NewRecorder()
+			s.GetAnnotation(w, r)
+			if w.Code != tt.wantCode {
+				t.Errorf("GetAnnotation() code = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+		})
+	}
+}
+
+func TestListAnnotationsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "annotations", wantCode: http.StatusOK},
+		{name: "invalid", err: fmt.Errorf("cursor: %w", model.ErrInvalidArgument), wantCode: http.StatusBadRequest},
+		{name: "forbidden", err: fmt.Errorf("read permission: %w", model.ErrForbidden), wantCode: http.StatusForbidden},
+		{name: "unknown video", err: fmt.Errorf("video: %w", model.ErrNotFound), wantCode: http.StatusNotFound},
+		{name: "storage error", err: fmt.Errorf("connection refused"), wantCode: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(zap.NewNop(), &Config{}, nil, nil, &fakeListController{err: tt.err})
+			r := httptest.NewRequest(http.MethodGet, "/v1/videos/video/annotations", http.NoBody)
+			r = mux.SetURLVars(r, map[string]string{entityIDKey: "video"})
+			w := httptest.NewRecorder()
+			s.ListAnnotations(w, r)
+			if w.Code != tt.wantCode {
+				t.Errorf("ListAnnotations() code = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+		})
+	}
+}
+
+func TestListAnnotationsInRangeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "annotations", wantCode: http.StatusOK},
+		{name: "invalid", err: fmt.Errorf("range: %w", model.ErrInvalidArgument), wantCode: http.StatusBadRequest},
+		{name: "forbidden", err: fmt.Errorf("read permission: %w", model.ErrForbidden), wantCode: http.StatusForbidden},
+		{name: "unknown video", err: fmt.Errorf("video: %w", model.ErrNotFound), wantCode: http.StatusNotFound},
+		{name: "storage error", err: fmt.Errorf("connection refused"), wantCode: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		for target, handler := range map[string]func(*Server) http.HandlerFunc{
+			"/v1/videos/video/annotations/at?t=1s": func(s *Server) http.HandlerFunc { return s.ListAnnotationsAt },
+			"/v1/videos/video/annotations/range?from=1s&to=2s": func(s *Server) http.HandlerFunc {
+				return s.ListAnnotationsInRange
+			},
+		} {
+			t.Run(tt.name+" "+target, func(t *testing.T) {
+				s := New(zap.NewNop(), &Config{}, nil, nil, &fakeListController{err: tt.err})
+				r := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+				r = mux.SetURLVars(r, map[string]string{entityIDKey: "video"})
+				w := httptest.NewRecorder()
+				handler(s)(w, r)
+				if w.Code != tt.wantCode {
+					t.Errorf("code = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+				}
+			})
+		}
+	}
+}
+
+// fakeDeleteController fails deletes with err.
+type fakeDeleteController struct {
+	Controller
+	err error
+}
+
+func (c *fakeDeleteController) DeleteVideo(_ context.Context, _ string) error {
+	return c.err
+}
+
+func (c *fakeDeleteController) DeleteAnnotation(_ context.Context, _ string) error {
+	return c.err
+}
+
+func TestDeleteErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "deleted", wantCode: http.StatusOK},
+		{name: "not owner", err: fmt.Errorf("video: %w", model.ErrForbidden), wantCode: http.StatusForbidden},
+		{name: "missing", err: fmt.Errorf("video: %w", model.ErrNotFound), wantCode: http.StatusNotFound},
+		{name: "storage error", err: fmt.Errorf("connection refused"), wantCode: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		for target, handler := range map[string]func(*Server) http.HandlerFunc{
+			"DeleteVideo":      func(s *Server) http.HandlerFunc { return s.DeleteVideo },
+			"DeleteAnnotation": func(s *Server) http.HandlerFunc { return s.DeleteAnnotation },
+		} {
+			t.Run(target+"/"+tt.name, func(t *testing.T) {
+				s := New(zap.NewNop(), &Config{}, nil, nil, &fakeDeleteController{err: tt.err})
+				r := httptest.NewRequest(http.MethodDelete, "/v1/entity/id", http.NoBody)
+				r = mux.SetURLVars(r, map[string]string{entityIDKey: "id"})
+				w := httptest.NewRecorder()
+				handler(s)(w, r)
+				if w.Code != tt.wantCode {
+					t.Errorf("%s() code = %d, want %d: %s", target, w.Code, tt.wantCode, w.Body)
+				}
+			})
+		}
+	}
+}
+
+// fakeRangeController records range of the request.
+type fakeRangeController struct {
+	Controller
+	from, to time.Duration
+	called   bool
+}
+
+func (c *fakeRangeController) ListAnnotationsInRange(
+	_ context.Context, _ string, from, to time.Duration,
+) ([]*model.Annotation, error) {
+	c.from, c.to, c.called = from, to, true
+	return nil, nil
+}
+
+func TestListAnnotationsInRangeParams(t *testing.T) {
+	tests := []struct {
+		target   string
+		wantFrom time.Duration
+		wantTo   time.Duration
+		wantCode int
+	}{
+		{
+			target:   "/v1/videos/video/annotations/at?t=1m32s",
+			wantFrom: 92 * time.Second, wantTo: 92 * time.Second, wantCode: http.StatusOK,
+		},
+		{
+			target:   "/v1/videos/video/annotations/at?t=1.5s",
+			wantFrom: 1500 * time.Millisecond, wantTo: 1500 * time.Millisecond, wantCode: http.StatusOK,
+		},
+		{target: "/v1/videos/video/annotations/at", wantCode: http.StatusBadRequest},
+		{target: "/v1/videos/video/annotations/at?t=soon", wantCode: http.StatusBadRequest},
+		{
+			target:   "/v1/videos/video/annotations/range?from=10s&to=1m",
+			wantFrom: 10 * time.Second, wantTo: time.Minute, wantCode: http.StatusOK,
+		},
+		{target: "/v1/videos/video/annotations/range?from=10s", wantCode: http.StatusBadRequest},
+		{target: "/v1/videos/video/annotations/range?to=10s", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			ctrl := &fakeRangeController{}
+			s := New(zap.NewNop(), &Config{}, nil, nil, ctrl)
+			r := httptest.NewRequest(http.MethodGet, tt.target, http.NoBody)
+			r = mux.SetURLVars(r, map[string]string{entityIDKey: "video"})
+			w := httptest.NewRecorder()
+			if strings.Contains(tt.target, "/at") {
+				s.ListAnnotationsAt(w, r)
+			} else {
+				s.ListAnnotationsInRange(w, r)
+			}
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if ctrl.called != (tt.wantCode == http.StatusOK) || ctrl.from != tt.wantFrom || ctrl.to != tt.wantTo {
+				t.Errorf("range = [%v, %v], want [%v, %v]", ctrl.from, ctrl.to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
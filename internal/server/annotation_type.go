@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/controller"
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+type ListAnnotationTypesResponse struct {
+	AnnotationTypes []*model.AnnotationTypeDef `json:"annotation_types"`
+}
+
+func (s *Server) ListAnnotationTypes(w http.ResponseWriter, r *http.Request) {
+	types, err := s.controller.ListAnnotationTypes(r.Context())
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to list annotation types: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, &ListAnnotationTypesResponse{AnnotationTypes: types})
+}
+
+func (s *Server) CreateAnnotationType(w http.ResponseWriter, r *http.Request) {
+	req := &controller.CreateAnnotationTypeParams{}
+	if dErr := json.NewDecoder(r.Body).Decode(req); dErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
+		return
+	}
+	err := s.controller.CreateAnnotationType(r.Context(), req)
+	if errors.Is(err, model.ErrInvalidArgument) || errors.Is(err, model.ErrAlreadyExists) {
+		s.ErrorResponse(w, fmt.Errorf("failed to create annotation type: %w", err), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to create annotation type: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, Response{Message: "annotation type created successfully"})
+}
+
+func (s *Server) DeleteAnnotationType(w http.ResponseWriter, r *http.Request) {
+	err := s.controller.DeleteAnnotationType(r.Context(), mux.Vars(r)[entityIDKey])
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to delete annotation type: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to delete annotation type: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to delete annotation type: %w", err), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, model.ErrConflict) {
+		s.ErrorResponse(w, fmt.Errorf("failed to delete annotation type: %w", err), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to delete annotation type: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, Response{Message: "annotation type deleted successfully"})
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/controller"
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+type CreateAPIKeyResponse struct {
+	APIKeyID string `json:"api_key_id"`
+	Key      string `json:"key"`
+}
+
+func (s *Server) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	req := &controller.CreateAPIKeyParams{}
+	if dErr := json.NewDecoder(r.Body).Decode(req); dErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
+		return
+	}
+	id, key, err := s.controller.CreateAPIKey(r.Context(), req)
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to create api key: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to create api key: %w", err), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to create api key: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, &CreateAPIKeyResponse{APIKeyID: id, Key: key})
+}
+
+type ListAPIKeysResponse struct {
+	APIKeys []*model.APIKey `json:"api_keys"`
+}
+
+func (s *Server) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.controller.ListAPIKeys(r.Context())
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list api keys: %w", err), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to list api keys: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, &ListAPIKeysResponse{APIKeys: keys})
+}
+
+func (s *Server) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	err := s.controller.DeleteAPIKey(r.Context(), mux.Vars(r)[entityIDKey])
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to delete api key: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to delete api key: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to delete api key: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to delete api key: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, Response{Message: "api key deleted successfully"})
+}
@@ -3,61 +3,130 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 
-	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
 
+	"github.com/triabokon/gotagv/internal/controller"
 	"github.com/triabokon/gotagv/internal/model"
 )
 
 type SignUpResponse struct {
-	UserID string `json:"user_id"`
-	Token  string `json:"token"`
+	UserID       string `json:"user_id"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 func (s *Server) SignUp(w http.ResponseWriter, r *http.Request) {
-	userID := uuid.New()
-	err := s.controller.CreateUser(r.Context(), userID)
+	req := &controller.SignUpParams{}
+	if dErr := json.NewDecoder(r.Body).Decode(req); dErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
+		return
+	}
+	user, err := s.controller.SignUp(r.Context(), req)
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to create user: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrAlreadyExists) {
+		s.ErrorResponse(w, fmt.Errorf("email is already taken"), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		s.ErrorResponse(w, fmt.Errorf("failed to create user: %w", err), http.StatusInternalServerError)
 		return
 	}
 
-	tokenString, err := s.auth.CreateToken(userID)
+	tokens, err := s.auth.IssueTokens(r.Context(), user)
 	if err != nil {
 		s.ErrorResponse(w, fmt.Errorf("failed to create jwt token: %w", err), http.StatusInternalServerError)
 		return
 	}
-	s.SuccessResponse(w, &SignUpResponse{UserID: userID, Token: tokenString})
-}
-
-type SignInRequest struct {
-	UserID string `json:"user_id,omitempty"`
+	s.SuccessResponse(w, &SignUpResponse{UserID: user.ID, Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken})
 }
 
 type SignInResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 func (s *Server) SignIn(w http.ResponseWriter, r *http.Request) {
-	req := &SignInRequest{}
-	err := json.NewDecoder(r.Body).Decode(req)
-	if err != nil {
-		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", err), http.StatusBadRequest)
+	req := &controller.SignInParams{}
+	if dErr := json.NewDecoder(r.Body).Decode(req); dErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
 		return
 	}
-	err = s.controller.GetUser(r.Context(), req.UserID)
-	if err == model.ErrNotFound {
-		s.ErrorResponse(w, fmt.Errorf("no such user"), http.StatusUnauthorized)
+	user, err := s.controller.SignIn(r.Context(), req)
+	if errors.Is(err, model.ErrUnauthenticated) {
+		s.ErrorResponse(w, fmt.Errorf("invalid email or password"), http.StatusUnauthorized)
 		return
-	} else if err != nil {
-		s.ErrorResponse(w, fmt.Errorf("failed to get user: %w", err), http.StatusInternalServerError)
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to sign in: %w", err), http.StatusInternalServerError)
 		return
 	}
-	tokenString, err := s.auth.CreateToken(req.UserID)
+	tokens, err := s.auth.IssueTokens(r.Context(), user)
 	if err != nil {
 		s.ErrorResponse(w, fmt.Errorf("failed to create jwt token: %w", err), http.StatusInternalServerError)
 		return
 	}
-	s.SuccessResponse(w, &SignInResponse{Token: tokenString})
+	s.SuccessResponse(w, &SignInResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken})
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (s *Server) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	req := &RefreshTokenRequest{}
+	if dErr := json.NewDecoder(r.Body).Decode(req); dErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
+		return
+	}
+	tokens, err := s.auth.RefreshTokens(r.Context(), req.RefreshToken)
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to refresh token: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrUnauthenticated) || errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to refresh token: %w", err), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to refresh token: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, &SignInResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken})
+}
+
+type SignOutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+func (s *Server) SignOut(w http.ResponseWriter, r *http.Request) {
+	req := &SignOutRequest{}
+	// request body is optional, only access token is revoked without it
+	if dErr := json.NewDecoder(r.Body).Decode(req); dErr != nil && !errors.Is(dErr, io.EOF) {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
+		return
+	}
+	err := s.auth.SignOut(r.Context(), req.RefreshToken)
+	if errors.Is(err, model.ErrUnauthenticated) {
+		s.ErrorResponse(w, fmt.Errorf("failed to sign out: %w", err), http.StatusUnauthorized)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to sign out: %w", err), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to sign out: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, Response{Message: "signed out successfully"})
+}
+
+func (s *Server) JWKS(w http.ResponseWriter, _ *http.Request) {
+	s.SuccessResponse(w, s.auth.JWKS())
 }
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/captions"
+	"github.com/triabokon/gotagv/internal/controller"
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// ExportAnnotations returns handler responding with annotations of the video as caption track
+// of the format ordered by start time, ?type= selects annotations of given types and may be repeated.
+func (s *Server) ExportAnnotations(format captions.Format) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		types := make(map[model.AnnotationType]bool)
+		for _, t := range r.URL.Query()["type"] {
+			types[model.AnnotationType(t)] = true
+		}
+		req := &controller.ListAnnotationsParams{
+			VideoID: mux.Vars(r)[entityIDKey], Sort: string(model.StartTimeSortField),
+		}
+		// single type is filtered by storage, several ones are filtered below
+		if len(types) == 1 {
+			req.Type = r.URL.Query().Get("type")
+		}
+		annotations, _, err := s.controller.ListAnnotations(r.Context(), req)
+		if errors.Is(err, model.ErrInvalidArgument) {
+			s.ErrorResponse(w, fmt.Errorf("failed to export annotations: %w", err), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, model.ErrForbidden) {
+			s.ErrorResponse(w, fmt.Errorf("failed to export annotations: %w", err), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, model.ErrNotFound) {
+			s.ErrorResponse(w, fmt.Errorf("failed to export annotations: %w", err), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			s.ErrorResponse(w, fmt.Errorf("failed to export annotations: %w", err), http.StatusInternalServerError)
+			return
+		}
+		var selected []*model.Annotation
+		for _, a := range annotations {
+			if len(types) == 0 || types[a.Type] {
+				selected = append(selected, a)
+			}
+		}
+		body, rErr := captions.Render(format, selected)
+		if rErr != nil {
+			s.ErrorResponse(w, fmt.Errorf("failed to export annotations: %w", rErr), http.StatusInternalServerError)
+			return
+		}
+		s.TextResponse(w, body, format.ContentType())
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"github.com/triabokon/gotagv/internal/captions"
+	"github.com/triabokon/gotagv/internal/controller"
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// fakeListController lists annotations of the video or fails with err.
+type fakeListController struct {
+	Controller
+	err error
+}
+
+func (c *fakeListController) ListAnnotations(
+	_ context.Context, _ *controller.ListAnnotationsParams,
+) ([]*model.Annotation, string, error) {
+	if c.err != nil {
+		return nil, "", c.err
+	}
+	return []*model.Annotation{
+		{Type: model.TextAnnotationType, Message: "text", EndTime: time.Second},
+		{Type: model.TitleAnnotationType, Title: "title", StartTime: time.Second, EndTime: 2 * time.Second},
+	}, "", nil
+}
+
+func (c *fakeListController) ListAnnotationsInRange(
+	ctx context.Context, videoID string, _, _ time.Duration,
+) ([]*model.Annotation, error) {
+	annotations, _, err := c.ListAnnotations(ctx, &controller.ListAnnotationsParams{VideoID: videoID})
+	return annotations, err
+}
+
+func TestExportAnnotations(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		err      error
+		wantCode int
+		wantBody string
+	}{
+		{name: "all", wantCode: http.StatusOK, wantBody: "WEBVTT\n\n1\n00:00:00.000 --> 00:00:01.000\ntext\n\n" +
+			"2 title\n00:00:01.000 --> 00:00:02.000\ntitle\n"},
+		{name: "several types", query: "?type=title&type=chapter", wantCode: http.StatusOK,
+			wantBody: "WEBVTT\n\n1 title\n00:00:01.000 --> 00:00:02.000\ntitle\n"},
+		{name: "invalid", err: fmt.Errorf("cursor: %w", model.ErrInvalidArgument), wantCode: http.StatusBadRequest},
+		{name: "forbidden", err: fmt.Errorf("read permission: %w", model.ErrForbidden), wantCode: http.StatusForbidden},
+		{name: "missing video", err: fmt.Errorf("video: %w", model.ErrNotFound), wantCode: http.StatusNotFound},
+		{name: "storage error", err: fmt.Errorf("connection refused"), wantCode: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(zap.NewNop(), &Config{}, nil, nil, &fakeListController{err: tt.err})
+			r := httptest.NewRequest(http.MethodGet, "/v1/videos/video/annotations.vtt"+tt.query, http.NoBody)
+			r = mux.SetURLVars(r, map[string]string{entityIDKey: "video"})
+			w := httptest.NewRecorder()
+			s.ExportAnnotations(captions.VTTFormat)(w, r)
+			if w.Code != tt.wantCode {
+				t.Fatalf("ExportAnnotations() code = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("ExportAnnotations() body = %q, want %q", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+const (
+	fillGapsParam = "fill_gaps"
+	formatParam   = "format"
+	// youtubeFormat renders chapters as lines of YouTube video description, e.g. 00:00 Intro.
+	youtubeFormat = "youtube"
+)
+
+// ListChapters responds with chapters of the video ordered by start time, ?fill_gaps=true stretches them
+// to cover the whole video and ?format=youtube renders them as text for YouTube description.
+func (s *Server) ListChapters(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	fillGaps, qErr := queryBool(query, fillGapsParam)
+	if qErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", qErr), http.StatusBadRequest)
+		return
+	}
+	format := query.Get(formatParam)
+	if format != "" && format != youtubeFormat {
+		s.ErrorResponse(w, fmt.Errorf("unknown format %q", format), http.StatusBadRequest)
+		return
+	}
+	formatter, tErr := s.timeFormatter(r)
+	if tErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", tErr), http.StatusBadRequest)
+		return
+	}
+	chapters, err := s.controller.ListChapters(r.Context(), mux.Vars(r)[entityIDKey], fillGaps)
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list chapters: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list chapters: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list chapters: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to list chapters: %w", err), http.StatusInternalServerError)
+		return
+	}
+	if format == youtubeFormat {
+		s.TextResponse(w, youtubeChapters(chapters), "text/plain; charset=utf-8")
+		return
+	}
+	resp, fErr := formatter.annotations(chapters)
+	if fErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to format chapters: %w", fErr), http.StatusBadRequest)
+		return
+	}
+	s.SuccessResponse(w, &ListAnnotationsResponse{Annotations: resp})
+}
+
+// youtubeChapters renders a line with start and title per chapter, hours are shown
+// only if some chapter starts after the first hour. YouTube ignores chapters which don't start
+// at 00:00, so the first chapter is stretched to the start of the video as with fill_gaps.
+func youtubeChapters(chapters []*model.Annotation) string {
+	withHours := len(chapters) > 0 && chapters[len(chapters)-1].StartTime >= time.Hour
+	var b strings.Builder
+	for i, ch := range chapters {
+		var secs int64
+		if i > 0 {
+			secs = int64(ch.StartTime / time.Second)
+		}
+		if withHours {
+			fmt.Fprintf(&b, "%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+		} else {
+			fmt.Fprintf(&b, "%02d:%02d", secs/60, secs%60)
+		}
+		// titles are single line in the description
+		fmt.Fprintf(&b, " %s\n", strings.Join(strings.Fields(ch.Title), " "))
+	}
+	return b.String()
+}
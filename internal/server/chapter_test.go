@@ -0,0 +1,43 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+func TestYoutubeChapters(t *testing.T) {
+	chapter := func(start time.Duration, title string) *model.Annotation {
+		return &model.Annotation{Type: model.ChapterAnnotationType, StartTime: start, Title: title}
+	}
+	tests := []struct {
+		name     string
+		chapters []*model.Annotation
+		want     string
+	}{
+		{name: "no chapters", want: ""},
+		{
+			name:     "starting at zero",
+			chapters: []*model.Annotation{chapter(0, "Intro"), chapter(90500*time.Millisecond, "Main\n part")},
+			want:     "00:00 Intro\n01:30 Main part\n",
+		},
+		{
+			name:     "first chapter after zero",
+			chapters: []*model.Annotation{chapter(15*time.Second, "Intro"), chapter(time.Minute, "Main")},
+			want:     "00:00 Intro\n01:00 Main\n",
+		},
+		{
+			name:     "with hours",
+			chapters: []*model.Annotation{chapter(0, "Intro"), chapter(time.Hour+2*time.Minute+3*time.Second, "Late")},
+			want:     "0:00:00 Intro\n1:02:03 Late\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := youtubeChapters(tt.chapters); got != tt.want {
+				t.Errorf("youtubeChapters() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
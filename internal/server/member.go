@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/controller"
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+type AddVideoMemberRequest struct {
+	UserID     string `json:"user_id"`
+	Permission string `json:"permission"`
+}
+
+func (s *Server) AddVideoMember(w http.ResponseWriter, r *http.Request) {
+	req := &AddVideoMemberRequest{}
+	if dErr := json.NewDecoder(r.Body).Decode(req); dErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
+		return
+	}
+	err := s.controller.AddVideoMember(r.Context(), mux.Vars(r)[entityIDKey], &controller.AddVideoMemberParams{
+		UserID:     req.UserID,
+		Permission: model.ToMemberPermission(req.Permission),
+	})
+	if errors.Is(err, model.ErrInvalidArgument) || errors.Is(err, model.ErrAlreadyExists) {
+		s.ErrorResponse(w, fmt.Errorf("failed to add video member: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to add video member: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to add video member: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to add video member: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, Response{Message: "video member added successfully"})
+}
+
+type ListVideoMembersResponse struct {
+	Members []*model.VideoMember `json:"members"`
+}
+
+func (s *Server) ListVideoMembers(w http.ResponseWriter, r *http.Request) {
+	members, err := s.controller.ListVideoMembers(r.Context(), mux.Vars(r)[entityIDKey])
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list video members: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list video members: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list video members: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to list video members: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, &ListVideoMembersResponse{Members: members})
+}
+
+func (s *Server) RemoveVideoMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	err := s.controller.RemoveVideoMember(r.Context(), vars[entityIDKey], vars[userIDKey])
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to remove video member: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to remove video member: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to remove video member: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to remove video member: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, Response{Message: "video member removed successfully"})
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/auth"
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+const (
+	// oidcCookie keeps state and nonce of the login between redirects.
+	oidcCookie       = "gotagv_oidc"
+	oidcCookiePath   = "/oidc"
+	oidcCookieMaxAge = 600
+)
+
+func (s *Server) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	state, err := auth.NewOpaqueToken()
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to create state: %w", err), http.StatusInternalServerError)
+		return
+	}
+	nonce, err := auth.NewOpaqueToken()
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to create nonce: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := s.auth.OIDCAuthURL(r.Context(), state, nonce)
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to login: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to login: %w", err), http.StatusBadGateway)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    state + "." + nonce,
+		Path:     oidcCookiePath,
+		MaxAge:   oidcCookieMaxAge,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (s *Server) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if e := query.Get("error"); e != "" {
+		s.ErrorResponse(w, fmt.Errorf("login failed: %s: %s", e, query.Get("error_description")), http.StatusUnauthorized)
+		return
+	}
+	cookie, err := r.Cookie(oidcCookie)
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("login wasn't started"), http.StatusBadRequest)
+		return
+	}
+	// login can be finished only once
+	http.SetCookie(w, &http.Cookie{Name: oidcCookie, Path: oidcCookiePath, MaxAge: -1})
+	state, nonce, ok := strings.Cut(cookie.Value, ".")
+	if !ok || state == "" || state != query.Get("state") {
+		s.ErrorResponse(w, fmt.Errorf("invalid login state"), http.StatusBadRequest)
+		return
+	}
+
+	identity, err := s.auth.OIDCExchange(r.Context(), query.Get("code"), nonce)
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to login: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrUnauthenticated) {
+		s.ErrorResponse(w, fmt.Errorf("failed to login: %w", err), http.StatusUnauthorized)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to login: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to login: %w", err), http.StatusBadGateway)
+		return
+	}
+
+	user, err := s.controller.SignInOIDC(r.Context(), identity)
+	if errors.Is(err, model.ErrAlreadyExists) {
+		s.ErrorResponse(w, fmt.Errorf("email is already taken by another account"), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to login: %w", err), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to login: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	tokens, err := s.auth.IssueTokens(r.Context(), user)
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to create jwt token: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, &SignUpResponse{UserID: user.ID, Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken})
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/triabokon/gotagv/internal/auth"
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// fakeOIDCAuth accepts testCode issued with the nonce, methods which tests don't use panic on the nil Auth.
+type fakeOIDCAuth struct {
+	Auth
+	nonce     string
+	exchanged bool
+}
+
+const testCode = "code"
+
+func (a *fakeOIDCAuth) OIDCAuthURL(_ context.Context, state, nonce string) (string, error) {
+	a.nonce = nonce
+	return "https://idp.example.com/authorize?" + url.Values{"state": {state}, "nonce": {nonce}}.Encode(), nil
+}
+
+func (a *fakeOIDCAuth) OIDCExchange(_ context.Context, code, nonce string) (*model.OIDCIdentity, error) {
+	a.exchanged = true
+	if code != testCode || nonce != a.nonce {
+		return nil, model.ErrUnauthenticated
+	}
+	return &model.OIDCIdentity{Issuer: "https://idp.example.com", Subject: "subject"}, nil
+}
+
+func (a *fakeOIDCAuth) IssueTokens(_ context.Context, u *model.User) (*auth.Tokens, error) {
+	return &auth.Tokens{AccessToken: "access-" + u.ID, RefreshToken: "refresh-" + u.ID}, nil
+}
+
+// fakeOIDCController signs in every identity as the same user.
+type fakeOIDCController struct {
+	Controller
+}
+
+func (c *fakeOIDCController) SignInOIDC(_ context.Context, identity *model.OIDCIdentity) (*model.User, error) {
+	return &model.User{ID: identity.Subject}, nil
+}
+
+func TestOIDCCallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		cookie       func(state, nonce string) string
+		query        func(state string) url.Values
+		wantCode     int
+		wantExchange bool
+	}{
+		{
+			name:         "valid state",
+			cookie:       func(state, nonce string) string { return state + "." + nonce },
+			query:        func(state string) url.Values { return url.Values{"state": {state}, "code": {testCode}} },
+			wantCode:     http.StatusOK,
+			wantExchange: true,
+		},
+		{
+			name:     "no cookie",
+			query:    func(state string) url.Values { return url.Values{"state": {state}, "code": {testCode}} },
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "state mismatch",
+			cookie:   func(state, nonce string) string { return state + "." + nonce },
+			query:    func(string) url.Values { return url.Values{"state": {"other"}, "code": {testCode}} },
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "no state",
+			cookie:   func(state, nonce string) string { return state + "." + nonce },
+			query:    func(string) url.Values { return url.Values{"code": {testCode}} },
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "empty cookie state",
+			cookie:   func(_, nonce string) string { return "." + nonce },
+			query:    func(string) url.Values { return url.Values{"state": {""}, "code": {testCode}} },
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:         "nonce of another login",
+			cookie:       func(state, _ string) string { return state + ".other" },
+			query:        func(state string) url.Values { return url.Values{"state": {state}, "code": {testCode}} },
+			wantCode:     http.StatusUnauthorized,
+			wantExchange: true,
+		},
+		{
+			name:     "provider error",
+			query:    func(string) url.Values { return url.Values{"error": {"access_denied"}} },
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &fakeOIDCAuth{}
+			s := New(zap.NewNop(), &Config{}, a, nil, &fakeOIDCController{})
+
+			login := httptest.NewRecorder()
+			s.OIDCLogin(login, httptest.NewRequest(http.MethodGet, "/oidc/login", http.NoBody))
+			if login.Code != http.StatusFound {
+				t.Fatalf("OIDCLogin() code = %d, want %d", login.Code, http.StatusFound)
+			}
+			redirect, err := url.Parse(login.Header().Get("Location"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			state := redirect.Query().Get("state")
+			cookies := login.Result().Cookies()
+			if len(cookies) != 1 || cookies[0].Value != state+"."+a.nonce || !cookies[0].HttpOnly {
+				t.Fatalf("OIDCLogin() cookies = %v, want state and nonce", cookies)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/oidc/callback?"+tt.query(state).Encode(), http.NoBody)
+			if tt.cookie != nil {
+				r.AddCookie(&http.Cookie{Name: oidcCookie, Value: tt.cookie(state, a.nonce)})
+			}
+			w := httptest.NewRecorder()
+			s.OIDCCallback(w, r)
+			if w.Code != tt.wantCode {
+				t.Fatalf("OIDCCallback() code = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if a.exchanged != tt.wantExchange {
+				t.Errorf("code exchanged = %t, want %t", a.exchanged, tt.wantExchange)
+			}
+			if tt.cookie != nil && !strings.Contains(w.Header().Get("Set-Cookie"), oidcCookie+"=;") {
+				t.Errorf("OIDCCallback() didn't clear the login cookie")
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			resp := &SignUpResponse{}
+			if dErr := json.NewDecoder(w.Body).Decode(resp); dErr != nil {
+				t.Fatal(dErr)
+			}
+			if resp.UserID != "subject" || resp.Token != "access-subject" || resp.RefreshToken != "refresh-subject" {
+				t.Errorf("OIDCCallback() = %+v, want tokens of the user", resp)
+			}
+		})
+	}
+}
@@ -3,30 +3,273 @@ package server
 import (
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/auth"
+	"github.com/triabokon/gotagv/internal/captions"
+	"github.com/triabokon/gotagv/internal/ratelimit"
 )
 
+const apiV1 = "/v1"
+
 func (s *Server) SetRoutes() {
+	s.router.MethodNotAllowedHandler = http.HandlerFunc(s.MethodNotAllowed)
+
 	s.router.HandleFunc("/healthcheck", s.HelloHandler)
+	s.router.HandleFunc("/.well-known/jwks.json", s.JWKS)
+	s.router.HandleFunc("/oidc/login", s.limiter.Limit(ratelimit.AuthGroup, s.OIDCLogin))
+	s.router.HandleFunc("/oidc/callback", s.limiter.Limit(ratelimit.AuthGroup, s.OIDCCallback))
+
+	s.setV1Routes(s.router.PathPrefix(apiV1).Subrouter())
+	s.setDeprecatedRoutes()
+}
+
+func (s *Server) setV1Routes(r *mux.Router) {
+	r.HandleFunc("/signup", s.limiter.Limit(ratelimit.AuthGroup, s.SignUp)).Methods(http.MethodPost)
+	r.HandleFunc("/signin", s.limiter.Limit(ratelimit.AuthGroup, s.SignIn)).Methods(http.MethodPost)
+	r.HandleFunc("/token/refresh", s.limiter.Limit(ratelimit.AuthGroup, s.RefreshToken)).Methods(http.MethodPost)
+	r.HandleFunc("/signout", s.authorize(auth.ReadPermission, s.SignOut)).Methods(http.MethodPost)
+
+	r.HandleFunc(
+		fmt.Sprintf("/users/{%s}/role", entityIDKey),
+		s.authorize(auth.AdminPermission, s.UpdateUserRole),
+	).Methods(http.MethodPut)
+	r.HandleFunc(
+		fmt.Sprintf("/users/{%s}/revoke", entityIDKey),
+		s.authorize(auth.AdminPermission, s.RevokeUserTokens),
+	).Methods(http.MethodPost)
+
+	r.HandleFunc("/apikeys", s.authorize(auth.ReadPermission, s.ListAPIKeys)).Methods(http.MethodGet)
+	r.HandleFunc("/apikeys", s.authorize(auth.ReadPermission, s.CreateAPIKey)).Methods(http.MethodPost)
+	r.HandleFunc(
+		fmt.Sprintf("/apikeys/{%s}", entityIDKey),
+		s.authorize(auth.ReadPermission, s.DeleteAPIKey),
+	).Methods(http.MethodDelete)
+
+	r.HandleFunc("/workspaces", s.authorize(auth.ReadPermission, s.ListWorkspaces)).Methods(http.MethodGet)
+	r.HandleFunc("/workspaces", s.authorize(auth.WritePermission, s.CreateWorkspace)).Methods(http.MethodPost)
+	r.HandleFunc(
+		fmt.Sprintf("/workspaces/{%s}/members", entityIDKey),
+		s.authorize(auth.WritePermission, s.AddWorkspaceMember),
+	).Methods(http.MethodPost)
+	r.HandleFunc(
+		fmt.Sprintf("/workspaces/{%s}/members/{%s}", entityIDKey, userIDKey),
+		s.authorize(auth.WritePermission, s.RemoveWorkspaceMember),
+	).Methods(http.MethodDelete)
+
+	r.HandleFunc("/videos", s.authorize(auth.ReadPermission, s.ListVideos)).Methods(http.MethodGet)
+	r.HandleFunc("/videos", s.authorize(auth.WritePermission, s.CreateVideo)).Methods(http.MethodPost)
+	r.HandleFunc(
+		fmt.Sprintf("/videos/{%s}", entityIDKey),
+		s.authorize(auth.ReadPermission, s.GetVideo),
+	).Methods(http.MethodGet)
+	r.HandleFunc(
+		fmt.Sprintf("/videos/{%s}", entityIDKey),
+		s.authorize(auth.WritePermission, s.UpdateVideo),
+	).Methods(http.MethodPatch)
+	r.HandleFunc(
+		fmt.Sprintf("/videos/{%s}", entityIDKey),
+		s.authorize(auth.WritePermission, s.DeleteVideo),
+	).Methods(http.MethodDelete)
+
+	r.HandleFunc(
+		fmt.Sprintf("/videos/{%s}/members", entityIDKey),
+		s.authorize(auth.ReadPermission, s.ListVideoMembers),
+	).Methods(http.MethodGet)
+	r.HandleFunc(
+		fmt.Sprintf("/videos/{%s}/members", entityIDKey),
+		s.authorize(auth.WritePermission, s.AddVideoMember),
+	).Methods(http.MethodPost)
+	r.HandleFunc(
+		fmt.Sprintf("/videos/{%s}/members/{%s}", entityIDKey, userIDKey),
+		s.authorize(auth.ReadPermission, s.RemoveVideoMember),
+	).Methods(http.MethodDelete)
+
+	r.HandleFunc(
+		fmt.Sprintf("/videos/{%s}/annotations", entityIDKey),
+		s.authorize(auth.ReadPermission, s.ListAnnotations),
+	).Methods(http.MethodGet)
+	r.HandleFunc(
+		fmt.Sprintf("/videos/{%s}/annotations", entityIDKey),
+		s.authorize(auth.WritePermission, s.CreateAnnotation),
+	).Methods(http.MethodPost)
+	r.HandleFunc(
+		fmt.Sprintf("/videos/{%s}/annotations.vtt", entityIDKey),
+		s.authorize(auth.ReadPermission, s.ExportAnnotations(captions.VTTFormat)),
+	).Methods(http.MethodGet)
+	r.HandleFunc(
+		fmt.Sprintf("/videos/{%s}/annotations.srt", entityIDKey),
+		s.authorize(auth.ReadPermission, s.ExportAnnotations(captions.SRTFormat)),
+	).Methods(http.MethodGet)
+	r.HandleFunc(
+		fmt.Sprintf("/videos/{%s}/annotations/at", entityIDKey),
+		s.authorize(auth.ReadPermission, s.ListAnnotationsAt),
+	).Methods(http.MethodGet)
+	r.HandleFunc(
+		fmt.Sprintf("/videos/{%s}/annotations/range", entityIDKey),
+		s.authorize(auth.ReadPermission, s.ListAnnotationsInRange),
+	).Methods(http.MethodGet)
+	r.HandleFunc(
+		fmt.Sprintf("/videos/{%s}/chapters", entityIDKey),
+		s.authorize(auth.ReadPermission, s.ListChapters),
+	).Methods(http.MethodGet)
+	r.HandleFunc(
+		fmt.Sprintf("/annotations/{%s}", entityIDKey),
+		s.authorize(auth.ReadPermission, s.GetAnnotation),
+	).Methods(http.MethodGet)
+	r.HandleFunc(
+		fmt.Sprintf("/annotations/{%s}", entityIDKey),
+		s.authorize(auth.WritePermission, s.UpdateAnnotation),
+	).Methods(http.MethodPatch)
+	r.HandleFunc(
+		fmt.Sprintf("/annotations/{%s}", entityIDKey),
+		s.authorize(auth.WritePermission, s.DeleteAnnotation),
+	).Methods(http.MethodDelete)
+
+	r.HandleFunc("/annotationtypes", s.authorize(auth.ReadPermission, s.ListAnnotationTypes)).Methods(http.MethodGet)
+	r.HandleFunc("/annotationtypes", s.authorize(auth.AdminPermission, s.CreateAnnotationType)).Methods(http.MethodPost)
+	r.HandleFunc(
+		fmt.Sprintf("/annotationtypes/{%s}", entityIDKey),
+		s.authorize(auth.AdminPermission, s.DeleteAnnotationType),
+	).Methods(http.MethodDelete)
+}
+
+// setDeprecatedRoutes keeps routes of the API before v1, they accept any method.
+func (s *Server) setDeprecatedRoutes() {
+	s.router.HandleFunc("/signup", s.deprecated("/signup", s.limiter.Limit(ratelimit.AuthGroup, s.SignUp)))
+	s.router.HandleFunc("/signin", s.deprecated("/signin", s.limiter.Limit(ratelimit.AuthGroup, s.SignIn)))
+	s.router.HandleFunc(
+		"/token/refresh",
+		s.deprecated("/token/refresh", s.limiter.Limit(ratelimit.AuthGroup, s.RefreshToken)),
+	)
+	s.router.HandleFunc("/signout", s.deprecated("/signout", s.authorize(auth.ReadPermission, s.SignOut)))
+
+	s.router.HandleFunc(
+		fmt.Sprintf("/users/{%s}/role", entityIDKey),
+		s.deprecated("/users/{id}/role", s.authorize(auth.AdminPermission, s.UpdateUserRole)),
+	)
+	s.router.HandleFunc(
+		fmt.Sprintf("/users/{%s}/revoke", entityIDKey),
+		s.deprecated("/users/{id}/revoke", s.authorize(auth.AdminPermission, s.RevokeUserTokens)),
+	)
+
+	s.router.HandleFunc("/apikeys/add", s.deprecated("/apikeys", s.authorize(auth.ReadPermission, s.CreateAPIKey)))
+	s.router.HandleFunc("/apikeys", s.deprecated("/apikeys", s.authorize(auth.ReadPermission, s.ListAPIKeys)))
+	s.router.HandleFunc(
+		fmt.Sprintf("/apikeys/delete/{%s}", entityIDKey),
+		s.deprecated("/apikeys/{id}", s.authorize(auth.ReadPermission, s.DeleteAPIKey)),
+	)
+
+	s.router.HandleFunc(
+		"/workspaces/add",
+		s.deprecated("/workspaces", s.authorize(auth.WritePermission, s.CreateWorkspace)),
+	)
+	s.router.HandleFunc(
+		"/workspaces",
+		s.deprecated("/workspaces", s.authorize(auth.ReadPermission, s.ListWorkspaces)),
+	)
+	s.router.HandleFunc(
+		fmt.Sprintf("/workspaces/{%s}/members/add", entityIDKey),
+		s.deprecated("/workspaces/{id}/members", s.authorize(auth.WritePermission, s.AddWorkspaceMember)),
+	)
+	s.router.HandleFunc(
+		fmt.Sprintf("/workspaces/{%s}/members/delete/{%s}", entityIDKey, userIDKey),
+		s.deprecated(
+			"/workspaces/{id}/members/{user_id}", s.authorize(auth.WritePermission, s.RemoveWorkspaceMember),
+		),
+	)
 
-	s.router.HandleFunc("/signup", s.SignUp)
-	s.router.HandleFunc("/signin", s.SignIn)
+	s.router.HandleFunc("/videos/add", s.deprecated("/videos", s.authorize(auth.WritePermission, s.CreateVideo)))
+	s.router.HandleFunc("/videos", s.deprecated("/videos", s.authorize(auth.ReadPermission, s.ListVideos)))
+	s.router.HandleFunc(
+		fmt.Sprintf("/videos/delete/{%s}", entityIDKey),
+		s.deprecated("/videos/{id}", s.authorize(auth.WritePermission, s.DeleteVideo)),
+	)
 
-	s.router.HandleFunc("/videos/add", s.auth.HandleAuth(s.CreateVideo))
-	s.router.HandleFunc("/videos", s.auth.HandleAuth(s.ListVideos))
-	s.router.HandleFunc(fmt.Sprintf("/videos/delete/{%s}", entityIDKey), s.auth.HandleAuth(s.DeleteVideo))
+	s.router.HandleFunc(
+		fmt.Sprintf("/videos/{%s}/members", entityIDKey),
+		s.deprecated("/videos/{id}/members", s.authorize(auth.ReadPermission, s.ListVideoMembers)),
+	)
+	s.router.HandleFunc(
+		fmt.Sprintf("/videos/{%s}/members/add", entityIDKey),
+		s.deprecated("/videos/{id}/members", s.authorize(auth.WritePermission, s.AddVideoMember)),
+	)
+	s.router.HandleFunc(
+		fmt.Sprintf("/videos/{%s}/members/delete/{%s}", entityIDKey, userIDKey),
+		s.deprecated("/videos/{id}/members/{user_id}", s.authorize(auth.ReadPermission, s.RemoveVideoMember)),
+	)
 
-	s.router.HandleFunc("/annotations/add", s.auth.HandleAuth(s.CreateAnnotation))
-	s.router.HandleFunc("/annotations", s.auth.HandleAuth(s.ListAnnotations))
+	s.router.HandleFunc(
+		"/annotations/add",
+		s.deprecated("/videos/{id}/annotations", s.authorize(auth.WritePermission, s.CreateAnnotation)),
+	)
+	s.router.HandleFunc(
+		"/annotations",
+		s.deprecated("/videos/{id}/annotations", s.authorize(auth.ReadPermission, s.ListAnnotations)),
+	)
 	s.router.HandleFunc(
 		fmt.Sprintf("/annotations/update/{%s}", entityIDKey),
-		s.auth.HandleAuth(s.UpdateAnnotation),
+		s.deprecated("/annotations/{id}", s.authorize(auth.WritePermission, s.UpdateAnnotation)),
 	)
 	s.router.HandleFunc(
 		fmt.Sprintf("/annotations/delete/{%s}", entityIDKey),
-		s.auth.HandleAuth(s.DeleteAnnotation),
+		s.deprecated("/annotations/{id}", s.authorize(auth.WritePermission, s.DeleteAnnotation)),
 	)
 }
 
+// authorize checks permission p and limits requests of the caller by the group of the permission,
+// requests are limited by client ip before authentication, so rejected ones count as well.
+func (s *Server) authorize(p auth.Permission, next http.HandlerFunc) http.HandlerFunc {
+	group := ratelimit.WriteGroup
+	if p == auth.ReadPermission {
+		group = ratelimit.ReadGroup
+	}
+	return s.limiter.Limit(ratelimit.ClientGroup, s.auth.Authorize(p, s.limiter.Limit(group, next)))
+}
+
+// deprecated marks responses of the route as deprecated and links v1 route replacing it.
+func (s *Server) deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := successor
+		for k, v := range mux.Vars(r) {
+			link = strings.ReplaceAll(link, "{"+k+"}", v)
+		}
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", apiV1, link))
+		next(w, r)
+	}
+}
+
+// MethodNotAllowed responds with methods of the routes matching request path in Allow header.
+func (s *Server) MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	allowed := make(map[string]struct{})
+	_ = s.router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		match := &mux.RouteMatch{}
+		if route.Match(r, match) || errors.Is(match.MatchErr, mux.ErrMethodMismatch) {
+			for _, m := range methods {
+				allowed[m] = struct{}{}
+			}
+		}
+		return nil
+	})
+
+	methods := make([]string, 0, len(allowed))
+	for m := range allowed {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	s.ErrorResponse(w, fmt.Errorf("method %s isn't allowed", r.Method), http.StatusMethodNotAllowed)
+}
+
 func (s *Server) HelloHandler(w http.ResponseWriter, _ *http.Request) {
 	s.SuccessResponse(w, "Ok!")
 }
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/triabokon/gotagv/internal/auth"
+	"github.com/triabokon/gotagv/internal/ratelimit"
+)
+
+// rejectingAuth rejects every request, as with invalid token or API key.
+type rejectingAuth struct {
+	Auth
+}
+
+func (a *rejectingAuth) Authorize(_ auth.Permission, _ http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+}
+
+func TestAuthorizeLimitsRejectedRequests(t *testing.T) {
+	limiter := ratelimit.New(&ratelimit.Config{ClientPerMinute: 1, ClientBurst: 2}, nil, zap.NewNop())
+	s := New(zap.NewNop(), &Config{}, &rejectingAuth{}, limiter, nil)
+	handler := s.authorize(auth.ReadPermission, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request passed authorization")
+	})
+
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodGet, "/v1/videos", http.NoBody)
+		r.Header.Set("Authorization", "Bearer invalid")
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != want {
+			t.Errorf("request %d: code = %d, want %d", i, w.Code, want)
+		}
+	}
+}
+
+// permissionAuth passes every request and reports permission the route requires.
+type permissionAuth struct {
+	Auth
+}
+
+func (a *permissionAuth) Authorize(p auth.Permission, _ http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Permission", string(p))
+	}
+}
+
+func TestRoutePermissions(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   auth.Permission
+	}{
+		{method: http.MethodGet, path: "/v1/videos", want: auth.ReadPermission},
+		{method: http.MethodPost, path: "/v1/videos", want: auth.WritePermission},
+		{method: http.MethodPatch, path: "/v1/videos/video", want: auth.WritePermission},
+		{method: http.MethodDelete, path: "/v1/videos/video", want: auth.WritePermission},
+		{method: http.MethodGet, path: "/v1/videos/video/annotations", want: auth.ReadPermission},
+		{method: http.MethodPost, path: "/v1/videos/video/annotations", want: auth.WritePermission},
+		{method: http.MethodPatch, path: "/v1/annotations/annotation", want: auth.WritePermission},
+		{method: http.MethodDelete, path: "/v1/annotations/annotation", want: auth.WritePermission},
+		{method: http.MethodPut, path: "/v1/users/user/role", want: auth.AdminPermission},
+		{method: http.MethodPost, path: "/v1/users/user/revoke", want: auth.AdminPermission},
+		{method: http.MethodPost, path: "/v1/annotationtypes", want: auth.AdminPermission},
+		{method: http.MethodPost, path: "/users/user/role", want: auth.AdminPermission},
+		{method: http.MethodPost, path: "/videos/delete/video", want: auth.WritePermission},
+	}
+	s := New(zap.NewNop(), &Config{}, &permissionAuth{}, ratelimit.New(&ratelimit.Config{}, nil, zap.NewNop()), nil)
+	s.SetRoutes()
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, http.NoBody))
+			if got := auth.Permission(w.Header().Get("X-Permission")); got != tt.want {
+				t.Errorf("permission = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	tests := []struct {
+		method    string
+		path      string
+		wantAllow string
+	}{
+		{method: http.MethodPut, path: "/v1/videos", wantAllow: "GET, POST"},
+		{method: http.MethodPost, path: "/v1/videos/video", wantAllow: "DELETE, GET, PATCH"},
+		{method: http.MethodGet, path: "/v1/signin", wantAllow: "POST"},
+		{method: http.MethodDelete, path: "/v1/videos/video/annotations", wantAllow: "GET, POST"},
+	}
+	s := New(zap.NewNop(), &Config{}, &permissionAuth{}, ratelimit.New(&ratelimit.Config{}, nil, zap.NewNop()), nil)
+	s.SetRoutes()
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, http.NoBody))
+			if w.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("code = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+			}
+			if allow := w.Header().Get("Allow"); allow != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", allow, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestDeprecatedRoutes(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		wantLink string
+	}{
+		{method: http.MethodPost, path: "/videos/delete/video", wantLink: `</v1/videos/video>; rel="successor-version"`},
+		{method: http.MethodGet, path: "/videos", wantLink: `</v1/videos>; rel="successor-version"`},
+		{
+			method:   http.MethodPost,
+			path:     "/annotations/update/annotation",
+			wantLink: `</v1/annotations/annotation>; rel="successor-version"`,
+		},
+		{method: http.MethodGet, path: "/v1/videos"},
+	}
+	s := New(zap.NewNop(), &Config{}, &permissionAuth{}, ratelimit.New(&ratelimit.Config{}, nil, zap.NewNop()), nil)
+	s.SetRoutes()
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, http.NoBody))
+			if w.Header().Get("X-Permission") == "" {
+				t.Fatalf("route isn't matched: code = %d", w.Code)
+			}
+			deprecated := w.Header().Get("Deprecation") == "true"
+			if deprecated != (tt.wantLink != "") || w.Header().Get("Link") != tt.wantLink {
+				t.Errorf("Deprecation = %q, Link = %q, want link %q",
+					w.Header().Get("Deprecation"), w.Header().Get("Link"), tt.wantLink)
+			}
+		})
+	}
+}
@@ -3,9 +3,12 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -17,29 +20,69 @@ import (
 	"github.com/triabokon/gotagv/internal/auth"
 	"github.com/triabokon/gotagv/internal/controller"
 	"github.com/triabokon/gotagv/internal/model"
+	"github.com/triabokon/gotagv/internal/ratelimit"
 )
 
-const entityIDKey = "id"
+const (
+	entityIDKey = "id"
+	userIDKey   = "user_id"
+)
 
 type Auth interface {
-	CreateToken(userID string) (string, error)
-	ValidateToken(tknStr string) (*auth.Claims, error)
+	IssueTokens(ctx context.Context, u *model.User) (*auth.Tokens, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (*auth.Tokens, error)
+	ValidateToken(ctx context.Context, tknStr string) (*auth.Claims, error)
+	SignOut(ctx context.Context, refreshToken string) error
+	RevokeUserTokens(ctx context.Context, userID string) error
+	JWKS() *auth.JWKSet
+	OIDCAuthURL(ctx context.Context, state, nonce string) (string, error)
+	OIDCExchange(ctx context.Context, code, nonce string) (*model.OIDCIdentity, error)
 
 	HandleAuth(next http.HandlerFunc) http.HandlerFunc
+	Authorize(p auth.Permission, next http.HandlerFunc) http.HandlerFunc
+}
+
+type RateLimiter interface {
+	Limit(g ratelimit.Group, next http.HandlerFunc) http.HandlerFunc
 }
 
 type Controller interface {
-	GetUser(ctx context.Context, id string) error
-	CreateUser(ctx context.Context, id string) error
+	SignUp(ctx context.Context, p *controller.SignUpParams) (*model.User, error)
+	SignIn(ctx context.Context, p *controller.SignInParams) (*model.User, error)
+	SignInOIDC(ctx context.Context, identity *model.OIDCIdentity) (*model.User, error)
+	UpdateUserRole(ctx context.Context, id string, role model.Role) error
+
+	ListAPIKeys(ctx context.Context) ([]*model.APIKey, error)
+	CreateAPIKey(ctx context.Context, p *controller.CreateAPIKeyParams) (string, string, error)
+	DeleteAPIKey(ctx context.Context, id string) error
+
+	ListWorkspaces(ctx context.Context) ([]*model.Workspace, error)
+	CreateWorkspace(ctx context.Context, name string) (string, error)
+	AddWorkspaceMember(ctx context.Context, workspaceID, userID string) error
+	RemoveWorkspaceMember(ctx context.Context, workspaceID, userID string) error
 
-	ListVideos(ctx context.Context) ([]*model.Video, error)
+	ListVideos(ctx context.Context, p *controller.ListVideosParams) ([]*model.Video, string, error)
+	GetVideo(ctx context.Context, id string) (*model.Video, error)
 	CreateVideo(ctx context.Context, p *controller.CreateVideoParams) (string, error)
+	UpdateVideo(ctx context.Context, id string, p *model.UpdateVideoParams) error
 	DeleteVideo(ctx context.Context, id string) error
 
-	ListAnnotations(ctx context.Context, p *controller.ListAnnotationsParams) ([]*model.Annotation, error)
-	CreateAnnotation(ctx context.Context, p *model.CreateAnnotationParams) (string, error)
-	UpdateAnnotation(ctx context.Context, id string, p *model.UpdateAnnotationParams) error
+	ListAnnotations(ctx context.Context, p *controller.ListAnnotationsParams) ([]*model.Annotation, string, error)
+	ListAnnotationsInRange(ctx context.Context, videoID string, from, to time.Duration) ([]*model.Annotation, error)
+	GetAnnotation(ctx context.Context, id string) (*model.Annotation, error)
+	CreateAnnotation(ctx context.Context, p *model.CreateAnnotationParams) (string, []string, error)
+	UpdateAnnotation(ctx context.Context, id string, p *model.UpdateAnnotationParams) ([]string, error)
 	DeleteAnnotation(ctx context.Context, id string) error
+
+	ListChapters(ctx context.Context, videoID string, fillGaps bool) ([]*model.Annotation, error)
+
+	ListAnnotationTypes(ctx context.Context) ([]*model.AnnotationTypeDef, error)
+	CreateAnnotationType(ctx context.Context, p *controller.CreateAnnotationTypeParams) error
+	DeleteAnnotationType(ctx context.Context, name string) error
+
+	ListVideoMembers(ctx context.Context, videoID string) ([]*model.VideoMember, error)
+	AddVideoMember(ctx context.Context, videoID string, p *controller.AddVideoMemberParams) error
+	RemoveVideoMember(ctx context.Context, videoID, userID string) error
 }
 
 type Server struct {
@@ -48,15 +91,17 @@ type Server struct {
 	config *Config
 
 	auth       Auth
+	limiter    RateLimiter
 	controller Controller
 }
 
-func New(logger *zap.Logger, config *Config, a Auth, ctrl Controller) *Server {
+func New(logger *zap.Logger, config *Config, a Auth, limiter RateLimiter, ctrl Controller) *Server {
 	srv := &Server{
 		router:     mux.NewRouter(),
 		logger:     logger,
 		config:     config,
 		auth:       a,
+		limiter:    limiter,
 		controller: ctrl,
 	}
 	return srv
@@ -139,6 +184,10 @@ func (s *Server) ServeWithGracefulShutdown(ctx context.Context, logger *zap.Logg
 }
 
 func (s *Server) SuccessResponse(w http.ResponseWriter, result interface{}) {
+	s.JSONResponse(w, result, http.StatusOK)
+}
+
+func (s *Server) JSONResponse(w http.ResponseWriter, result interface{}, code int) {
 	body, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -147,35 +196,87 @@ func (s *Server) SuccessResponse(w http.ResponseWriter, result interface{}) {
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(code)
 	if _, wErr := w.Write(body); wErr != nil {
 		s.logger.Error("failed to write response body", zap.Error(wErr))
 	}
 }
 
+// TextResponse responds with body of the content type, e.g. exported text formats.
+func (s *Server) TextResponse(w http.ResponseWriter, body, contentType string) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, wErr := w.Write([]byte(body)); wErr != nil {
+		s.logger.Error("failed to write response body", zap.Error(wErr))
+	}
+}
+
 type Response struct {
 	Message string `json:"message"`
 }
 
 func (s *Server) ErrorResponse(w http.ResponseWriter, err error, code int) {
-	body, err := json.MarshalIndent(&Response{Message: err.Error()}, "", "  ")
+	s.JSONResponse(w, &Response{Message: err.Error()}, code)
+}
+
+type ConflictResponse struct {
+	Message       string   `json:"message"`
+	AnnotationIDs []string `json:"annotation_ids"`
+}
+
+// ConflictResponse responds with 409 listing annotations causing the conflict.
+func (s *Server) ConflictResponse(w http.ResponseWriter, err error, conflict *model.ConflictError) {
+	s.JSONResponse(w, &ConflictResponse{Message: err.Error(), AnnotationIDs: conflict.AnnotationIDs}, http.StatusConflict)
+}
+
+// queryBool parses optional boolean query param, false is returned when it's missing.
+func queryBool(query url.Values, key string) (bool, error) {
+	v := query.Get(key)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		s.logger.Error("JSON marshal failed", zap.Error(err))
-		return
+		return false, fmt.Errorf("invalid %s %q", key, v)
 	}
+	return b, nil
+}
 
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(code)
-	if _, wErr := w.Write(body); wErr != nil {
-		s.logger.Error("failed to write response body", zap.Error(wErr))
+// queryInt parses optional integer query param, zero is returned when it's missing.
+func queryInt(query url.Values, key string) (int, error) {
+	v := query.Get(key)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// queryDuration parses optional duration query param.
+func queryDuration(query url.Values, key string) (*time.Duration, error) {
+	v := query.Get(key)
+	if v == "" {
+		return nil, nil
 	}
+	d, err := parseDuration(v, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return &d, nil
 }
 
-func parseDuration(d string) (time.Duration, error) {
-	duration, err := time.ParseDuration(d)
+// queryTime parses optional RFC 3339 timestamp query param.
+func queryTime(query url.Values, key string) (*time.Time, error) {
+	v := query.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
 	}
-	return duration, nil
+	return &t, nil
 }
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+	"github.com/triabokon/gotagv/internal/timecode"
+)
+
+// frameRateFunc looks up frame rate needed to parse SMPTE timecode.
+type frameRateFunc func() (timecode.Rate, error)
+
+// parseDuration parses time in any notation supported by timecode.Parse,
+// frame rate is looked up only for SMPTE timecode, nil frameRate rejects it.
+func parseDuration(d string, frameRate frameRateFunc) (time.Duration, error) {
+	duration, err := timecode.Parse(d, timecode.Rate{})
+	if !errors.Is(err, timecode.ErrFrameRateRequired) || frameRate == nil {
+		return duration, err
+	}
+	rate, fErr := frameRate()
+	if fErr != nil {
+		return 0, fmt.Errorf("failed to get frame rate: %w", fErr)
+	}
+	return timecode.Parse(d, rate)
+}
+
+// videoFrameRate returns frameRateFunc of the video.
+func (s *Server) videoFrameRate(ctx context.Context, videoID string) frameRateFunc {
+	return func() (timecode.Rate, error) {
+		video, err := s.controller.GetVideo(ctx, videoID)
+		if err != nil {
+			return timecode.Rate{}, err
+		}
+		return video.Rate(), nil
+	}
+}
+
+// annotationFrameRate returns frameRateFunc of the video of the annotation.
+func (s *Server) annotationFrameRate(ctx context.Context, annotationID string) frameRateFunc {
+	return func() (timecode.Rate, error) {
+		annotation, err := s.controller.GetAnnotation(ctx, annotationID)
+		if err != nil {
+			return timecode.Rate{}, err
+		}
+		return s.videoFrameRate(ctx, annotation.VideoID)()
+	}
+}
+
+// VideoResponse is the video with times rendered in the requested format.
+type VideoResponse struct {
+	*model.Video
+	Duration interface{} `json:"duration"`
+}
+
+// AnnotationResponse is the annotation with times rendered in the requested format.
+type AnnotationResponse struct {
+	*model.Annotation
+	StartTime     interface{}     `json:"start_time"`
+	EndTime       interface{}     `json:"end_time"`
+	VideoDuration interface{}     `json:"video_duration,omitempty"`
+	Region        *RegionResponse `json:"region,omitempty"`
+	// RegionAt is the region at the requested time.
+	RegionAt *model.Shape `json:"region_at,omitempty"`
+}
+
+type RegionResponse struct {
+	model.Shape
+	Keyframes []*KeyframeResponse `json:"keyframes,omitempty"`
+}
+
+type KeyframeResponse struct {
+	Time interface{} `json:"time"`
+	model.Shape
+}
+
+// timeFormatter renders times of responses in the format chosen with ?time_format=,
+// by default times stay integer nanoseconds.
+type timeFormatter struct {
+	format timecode.Format
+	// frameRate looks up frame rate of the video of annotations for SMPTE format.
+	frameRate func(videoID string) (timecode.Rate, error)
+	rates     map[string]timecode.Rate
+}
+
+const timeFormatParam = "time_format"
+
+func (s *Server) timeFormatter(r *http.Request) (*timeFormatter, error) {
+	format, err := timecode.ToFormat(r.URL.Query().Get(timeFormatParam))
+	if err != nil {
+		return nil, err
+	}
+	return &timeFormatter{
+		format: format,
+		frameRate: func(videoID string) (timecode.Rate, error) {
+			return s.videoFrameRate(r.Context(), videoID)()
+		},
+		rates: make(map[string]timecode.Rate),
+	}, nil
+}
+
+func (f *timeFormatter) formatTime(d time.Duration, rate timecode.Rate) (interface{}, error) {
+	if f.format == timecode.NanosecondsFormat {
+		return d, nil
+	}
+	return f.format.Format(d, rate)
+}
+
+func (f *timeFormatter) video(v *model.Video) (*VideoResponse, error) {
+	duration, err := f.formatTime(v.Duration, v.Rate())
+	if err != nil {
+		return nil, err
+	}
+	return &VideoResponse{Video: v, Duration: duration}, nil
+}
+
+func (f *timeFormatter) videos(videos []*model.Video) ([]*VideoResponse, error) {
+	var result []*VideoResponse
+	for _, v := range videos {
+		resp, err := f.video(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, resp)
+	}
+	return result, nil
+}
+
+func (f *timeFormatter) annotation(a *model.Annotation) (*AnnotationResponse, error) {
+	var rate timecode.Rate
+	if f.format == timecode.SMPTEFormat {
+		var ok bool
+		if rate, ok = f.rates[a.VideoID]; !ok {
+			var err error
+			if rate, err = f.frameRate(a.VideoID); err != nil {
+				return nil, fmt.Errorf("failed to get frame rate: %w", err)
+			}
+			f.rates[a.VideoID] = rate
+		}
+	}
+	resp := &AnnotationResponse{Annotation: a}
+	var err error
+	if resp.StartTime, err = f.formatTime(a.StartTime, rate); err != nil {
+		return nil, err
+	}
+	if resp.EndTime, err = f.formatTime(a.EndTime, rate); err != nil {
+		return nil, err
+	}
+	if a.VideoDuration != 0 {
+		if resp.VideoDuration, err = f.formatTime(a.VideoDuration, rate); err != nil {
+			return nil, err
+		}
+	}
+	if a.Region != nil {
+		resp.Region = &RegionResponse{Shape: a.Region.Shape}
+		for _, k := range a.Region.Keyframes {
+			t, tErr := f.formatTime(k.Time, rate)
+			if tErr != nil {
+				return nil, tErr
+			}
+			resp.Region.Keyframes = append(resp.Region.Keyframes, &KeyframeResponse{Time: t, Shape: k.Shape})
+		}
+	}
+	return resp, nil
+}
+
+func (f *timeFormatter) annotations(annotations []*model.Annotation) ([]*AnnotationResponse, error) {
+	var result []*AnnotationResponse
+	for _, a := range annotations {
+		resp, err := f.annotation(a)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, resp)
+	}
+	return result, nil
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// fakeRateController returns videos with the frame rate.
+type fakeRateController struct {
+	Controller
+	video *model.Video
+}
+
+func (c *fakeRateController) GetVideo(_ context.Context, id string) (*model.Video, error) {
+	if c.video == nil {
+		return nil, model.ErrNotFound
+	}
+	return c.video, nil
+}
+
+func TestTimeFormat(t *testing.T) {
+	dropFrame := &model.Video{ID: "video", Duration: 10 * time.Minute, FPS: 29.97, DropFrame: true}
+	annotation := &model.Annotation{
+		ID: "annotation", VideoID: "video", StartTime: 60060 * time.Millisecond, EndTime: 92500 * time.Millisecond,
+		VideoDuration: 10 * time.Minute,
+	}
+	tests := []struct {
+		format  string
+		video   *model.Video
+		want    string
+		wantErr bool
+	}{
+		{format: "", want: `{"start_time":60060000000,"end_time":92500000000,"video_duration":600000000000}`},
+		{format: "go", want: `{"start_time":"1m0.06s","end_time":"1m32.5s","video_duration":"10m0s"}`},
+		{format: "clock", want: `{"start_time":"00:01:00.060","end_time":"00:01:32.500","video_duration":"00:10:00.000"}`},
+		{format: "iso8601", want: `{"start_time":"PT1M0.06S","end_time":"PT1M32.5S","video_duration":"PT10M"}`},
+		{format: "seconds", want: `{"start_time":"60.06","end_time":"92.5","video_duration":"600"}`},
+		{
+			format: "smpte", video: dropFrame,
+			want: `{"start_time":"00:01:00;02","end_time":"00:01:32;14","video_duration":"00:10:00;00"}`,
+		},
+		{format: "smpte", video: &model.Video{ID: "video", Duration: 10 * time.Minute}, wantErr: true},
+		{format: "smpte", wantErr: true},
+		{format: "frames", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			s := New(zap.NewNop(), &Config{}, nil, nil, &fakeRateController{video: tt.video})
+			r := httptest.NewRequest(http.MethodGet, "/v1/annotations/annotation?time_format="+tt.format, http.NoBody)
+			formatter, err := s.timeFormatter(r)
+			var resp *AnnotationResponse
+			if err == nil {
+				resp, err = formatter.annotation(annotation)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("annotation() = %+v, want error", resp)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, mErr := json.Marshal(map[string]interface{}{
+				"start_time": resp.StartTime, "end_time": resp.EndTime, "video_duration": resp.VideoDuration,
+			})
+			if mErr != nil {
+				t.Fatal(mErr)
+			}
+			// keys of maps are sorted
+			var want map[string]interface{}
+			if uErr := json.Unmarshal([]byte(tt.want), &want); uErr != nil {
+				t.Fatal(uErr)
+			}
+			wantJSON, _ := json.Marshal(want)
+			if string(got) != string(wantJSON) {
+				t.Errorf("annotation() = %s, want %s", got, wantJSON)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	s := New(zap.NewNop(), &Config{}, nil, nil, &fakeRateController{
+		video: &model.Video{ID: "video", FPS: 29.97, DropFrame: true},
+	})
+	frameRate := s.videoFrameRate(context.Background(), "video")
+	tests := []struct {
+		s         string
+		frameRate frameRateFunc
+		want      time.Duration
+		wantErr   bool
+	}{
+		{s: "92.5", want: 92500 * time.Millisecond},
+		{s: "PT1M32.5S", want: 92500 * time.Millisecond},
+		{s: "00:01:00;02", frameRate: frameRate, want: 60060 * time.Millisecond},
+		{s: "00:01:00;02", wantErr: true},
+		{s: "P4294967296W", frameRate: frameRate, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parseDuration(tt.s, tt.frameRate)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseDuration(%q) = %v, %v, want %v", tt.s, got, err, tt.want)
+			}
+		})
+	}
+}
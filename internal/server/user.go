@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role"`
+}
+
+func (s *Server) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	req := &UpdateUserRoleRequest{}
+	if dErr := json.NewDecoder(r.Body).Decode(req); dErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
+		return
+	}
+	err := s.controller.UpdateUserRole(r.Context(), mux.Vars(r)[entityIDKey], model.ToRole(req.Role))
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to update user role: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to update user role: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to update user role: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, Response{Message: "user role updated successfully"})
+}
+
+func (s *Server) RevokeUserTokens(w http.ResponseWriter, r *http.Request) {
+	err := s.auth.RevokeUserTokens(r.Context(), mux.Vars(r)[entityIDKey])
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to revoke user tokens: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to revoke user tokens: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to revoke user tokens: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, Response{Message: "user tokens revoked successfully"})
+}
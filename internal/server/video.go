@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -11,11 +12,14 @@ import (
 	"github.com/triabokon/gotagv/internal/auth"
 	"github.com/triabokon/gotagv/internal/controller"
 	"github.com/triabokon/gotagv/internal/model"
+	"github.com/triabokon/gotagv/internal/timecode"
 )
 
 type CreateVideoRequest struct {
-	URL      string `json:"url"`
-	Duration string `json:"duration"`
+	URL       string  `json:"url"`
+	Duration  string  `json:"duration"`
+	FPS       float64 `json:"fps"`
+	DropFrame bool    `json:"drop_frame"`
 }
 
 type CreateVideoResponse struct {
@@ -33,20 +37,31 @@ func (s *Server) CreateVideo(w http.ResponseWriter, r *http.Request) {
 		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
 		return
 	}
-	duration, pErr := parseDuration(req.Duration)
+	rate := timecode.Rate{FPS: req.FPS, DropFrame: req.DropFrame}
+	duration, pErr := parseDuration(req.Duration, func() (timecode.Rate, error) { return rate, nil })
 	if pErr != nil {
 		s.ErrorResponse(w, fmt.Errorf("failed to parse duration: %w", pErr), http.StatusBadRequest)
 		return
 	}
 	videoID, err := s.controller.CreateVideo(r.Context(), &controller.CreateVideoParams{
-		UserID:   userID,
-		URL:      req.URL,
-		Duration: duration,
+		UserID:    userID,
+		URL:       req.URL,
+		Duration:  duration,
+		FPS:       req.FPS,
+		DropFrame: req.DropFrame,
 	})
 	if errors.Is(err, model.ErrInvalidArgument) {
 		s.ErrorResponse(w, fmt.Errorf("failed to create video: %w", err), http.StatusBadRequest)
 		return
 	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to create video: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to create video: %w", err), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		s.ErrorResponse(w, fmt.Errorf("failed to create video: %w", err), http.StatusInternalServerError)
 		return
@@ -55,16 +70,167 @@ func (s *Server) CreateVideo(w http.ResponseWriter, r *http.Request) {
 }
 
 type ListVideosResponse struct {
-	Videos []*model.Video `json:"videos"`
+	Videos     []*VideoResponse `json:"videos"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+func toListVideosParams(query url.Values) (*controller.ListVideosParams, error) {
+	limit, lErr := queryInt(query, "limit")
+	if lErr != nil {
+		return nil, lErr
+	}
+	createdAfter, aErr := queryTime(query, "created_after")
+	if aErr != nil {
+		return nil, aErr
+	}
+	createdBefore, bErr := queryTime(query, "created_before")
+	if bErr != nil {
+		return nil, bErr
+	}
+	return &controller.ListVideosParams{
+		Limit:         limit,
+		Cursor:        query.Get("cursor"),
+		Sort:          query.Get("sort"),
+		Order:         query.Get("order"),
+		CreatedAfter:  createdAfter,
+		CreatedBefore: createdBefore,
+	}, nil
 }
 
 func (s *Server) ListVideos(w http.ResponseWriter, r *http.Request) {
-	videos, err := s.controller.ListVideos(r.Context())
+	formatter, tErr := s.timeFormatter(r)
+	if tErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", tErr), http.StatusBadRequest)
+		return
+	}
+	req, qErr := toListVideosParams(r.URL.Query())
+	if qErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", qErr), http.StatusBadRequest)
+		return
+	}
+	videos, next, err := s.controller.ListVideos(r.Context(), req)
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list videos: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to list videos: %w", err), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		s.ErrorResponse(w, fmt.Errorf("failed to list videos: %w", err), http.StatusInternalServerError)
 		return
 	}
-	s.SuccessResponse(w, &ListVideosResponse{Videos: videos})
+	resp, fErr := formatter.videos(videos)
+	if fErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to format videos: %w", fErr), http.StatusBadRequest)
+		return
+	}
+	s.SuccessResponse(w, &ListVideosResponse{Videos: resp, NextCursor: next})
+}
+
+func (s *Server) GetVideo(w http.ResponseWriter, r *http.Request) {
+	formatter, tErr := s.timeFormatter(r)
+	if tErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", tErr), http.StatusBadRequest)
+		return
+	}
+	video, err := s.controller.GetVideo(r.Context(), mux.Vars(r)[entityIDKey])
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to get video: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to get video: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to get video: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to get video: %w", err), http.StatusInternalServerError)
+		return
+	}
+	resp, fErr := formatter.video(video)
+	if fErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to format video: %w", fErr), http.StatusBadRequest)
+		return
+	}
+	s.SuccessResponse(w, resp)
+}
+
+type UpdateVideoRequest struct {
+	URL       *string  `json:"url,omitempty"`
+	Duration  *string  `json:"duration,omitempty"`
+	FPS       *float64 `json:"fps,omitempty"`
+	DropFrame *bool    `json:"drop_frame,omitempty"`
+}
+
+// toUpdateVideoParams parses the request, SMPTE duration uses the new frame rate if it's changed.
+func toUpdateVideoParams(r *UpdateVideoRequest, frameRate frameRateFunc) (*model.UpdateVideoParams, error) {
+	p := &model.UpdateVideoParams{URL: r.URL, FPS: r.FPS, DropFrame: r.DropFrame}
+	if r.FPS != nil || r.DropFrame != nil {
+		current := frameRate
+		frameRate = func() (timecode.Rate, error) {
+			rate, err := current()
+			if err != nil {
+				return timecode.Rate{}, err
+			}
+			if r.FPS != nil {
+				rate.FPS = *r.FPS
+			}
+			if r.DropFrame != nil {
+				rate.DropFrame = *r.DropFrame
+			}
+			return rate, nil
+		}
+	}
+	if r.Duration != nil {
+		duration, pErr := parseDuration(*r.Duration, frameRate)
+		if pErr != nil {
+			return nil, fmt.Errorf("failed to parse duration: %w", pErr)
+		}
+		p.Duration = &duration
+	}
+	return p, nil
+}
+
+func (s *Server) UpdateVideo(w http.ResponseWriter, r *http.Request) {
+	req := &UpdateVideoRequest{}
+	if dErr := json.NewDecoder(r.Body).Decode(req); dErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
+		return
+	}
+	id := mux.Vars(r)[entityIDKey]
+	p, pErr := toUpdateVideoParams(req, s.videoFrameRate(r.Context(), id))
+	if pErr != nil {
+		s.ErrorResponse(w, pErr, http.StatusBadRequest)
+		return
+	}
+	err := s.controller.UpdateVideo(r.Context(), id, p)
+	var conflict *model.ConflictError
+	if errors.As(err, &conflict) {
+		s.ConflictResponse(w, fmt.Errorf("failed to update video: %w", err), conflict)
+		return
+	}
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to update video: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to update video: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to update video: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to update video: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, Response{Message: "video updated successfully"})
 }
 
 func (s *Server) DeleteVideo(w http.ResponseWriter, r *http.Request) {
@@ -73,6 +239,14 @@ func (s *Server) DeleteVideo(w http.ResponseWriter, r *http.Request) {
 		s.ErrorResponse(w, fmt.Errorf("failed to delete video: %w", err), http.StatusBadRequest)
 		return
 	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to delete video: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to delete video: %w", err), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		s.ErrorResponse(w, fmt.Errorf("failed to delete video: %w", err), http.StatusInternalServerError)
 		return
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+// fakeUpdateVideoController returns the video and fails to update it with err.
+type fakeUpdateVideoController struct {
+	Controller
+	err error
+}
+
+func (c *fakeUpdateVideoController) GetVideo(_ context.Context, id string) (*model.Video, error) {
+	return &model.Video{ID: id, Duration: time.Minute}, nil
+}
+
+func (c *fakeUpdateVideoController) UpdateVideo(_ context.Context, _ string, _ *model.UpdateVideoParams) error {
+	return c.err
+}
+
+func TestUpdateVideoErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		wantCode int
+		wantIDs  []string
+	}{
+		{name: "updated", body: `{"duration":"30s"}`, wantCode: http.StatusOK},
+		{name: "invalid duration", body: `{"duration":"soon"}`, wantCode: http.StatusBadRequest},
+		{
+			name: "annotations after duration", body: `{"duration":"1s"}`,
+			err:      &model.ConflictError{Reason: "annotations end after the new duration", AnnotationIDs: []string{"a"}},
+			wantCode: http.StatusConflict, wantIDs: []string{"a"},
+		},
+		{
+			name: "not manager", body: `{"url":"https://example.com"}`,
+			err: fmt.Errorf("manage permission: %w", model.ErrForbidden), wantCode: http.StatusForbidden,
+		},
+		{
+			name: "missing", body: `{"url":"https://example.com"}`,
+			err: fmt.Errorf("video: %w", model.ErrNotFound), wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(zap.NewNop(), &Config{}, nil, nil, &fakeUpdateVideoController{err: tt.err})
+			r := httptest.NewRequest(http.MethodPatch, "/v1/videos/video", strings.NewReader(tt.body))
+			r = mux.SetURLVars(r, map[string]string{entityIDKey: "video"})
+			w := httptest.NewRecorder()
+			s.UpdateVideo(w, r)
+			if w.Code != tt.wantCode {
+				t.Fatalf("UpdateVideo() code = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantIDs == nil {
+				return
+			}
+			resp := &ConflictResponse{}
+			if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(resp.AnnotationIDs) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("UpdateVideo() annotation ids = %v, want %v", resp.AnnotationIDs, tt.wantIDs)
+			}
+		})
+	}
+}
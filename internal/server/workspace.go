@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+)
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+type CreateWorkspaceResponse struct {
+	WorkspaceID string `json:"workspace_id"`
+}
+
+func (s *Server) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	req := &CreateWorkspaceRequest{}
+	if dErr := json.NewDecoder(r.Body).Decode(req); dErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
+		return
+	}
+	workspaceID, err := s.controller.CreateWorkspace(r.Context(), req.Name)
+	if errors.Is(err, model.ErrInvalidArgument) || errors.Is(err, model.ErrAlreadyExists) {
+		s.ErrorResponse(w, fmt.Errorf("failed to create workspace: %w", err), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to create workspace: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, CreateWorkspaceResponse{WorkspaceID: workspaceID})
+}
+
+type ListWorkspacesResponse struct {
+	Workspaces []*model.Workspace `json:"workspaces"`
+}
+
+func (s *Server) ListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	workspaces, err := s.controller.ListWorkspaces(r.Context())
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to list workspaces: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, &ListWorkspacesResponse{Workspaces: workspaces})
+}
+
+type AddWorkspaceMemberRequest struct {
+	UserID string `json:"user_id"`
+}
+
+func (s *Server) AddWorkspaceMember(w http.ResponseWriter, r *http.Request) {
+	req := &AddWorkspaceMemberRequest{}
+	if dErr := json.NewDecoder(r.Body).Decode(req); dErr != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to parse request: %w", dErr), http.StatusBadRequest)
+		return
+	}
+	err := s.controller.AddWorkspaceMember(r.Context(), mux.Vars(r)[entityIDKey], req.UserID)
+	if errors.Is(err, model.ErrInvalidArgument) || errors.Is(err, model.ErrAlreadyExists) {
+		s.ErrorResponse(w, fmt.Errorf("failed to add workspace member: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to add workspace member: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to add workspace member: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to add workspace member: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, Response{Message: "workspace member added successfully"})
+}
+
+func (s *Server) RemoveWorkspaceMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	err := s.controller.RemoveWorkspaceMember(r.Context(), vars[entityIDKey], vars[userIDKey])
+	if errors.Is(err, model.ErrInvalidArgument) {
+		s.ErrorResponse(w, fmt.Errorf("failed to remove workspace member: %w", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrForbidden) {
+		s.ErrorResponse(w, fmt.Errorf("failed to remove workspace member: %w", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		s.ErrorResponse(w, fmt.Errorf("failed to remove workspace member: %w", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.ErrorResponse(w, fmt.Errorf("failed to remove workspace member: %w", err), http.StatusInternalServerError)
+		return
+	}
+	s.SuccessResponse(w, Response{Message: "workspace member removed successfully"})
+}
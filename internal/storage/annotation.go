@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -16,11 +17,18 @@ import (
 
 const annotationTable = "annotations"
 
-func (s *Storage) GetAnnotationWithDuration(ctx context.Context, id string) (*model.Annotation, error) {
+// annotationSortColumns is the whitelist of columns annotations can be sorted by.
+var annotationSortColumns = map[model.SortField]string{
+	model.StartTimeSortField: "start_time",
+	model.CreatedAtSortField: "created_at",
+	model.UpdatedAtSortField: "updated_at",
+}
+
+func (s *Storage) GetAnnotationWithDuration(ctx context.Context, workspaceID, id string) (*model.Annotation, error) {
 	sql, params, err := postgresql.StatementBuilder.
 		Select(append(annotationColumns(), "videos.duration")...).
 		From(annotationTable).
-		Where(squirrel.Eq{"annotations.id": id}).
+		Where(squirrel.Eq{"annotations.workspace_id": workspaceID, "annotations.id": id}).
 		Join(fmt.Sprintf("%s ON %s.video_id = %s.id", videoTable, annotationTable, videoTable)).
 		ToSql()
 	if err != nil {
@@ -39,20 +47,28 @@ func (s *Storage) GetAnnotationWithDuration(ctx context.Context, id string) (*mo
 	return a, nil
 }
 
-func (s *Storage) ListAnnotations(ctx context.Context, videoID string) ([]*model.Annotation, error) {
-	sql, params, err := postgresql.StatementBuilder.
+func (s *Storage) ListAnnotations(
+	ctx context.Context, workspaceID, videoID string, f *model.AnnotationFilter, page *model.Page,
+) ([]*model.Annotation, *model.Cursor, error) {
+	builder := postgresql.StatementBuilder.
 		Select(annotationColumns()...).
-		Where(squirrel.Eq{"video_id": videoID}).
-		From(annotationTable).
-		OrderBy("updated_at").
-		ToSql()
+		Where(squirrel.Eq{"workspace_id": workspaceID, "video_id": videoID}).
+		From(annotationTable)
+	if f != nil {
+		builder = builder.Where(annotationFilter(f))
+	}
+	builder, pErr := paginate(builder, page, annotationSortColumns)
+	if pErr != nil {
+		return nil, nil, pErr
+	}
+	sql, params, err := builder.ToSql()
 	if err != nil {
-		return nil, fmt.Errorf("failed to build query: %w", err)
+		return nil, nil, fmt.Errorf("failed to build query: %w", err)
 	}
 
 	rows, err := s.client.DB.Query(ctx, sql, params...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to exec query: %w", err)
+		return nil, nil, fmt.Errorf("failed to exec query: %w", err)
 	}
 	defer rows.Close()
 
@@ -60,61 +76,109 @@ func (s *Storage) ListAnnotations(ctx context.Context, videoID string) ([]*model
 	for rows.Next() {
 		a, sErr := scanAnnotation(rows, false)
 		if sErr != nil {
-			return nil, fmt.Errorf("scan failed: %w", sErr)
+			return nil, nil, fmt.Errorf("scan failed: %w", sErr)
 		}
 		result = append(result, a)
 	}
 	if rErr := rows.Err(); rErr != nil {
-		return nil, rErr
+		return nil, nil, rErr
 	}
-	return result, nil
+
+	var next *model.Cursor
+	if n, more := trimPage(len(result), page); more {
+		result = result[:n]
+		next = result[n-1].Cursor(page.Sort)
+	}
+	return result, next, nil
 }
 
-func (s *Storage) InsertAnnotation(ctx context.Context, a *model.Annotation) error {
+// InsertAnnotation inserts the annotation and returns ids of annotations of the same type overlapping it,
+// see checkOverlaps. Video is locked and annotation is checked to end within its duration, so concurrent
+// UpdateVideo can't shrink the video under the annotation.
+func (s *Storage) InsertAnnotation(
+	ctx context.Context, a *model.Annotation, check *model.OverlapCheck,
+) ([]string, error) {
+	region, rErr := regionValue(a.Region)
+	if rErr != nil {
+		return nil, rErr
+	}
 	query, args, err := postgresql.StatementBuilder.
 		Insert(annotationTable).
 		SetMap(map[string]interface{}{
-			"id":         a.ID,
-			"video_id":   a.VideoID,
-			"user_id":    a.UserID,
-			"start_time": a.StartTime.Seconds(),
-			"end_time":   a.EndTime.Seconds(),
-			"type":       a.Type,
-			"message":    a.Message,
-			"url":        a.URL,
-			"title":      a.Title,
-			"created_at": a.CreatedAt,
-			"updated_at": a.UpdatedAt,
+			"id":           a.ID,
+			"workspace_id": a.WorkspaceID,
+			"video_id":     a.VideoID,
+			"user_id":      a.UserID,
+			"start_time":   a.StartTime.Milliseconds(),
+			"end_time":     a.EndTime.Milliseconds(),
+			"type":         a.Type,
+			"message":      a.Message,
+			"url":          a.URL,
+			"title":        a.Title,
+			"payload":      payloadValue(a.Payload),
+			"region":       region,
+			"created_at":   a.CreatedAt,
+			"updated_at":   a.UpdatedAt,
 		}).ToSql()
 	if err != nil {
-		return fmt.Errorf("failed to build query: %w", err)
+		return nil, fmt.Errorf("failed to build query: %w", err)
 	}
 
-	_, qErr := s.client.DB.Exec(ctx, query, args...)
-	if qErr != nil {
-		pgErr, ok := qErr.(*pgconn.PgError)
-		if ok && pgErr.Code == uniqueViolation {
-			return model.ErrAlreadyExists
+	var overlapping []string
+	txErr := s.inTx(ctx, func(tx pgx.Tx) error {
+		duration, lErr := lockVideo(ctx, tx, a.WorkspaceID, a.VideoID)
+		if lErr != nil {
+			return lErr
+		}
+		if a.EndTime > duration {
+			return fmt.Errorf("annotation end time exceeds video duration: %w", model.ErrInvalidArgument)
 		}
-		return fmt.Errorf("failed to insert: %w", qErr)
+		if tErr := lockAnnotationType(ctx, tx, a.Type); tErr != nil {
+			return tErr
+		}
+		var oErr error
+		if overlapping, oErr = checkOverlaps(ctx, tx, a.WorkspaceID, a.VideoID, a.ID, check); oErr != nil {
+			return oErr
+		}
+		if _, qErr := tx.Exec(ctx, query, args...); qErr != nil {
+			pgErr, ok := qErr.(*pgconn.PgError)
+			if ok && pgErr.Code == uniqueViolation {
+				return model.ErrAlreadyExists
+			}
+			if ok && pgErr.Code == foreignKeyViolation {
+				return model.ErrNotFound
+			}
+			return fmt.Errorf("failed to insert: %w", qErr)
+		}
+		return nil
+	})
+	if txErr != nil {
+		return nil, txErr
 	}
-	return nil
+	return overlapping, nil
 }
 
-func (s *Storage) UpdateAnnotation(ctx context.Context, id string, p *model.UpdateAnnotationParams) error {
+// UpdateAnnotation changes the annotation of the video and returns ids of annotations of the same type
+// overlapping it after update, see checkOverlaps. Video and annotation are locked, so the update is merged
+// with the current annotation and checked to keep its times ordered and within video duration.
+// Overlaps are checked with overlapCheck only if the annotation moves.
+func (s *Storage) UpdateAnnotation(
+	ctx context.Context, workspaceID, videoID, id, userID string, p *model.UpdateAnnotationParams,
+	overlapCheck model.OverlapCheckFunc,
+) ([]string, error) {
 	if p.NoUpdates() {
-		return fmt.Errorf("no updates")
+		return nil, fmt.Errorf("no updates")
 	}
 	builder := postgresql.StatementBuilder.
 		Update(annotationTable).
-		Where(squirrel.Eq{"id": id}).
+		Where(ownedBy(workspaceID, id, userID)).
 		Set("updated_at", time.Now())
 
 	if p.StartTime != nil {
-		builder = builder.Set("start_time", p.StartTime.Seconds())
+		builder = builder.Set("start_time", p.StartTime.Milliseconds())
 	}
 	if p.EndTime != nil {
-		builder = builder.Set("end_time", p.EndTime.Seconds())
+		builder = builder.Set("end_time", p.EndTime.Milliseconds())
 	}
 	if p.Type != nil {
 		builder = builder.Set("type", *p.Type)
@@ -128,71 +192,260 @@ func (s *Storage) UpdateAnnotation(ctx context.Context, id string, p *model.Upda
 	if p.Title != nil {
 		builder = builder.Set("title", *p.Title)
 	}
+	if p.Payload != nil {
+		builder = builder.Set("payload", payloadValue(p.Payload))
+	}
+	if p.Region != nil {
+		region, rErr := regionValue(p.Region)
+		if rErr != nil {
+			return nil, rErr
+		}
+		builder = builder.Set("region", region)
+	}
 	sql, params, err := builder.ToSql()
 	if err != nil {
-		return fmt.Errorf("failed to build query: %w", err)
+		return nil, fmt.Errorf("failed to build query: %w", err)
 	}
-
-	ct, err := s.client.DB.Exec(ctx, sql, params...)
+	lockSQL, lockParams, err := postgresql.StatementBuilder.
+		Select(annotationColumns()...).
+		From(annotationTable).
+		Where(ownedBy(workspaceID, id, userID)).
+		Suffix("FOR UPDATE").
+		ToSql()
 	if err != nil {
-		return fmt.Errorf("failed to execute: %w", err)
+		return nil, fmt.Errorf("failed to build query: %w", err)
 	}
-	switch ra := ct.RowsAffected(); ra {
-	case 0:
-		return model.ErrNotFound
-	case 1:
+
+	var overlapping []string
+	txErr := s.inTx(ctx, func(tx pgx.Tx) error {
+		duration, lErr := lockVideo(ctx, tx, workspaceID, videoID)
+		if lErr != nil {
+			return lErr
+		}
+		current, sErr := scanAnnotation(tx.QueryRow(ctx, lockSQL, lockParams...), false)
+		if errors.Is(sErr, pgx.ErrNoRows) {
+			return missingOrForbidden(ctx, tx, annotationTable, workspaceID, id)
+		}
+		if sErr != nil {
+			return fmt.Errorf("failed to lock annotation: %w", sErr)
+		}
+		if p.Type != nil {
+			if tErr := lockAnnotationType(ctx, tx, *p.Type); tErr != nil {
+				return tErr
+			}
+		}
+		check, vErr := validateUpdate(current, p, duration, overlapCheck)
+		if vErr != nil {
+			return vErr
+		}
+		var oErr error
+		if overlapping, oErr = checkOverlaps(ctx, tx, workspaceID, videoID, id, check); oErr != nil {
+			return oErr
+		}
+		if _, qErr := tx.Exec(ctx, sql, params...); qErr != nil {
+			return fmt.Errorf("failed to execute: %w", qErr)
+		}
 		return nil
-	default:
-		return fmt.Errorf("update more than one (%d) annotation with id %q", ra, id)
+	})
+	if txErr != nil {
+		return nil, txErr
+	}
+	return overlapping, nil
+}
+
+// validateUpdate checks the locked annotation after update and returns its overlap check,
+// nil if the annotation doesn't move.
+func validateUpdate(
+	current *model.Annotation, p *model.UpdateAnnotationParams, duration time.Duration,
+	overlapCheck model.OverlapCheckFunc,
+) (*model.OverlapCheck, error) {
+	t, start, end := p.Bounds(current)
+	if end < start {
+		return nil, fmt.Errorf("start time should be less or equal than end time: %w", model.ErrInvalidArgument)
+	}
+	if end > duration {
+		return nil, fmt.Errorf("annotation end time exceeds video duration: %w", model.ErrInvalidArgument)
+	}
+	if rErr := p.ValidateRegion(current); rErr != nil {
+		return nil, rErr
+	}
+	if !p.Moves() {
+		return nil, nil
 	}
+	return overlapCheck(t, start, end)
 }
 
-func (s *Storage) DeleteAnnotation(ctx context.Context, id string) error {
+// lockVideo locks video row until the end of transaction and returns its duration, so concurrent changes
+// of the video and its annotations can't slip past each other.
+func lockVideo(ctx context.Context, tx pgx.Tx, workspaceID, videoID string) (time.Duration, error) {
+	sql, params, err := postgresql.StatementBuilder.
+		Select("duration").
+		From(videoTable).
+		Where(squirrel.Eq{"workspace_id": workspaceID, "id": videoID}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build query: %w", err)
+	}
+	var duration int64
+	lErr := tx.QueryRow(ctx, sql, params...).Scan(&duration)
+	if errors.Is(lErr, pgx.ErrNoRows) {
+		return 0, model.ErrNotFound
+	}
+	if lErr != nil {
+		return 0, fmt.Errorf("failed to lock video: %w", lErr)
+	}
+	return time.Duration(duration) * time.Millisecond, nil
+}
+
+// checkOverlaps finds annotations of the checked type overlapping the annotation with id, which itself
+// is skipped, the video has to be locked with lockVideo. RejectOverlapPolicy fails with model.ConflictError,
+// nil check and AllowOverlapPolicy skip the check.
+func checkOverlaps(
+	ctx context.Context, tx pgx.Tx, workspaceID, videoID, id string, check *model.OverlapCheck,
+) ([]string, error) {
+	if check == nil || check.Policy == model.AllowOverlapPolicy {
+		return nil, nil
+	}
+	sql, params, err := postgresql.StatementBuilder.
+		Select("id").
+		From(annotationTable).
+		Where(squirrel.Eq{"workspace_id": workspaceID, "video_id": videoID, "type": check.Type}).
+		Where(squirrel.NotEq{"id": id}).
+		Where(squirrel.Lt{"start_time": check.EndTime.Milliseconds()}).
+		Where(squirrel.Gt{"end_time": check.StartTime.Milliseconds()}).
+		OrderBy("start_time", "id").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var overlappingID string
+		if sErr := rows.Scan(&overlappingID); sErr != nil {
+			return nil, fmt.Errorf("scan failed: %w", sErr)
+		}
+		ids = append(ids, overlappingID)
+	}
+	if rErr := rows.Err(); rErr != nil {
+		return nil, rErr
+	}
+	if len(ids) > 0 && check.Policy == model.RejectOverlapPolicy {
+		return nil, &model.ConflictError{Reason: fmt.Sprintf("%s annotations overlap", check.Type), AnnotationIDs: ids}
+	}
+	return ids, nil
+}
+
+func (s *Storage) DeleteAnnotation(ctx context.Context, workspaceID, id, userID string) error {
 	deleteBuilder := postgresql.StatementBuilder.Delete(annotationTable).
-		Where(squirrel.Eq{"id": id})
+		Where(ownedBy(workspaceID, id, userID))
 
 	sql, params, err := deleteBuilder.ToSql()
 	if err != nil {
 		return fmt.Errorf("failed to build query: %w", err)
 	}
-	_, err = s.client.DB.Exec(ctx, sql, params...)
+	ct, err := s.client.DB.Exec(ctx, sql, params...)
 	if err != nil {
 		return fmt.Errorf("failed to delete: %w", err)
 	}
+	if ct.RowsAffected() == 0 {
+		return missingOrForbidden(ctx, s.client.DB, annotationTable, workspaceID, id)
+	}
 	return nil
 }
 
 func annotationColumns() []string {
 	columns := []string{
-		"annotations.id", "annotations.video_id", "annotations.user_id", "annotations.start_time",
-		"annotations.end_time", "annotations.type", "annotations.message", "annotations.url",
-		"annotations.title", "annotations.created_at", "annotations.updated_at",
+		"annotations.id", "annotations.workspace_id", "annotations.video_id", "annotations.user_id",
+		"annotations.start_time", "annotations.end_time", "annotations.type", "annotations.message",
+		"annotations.url", "annotations.title", "annotations.payload", "annotations.region",
+		"annotations.created_at", "annotations.updated_at",
 	}
 	return columns
 }
 
+// annotationFilter builds conditions of the filter, annotation overlaps the time window
+// if it starts before its end and ends after its start.
+func annotationFilter(f *model.AnnotationFilter) squirrel.And {
+	cond := squirrel.And{}
+	if f.Type != "" {
+		cond = append(cond, squirrel.Eq{"type": f.Type})
+	}
+	if f.UserID != "" {
+		cond = append(cond, squirrel.Eq{"user_id": f.UserID})
+	}
+	if f.From != nil {
+		cond = append(cond, squirrel.GtOrEq{"end_time": f.From.Milliseconds()})
+	}
+	if f.To != nil {
+		cond = append(cond, squirrel.LtOrEq{"start_time": f.To.Milliseconds()})
+	}
+	if f.CreatedAfter != nil {
+		cond = append(cond, squirrel.GtOrEq{"created_at": *f.CreatedAfter})
+	}
+	if f.CreatedBefore != nil {
+		cond = append(cond, squirrel.Lt{"created_at": *f.CreatedBefore})
+	}
+	return cond
+}
+
+// regionValue encodes region as jsonb, empty region is stored as NULL.
+func regionValue(r *model.Region) (interface{}, error) {
+	if r == nil || r.Empty() && len(r.Keyframes) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal region: %w", err)
+	}
+	return data, nil
+}
+
+// payloadValue stores empty and null payload as NULL.
+func payloadValue(p json.RawMessage) interface{} {
+	if len(p) == 0 || string(p) == "null" {
+		return nil
+	}
+	return []byte(p)
+}
+
 func scanAnnotation(row pgx.Row, withDuration bool) (*model.Annotation, error) {
 	var a model.Annotation
-	var startTime, endTime, vidDuration int
+	var startTime, endTime, vidDuration int64
+	var payload, region []byte
 	var rErr error
 	if withDuration {
 		rErr = row.Scan(
-			&a.ID, &a.VideoID, &a.UserID, &startTime,
-			&endTime, &a.Type, &a.Message, &a.URL, &a.Title,
+			&a.ID, &a.WorkspaceID, &a.VideoID, &a.UserID, &startTime,
+			&endTime, &a.Type, &a.Message, &a.URL, &a.Title, &payload, &region,
 			&a.CreatedAt, &a.UpdatedAt, &vidDuration,
 		)
-		a.VideoDuration = time.Duration(vidDuration) * time.Second
+		a.VideoDuration = time.Duration(vidDuration) * time.Millisecond
 	} else {
 		rErr = row.Scan(
-			&a.ID, &a.VideoID, &a.UserID, &startTime,
-			&endTime, &a.Type, &a.Message, &a.URL, &a.Title,
+			&a.ID, &a.WorkspaceID, &a.VideoID, &a.UserID, &startTime,
+			&endTime, &a.Type, &a.Message, &a.URL, &a.Title, &payload, &region,
 			&a.CreatedAt, &a.UpdatedAt,
 		)
 	}
 	if rErr != nil {
 		return nil, fmt.Errorf("failed to scan annotation: %w", rErr)
 	}
-	a.StartTime = time.Duration(startTime) * time.Second
-	a.EndTime = time.Duration(endTime) * time.Second
+	a.Payload = payload
+	if region != nil {
+		a.Region = &model.Region{}
+		if uErr := json.Unmarshal(region, a.Region); uErr != nil {
+			return nil, fmt.Errorf("failed to unmarshal region: %w", uErr)
+		}
+	}
+	a.StartTime = time.Duration(startTime) * time.Millisecond
+	a.EndTime = time.Duration(endTime) * time.Millisecond
 	return &a, nil
 }
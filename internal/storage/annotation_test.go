@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+	"github.com/triabokon/gotagv/internal/postgresql"
+)
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
+
+func TestValidateUpdate(t *testing.T) {
+	// the annotation as locked, it may have changed since the update was validated
+	current := &model.Annotation{
+		Type: model.TitleAnnotationType, StartTime: 10 * time.Second, EndTime: 20 * time.Second,
+	}
+	chapter := model.ChapterAnnotationType
+	tests := []struct {
+		name      string
+		p         *model.UpdateAnnotationParams
+		wantCheck *model.OverlapCheck
+		wantErr   error
+	}{
+		{
+			name: "new start",
+			p:    &model.UpdateAnnotationParams{StartTime: durationPtr(15 * time.Second)},
+			wantCheck: &model.OverlapCheck{
+				Type: model.TitleAnnotationType, StartTime: 15 * time.Second, EndTime: 20 * time.Second,
+			},
+		},
+		{
+			name:    "new start after current end",
+			p:       &model.UpdateAnnotationParams{StartTime: durationPtr(25 * time.Second)},
+			wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name:    "new end before current start",
+			p:       &model.UpdateAnnotationParams{EndTime: durationPtr(5 * time.Second)},
+			wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name:    "new end after video",
+			p:       &model.UpdateAnnotationParams{EndTime: durationPtr(2 * time.Minute)},
+			wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name:      "new type",
+			p:         &model.UpdateAnnotationParams{Type: &chapter},
+			wantCheck: &model.OverlapCheck{Type: chapter, StartTime: 10 * time.Second, EndTime: 20 * time.Second},
+		},
+		{
+			name: "content only",
+			p:    &model.UpdateAnnotationParams{Title: new(string)},
+		},
+		{
+			name: "region outside of the annotation",
+			p: &model.UpdateAnnotationParams{Region: &model.Region{
+				Shape:     model.Shape{Box: &model.Box{W: 0.5, H: 0.5}},
+				Keyframes: []model.Keyframe{{Time: 25 * time.Second, Shape: model.Shape{Box: &model.Box{W: 0.5, H: 0.5}}}},
+			}},
+			wantErr: model.ErrInvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check, err := validateUpdate(current, tt.p, time.Minute,
+				func(t model.AnnotationType, start, end time.Duration) (*model.OverlapCheck, error) {
+					return &model.OverlapCheck{Type: t, StartTime: start, EndTime: end}, nil
+				},
+			)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("validateUpdate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (check == nil) != (tt.wantCheck == nil) || check != nil && *check != *tt.wantCheck {
+				t.Errorf("validateUpdate() = %+v, want %+v", check, tt.wantCheck)
+			}
+		})
+	}
+}
+
+func TestAnnotationFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		f          *model.AnnotationFilter
+		wantSQL    string
+		wantParams []interface{}
+	}{
+		{
+			// annotations active at the time, both ends are inclusive
+			name: "at",
+			f: &model.AnnotationFilter{
+				From: durationPtr(92500 * time.Millisecond), To: durationPtr(92500 * time.Millisecond),
+			},
+			wantSQL:    "SELECT id FROM annotations WHERE (end_time >= $1 AND start_time <= $2)",
+			wantParams: []interface{}{int64(92500), int64(92500)},
+		},
+		{
+			name: "range of type",
+			f: &model.AnnotationFilter{
+				Type: model.ChapterAnnotationType, From: durationPtr(0), To: durationPtr(time.Minute),
+			},
+			wantSQL:    "SELECT id FROM annotations WHERE (type = $1 AND end_time >= $2 AND start_time <= $3)",
+			wantParams: []interface{}{model.ChapterAnnotationType, int64(0), int64(60000)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, params, err := postgresql.StatementBuilder.Select("id").From(annotationTable).
+				Where(annotationFilter(tt.f)).ToSql()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("sql = %q, want %q", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("params = %v, want %v", params, tt.wantParams)
+			}
+		})
+	}
+}
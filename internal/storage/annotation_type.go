@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+	"github.com/triabokon/gotagv/internal/postgresql"
+)
+
+const annotationTypeTable = "annotation_types"
+
+func (s *Storage) ListAnnotationTypes(ctx context.Context) ([]*model.AnnotationTypeDef, error) {
+	sql, params, err := postgresql.StatementBuilder.
+		Select(annotationTypeColumns()...).
+		From(annotationTypeTable).
+		OrderBy("name").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := s.client.DB.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*model.AnnotationTypeDef
+	for rows.Next() {
+		t, sErr := scanAnnotationType(rows)
+		if sErr != nil {
+			return nil, fmt.Errorf("scan failed: %w", sErr)
+		}
+		result = append(result, t)
+	}
+	if rErr := rows.Err(); rErr != nil {
+		return nil, rErr
+	}
+	return result, nil
+}
+
+func (s *Storage) GetAnnotationType(ctx context.Context, name model.AnnotationType) (*model.AnnotationTypeDef, error) {
+	sql, params, err := postgresql.StatementBuilder.
+		Select(annotationTypeColumns()...).
+		From(annotationTypeTable).
+		Where(squirrel.Eq{"name": name}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	t, sErr := scanAnnotationType(s.client.DB.QueryRow(ctx, sql, params...))
+	if errors.Is(sErr, pgx.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if sErr != nil {
+		return nil, fmt.Errorf("failed to get annotation type: %w", sErr)
+	}
+	return t, nil
+}
+
+func (s *Storage) InsertAnnotationType(ctx context.Context, t *model.AnnotationTypeDef) error {
+	var payloadFields interface{}
+	if len(t.PayloadFields) > 0 {
+		data, mErr := json.Marshal(t.PayloadFields)
+		if mErr != nil {
+			return fmt.Errorf("failed to marshal payload fields: %w", mErr)
+		}
+		payloadFields = data
+	}
+	query, args, err := postgresql.StatementBuilder.
+		Insert(annotationTypeTable).
+		SetMap(map[string]interface{}{
+			"name":            t.Name,
+			"required_fields": fieldNames(t.RequiredFields),
+			"allowed_fields":  fieldNames(t.AllowedFields),
+			"payload_fields":  payloadFields,
+			"created_at":      t.CreatedAt,
+		}).ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, qErr := s.client.DB.Exec(ctx, query, args...); qErr != nil {
+		pgErr, ok := qErr.(*pgconn.PgError)
+		if ok && pgErr.Code == uniqueViolation {
+			return model.ErrAlreadyExists
+		}
+		return fmt.Errorf("failed to insert: %w", qErr)
+	}
+	return nil
+}
+
+// DeleteAnnotationType deletes the type unless there are annotations of it,
+// in which case model.ErrConflict is returned. The type is locked, so annotations of it
+// can't be inserted concurrently, see lockAnnotationType.
+func (s *Storage) DeleteAnnotationType(ctx context.Context, name model.AnnotationType) error {
+	lockSQL, lockParams, err := postgresql.StatementBuilder.
+		Select("name").
+		From(annotationTypeTable).
+		Where(squirrel.Eq{"name": name}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+	usedSQL, usedParams, err := postgresql.StatementBuilder.
+		Select("id").
+		From(annotationTable).
+		Where(squirrel.Eq{"type": name}).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+	sql, params, err := postgresql.StatementBuilder.
+		Delete(annotationTypeTable).
+		Where(squirrel.Eq{"name": name}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	return s.inTx(ctx, func(tx pgx.Tx) error {
+		var locked string
+		lErr := tx.QueryRow(ctx, lockSQL, lockParams...).Scan(&locked)
+		if errors.Is(lErr, pgx.ErrNoRows) {
+			return model.ErrNotFound
+		}
+		if lErr != nil {
+			return fmt.Errorf("failed to lock annotation type: %w", lErr)
+		}
+		var used string
+		uErr := tx.QueryRow(ctx, usedSQL, usedParams...).Scan(&used)
+		if uErr == nil {
+			return fmt.Errorf("there are annotations of type %s: %w", name, model.ErrConflict)
+		}
+		if !errors.Is(uErr, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to check annotations of type: %w", uErr)
+		}
+		if _, qErr := tx.Exec(ctx, sql, params...); qErr != nil {
+			return fmt.Errorf("failed to delete: %w", qErr)
+		}
+		return nil
+	})
+}
+
+// lockAnnotationType share-locks custom type until the end of transaction, so DeleteAnnotationType
+// waits for annotations of the type being written, or the type is reported unknown if it was deleted.
+// Built-in types aren't stored and need no lock.
+func lockAnnotationType(ctx context.Context, db rowQuerier, name model.AnnotationType) error {
+	if model.BuiltinAnnotationType(name) != nil {
+		return nil
+	}
+	sql, params, err := postgresql.StatementBuilder.
+		Select("name").
+		From(annotationTypeTable).
+		Where(squirrel.Eq{"name": name}).
+		Suffix("FOR SHARE").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+	var locked string
+	lErr := db.QueryRow(ctx, sql, params...).Scan(&locked)
+	if errors.Is(lErr, pgx.ErrNoRows) {
+		return fmt.Errorf("unknown type %q: %w", name, model.ErrInvalidArgument)
+	}
+	if lErr != nil {
+		return fmt.Errorf("failed to lock annotation type: %w", lErr)
+	}
+	return nil
+}
+
+func annotationTypeColumns() []string {
+	columns := []string{"name", "required_fields", "allowed_fields", "payload_fields", "created_at"}
+	return columns
+}
+
+func fieldNames(fields []model.AnnotationField) []string {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return names
+}
+
+func scanAnnotationType(row pgx.Row) (*model.AnnotationTypeDef, error) {
+	var t model.AnnotationTypeDef
+	var required, allowed []string
+	var payloadFields []byte
+	if rErr := row.Scan(&t.Name, &required, &allowed, &payloadFields, &t.CreatedAt); rErr != nil {
+		return nil, fmt.Errorf("failed to scan annotation type: %w", rErr)
+	}
+	for _, f := range required {
+		t.RequiredFields = append(t.RequiredFields, model.AnnotationField(f))
+	}
+	for _, f := range allowed {
+		t.AllowedFields = append(t.AllowedFields, model.AnnotationField(f))
+	}
+	if payloadFields != nil {
+		if uErr := json.Unmarshal(payloadFields, &t.PayloadFields); uErr != nil {
+			return nil, fmt.Errorf("failed to unmarshal payload fields: %w", uErr)
+		}
+	}
+	return &t, nil
+}
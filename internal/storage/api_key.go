@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+	"github.com/triabokon/gotagv/internal/postgresql"
+)
+
+const apiKeyTable = "api_keys"
+
+func (s *Storage) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	sql, params, err := postgresql.StatementBuilder.
+		Select(apiKeyColumns()...).
+		From(apiKeyTable).
+		Where(squirrel.Eq{"user_id": userID}).
+		OrderBy("created_at").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := s.client.DB.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*model.APIKey
+	for rows.Next() {
+		k, sErr := scanAPIKey(rows)
+		if sErr != nil {
+			return nil, fmt.Errorf("scan failed: %w", sErr)
+		}
+		result = append(result, k)
+	}
+	if rErr := rows.Err(); rErr != nil {
+		return nil, rErr
+	}
+	return result, nil
+}
+
+func (s *Storage) InsertAPIKey(ctx context.Context, k *model.APIKey) error {
+	query, args, err := postgresql.StatementBuilder.
+		Insert(apiKeyTable).
+		SetMap(map[string]interface{}{
+			"id":         k.ID,
+			"user_id":    k.UserID,
+			"name":       k.Name,
+			"key_hash":   k.KeyHash,
+			"scopes":     k.Scopes,
+			"expires_at": k.ExpiresAt,
+			"created_at": k.CreatedAt,
+		}).ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, qErr := s.client.DB.Exec(ctx, query, args...); qErr != nil {
+		pgErr, ok := qErr.(*pgconn.PgError)
+		if ok && pgErr.Code == uniqueViolation {
+			return model.ErrAlreadyExists
+		}
+		if ok && pgErr.Code == foreignKeyViolation {
+			return model.ErrNotFound
+		}
+		return fmt.Errorf("failed to insert: %w", qErr)
+	}
+	return nil
+}
+
+// UseAPIKey records usage of the key and returns it.
+// It returns model.ErrNotFound if there is no such key or it has expired.
+func (s *Storage) UseAPIKey(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	now := time.Now().UTC()
+	sql, params, err := postgresql.StatementBuilder.
+		Update(apiKeyTable).
+		Set("last_used_at", now).
+		Where(squirrel.Eq{"key_hash": keyHash}).
+		Where(squirrel.Or{squirrel.Eq{"expires_at": nil}, squirrel.Gt{"expires_at": now}}).
+		Suffix("RETURNING " + columnList(apiKeyColumns())).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	row := s.client.DB.QueryRow(ctx, sql, params...)
+	k, sErr := scanAPIKey(row)
+	if errors.Is(sErr, pgx.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if sErr != nil {
+		return nil, fmt.Errorf("failed to use api key: %w", sErr)
+	}
+	return k, nil
+}
+
+// DeleteAPIKey deletes key of the user, empty userID allows to delete key of any user.
+func (s *Storage) DeleteAPIKey(ctx context.Context, id, userID string) error {
+	where := squirrel.Eq{"id": id}
+	if userID != "" {
+		where["user_id"] = userID
+	}
+	sql, params, err := postgresql.StatementBuilder.
+		Delete(apiKeyTable).
+		Where(where).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	ct, err := s.client.DB.Exec(ctx, sql, params...)
+	if err != nil {
+		return fmt.Errorf("failed to delete: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func apiKeyColumns() []string {
+	columns := []string{
+		"id", "user_id", "name", "key_hash", "scopes", "expires_at", "last_used_at", "created_at",
+	}
+	return columns
+}
+
+func scanAPIKey(row pgx.Row) (*model.APIKey, error) {
+	var k model.APIKey
+	if rErr := row.Scan(
+		&k.ID, &k.UserID, &k.Name, &k.KeyHash, &k.Scopes, &k.ExpiresAt, &k.LastUsedAt, &k.CreatedAt,
+	); rErr != nil {
+		return nil, fmt.Errorf("failed to scan api key: %w", rErr)
+	}
+	return &k, nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/triabokon/gotagv/internal/model"
+	"github.com/triabokon/gotagv/internal/postgresql"
+)
+
+const rateLimitBucketTable = "rate_limit_buckets"
+
+// TakeRateLimitToken takes a token from the bucket identified by key, new buckets are full.
+// The bucket row is locked, so concurrent requests of all instances are counted.
+// Columns have no time zone, so now has to be UTC to be compared with times read back.
+func (s *Storage) TakeRateLimitToken(
+	ctx context.Context, key string, rate float64, burst int, now time.Time,
+) (*model.RateLimitBucket, bool, error) {
+	insertSQL, insertParams, err := postgresql.StatementBuilder.
+		Insert(rateLimitBucketTable).
+		SetMap(map[string]interface{}{
+			"key":        key,
+			"tokens":     burst,
+			"updated_at": now,
+		}).
+		Suffix("ON CONFLICT (key) DO NOTHING").
+		ToSql()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to build query: %w", err)
+	}
+	selectSQL, selectParams, err := postgresql.StatementBuilder.
+		Select("tokens", "updated_at").
+		From(rateLimitBucketTable).
+		Where(squirrel.Eq{"key": key}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	b := &model.RateLimitBucket{}
+	var allowed bool
+	txErr := s.inTx(ctx, func(tx pgx.Tx) error {
+		if _, qErr := tx.Exec(ctx, insertSQL, insertParams...); qErr != nil {
+			return fmt.Errorf("failed to insert: %w", qErr)
+		}
+		if sErr := tx.QueryRow(ctx, selectSQL, selectParams...).Scan(&b.Tokens, &b.UpdatedAt); sErr != nil {
+			return fmt.Errorf("failed to get rate limit bucket: %w", sErr)
+		}
+		allowed = b.Take(rate, burst, now)
+
+		updateSQL, updateParams, uErr := postgresql.StatementBuilder.
+			Update(rateLimitBucketTable).
+			Set("tokens", b.Tokens).
+			Set("updated_at", b.UpdatedAt).
+			Where(squirrel.Eq{"key": key}).
+			ToSql()
+		if uErr != nil {
+			return fmt.Errorf("failed to build query: %w", uErr)
+		}
+		if _, qErr := tx.Exec(ctx, updateSQL, updateParams...); qErr != nil {
+			return fmt.Errorf("failed to update rate limit bucket: %w", qErr)
+		}
+		return nil
+	})
+	if txErr != nil {
+		return nil, false, txErr
+	}
+	return b, allowed, nil
+}
+
+// PruneRateLimitBuckets deletes buckets which weren't used since before, they are full by then.
+func (s *Storage) PruneRateLimitBuckets(ctx context.Context, before time.Time) (int64, error) {
+	sql, params, err := postgresql.StatementBuilder.
+		Delete(rateLimitBucketTable).
+		Where(squirrel.Lt{"updated_at": before}).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build query: %w", err)
+	}
+	ct, qErr := s.client.DB.Exec(ctx, sql, params...)
+	if qErr != nil {
+		return 0, fmt.Errorf("failed to prune rate limit buckets: %w", qErr)
+	}
+	return ct.RowsAffected(), nil
+}
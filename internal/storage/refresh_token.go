@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+	"github.com/triabokon/gotagv/internal/postgresql"
+)
+
+const refreshTokenTable = "refresh_tokens"
+
+func (s *Storage) GetRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	sql, params, err := postgresql.StatementBuilder.
+		Select(refreshTokenColumns()...).
+		From(refreshTokenTable).
+		Where(squirrel.Eq{"token_hash": tokenHash}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	row := s.client.DB.QueryRow(ctx, sql, params...)
+	t, sErr := scanRefreshToken(row)
+	if errors.Is(sErr, pgx.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if sErr != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", sErr)
+	}
+	return t, nil
+}
+
+func (s *Storage) InsertRefreshToken(ctx context.Context, t *model.RefreshToken) error {
+	query, args, err := postgresql.StatementBuilder.
+		Insert(refreshTokenTable).
+		SetMap(map[string]interface{}{
+			"id":         t.ID,
+			"user_id":    t.UserID,
+			"family_id":  t.FamilyID,
+			"token_hash": t.TokenHash,
+			"expires_at": t.ExpiresAt,
+			"created_at": t.CreatedAt,
+		}).ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, qErr := s.client.DB.Exec(ctx, query, args...); qErr != nil {
+		pgErr, ok := qErr.(*pgconn.PgError)
+		if ok && pgErr.Code == uniqueViolation {
+			return model.ErrAlreadyExists
+		}
+		if ok && pgErr.Code == foreignKeyViolation {
+			return model.ErrNotFound
+		}
+		return fmt.Errorf("failed to insert: %w", qErr)
+	}
+	return nil
+}
+
+// UseRefreshToken marks token as used, so it can't be exchanged twice.
+// It returns model.ErrNotFound if there is no such token or it has been already used or revoked.
+func (s *Storage) UseRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	sql, params, err := postgresql.StatementBuilder.
+		Update(refreshTokenTable).
+		Set("used_at", time.Now().UTC()).
+		Where(squirrel.Eq{"token_hash": tokenHash, "used_at": nil, "revoked_at": nil}).
+		Suffix("RETURNING " + columnList(refreshTokenColumns())).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	row := s.client.DB.QueryRow(ctx, sql, params...)
+	t, sErr := scanRefreshToken(row)
+	if errors.Is(sErr, pgx.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if sErr != nil {
+		return nil, fmt.Errorf("failed to use refresh token: %w", sErr)
+	}
+	return t, nil
+}
+
+func (s *Storage) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	sql, params, err := postgresql.StatementBuilder.
+		Update(refreshTokenTable).
+		Set("revoked_at", time.Now().UTC()).
+		Where(squirrel.Eq{"family_id": familyID, "revoked_at": nil}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, qErr := s.client.DB.Exec(ctx, sql, params...); qErr != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", qErr)
+	}
+	return nil
+}
+
+func refreshTokenColumns() []string {
+	columns := []string{
+		"id", "user_id", "family_id", "token_hash", "expires_at", "created_at", "used_at", "revoked_at",
+	}
+	return columns
+}
+
+func scanRefreshToken(row pgx.Row) (*model.RefreshToken, error) {
+	var t model.RefreshToken
+	if rErr := row.Scan(
+		&t.ID, &t.UserID, &t.FamilyID, &t.TokenHash,
+		&t.ExpiresAt, &t.CreatedAt, &t.UsedAt, &t.RevokedAt,
+	); rErr != nil {
+		return nil, fmt.Errorf("failed to scan refresh token: %w", rErr)
+	}
+	return &t, nil
+}
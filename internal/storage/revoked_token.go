@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/triabokon/gotagv/internal/model"
+	"github.com/triabokon/gotagv/internal/postgresql"
+)
+
+// revokedTokenTable keeps times in UTC, its columns have no time zone.
+const revokedTokenTable = "revoked_tokens"
+
+func (s *Storage) RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error {
+	query, args, err := postgresql.StatementBuilder.
+		Insert(revokedTokenTable).
+		SetMap(map[string]interface{}{
+			"jti":        jti,
+			"user_id":    userID,
+			"expires_at": expiresAt.UTC(),
+			"revoked_at": time.Now().UTC(),
+		}).
+		Suffix("ON CONFLICT (jti) DO NOTHING").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, qErr := s.client.DB.Exec(ctx, query, args...); qErr != nil {
+		return fmt.Errorf("failed to insert: %w", qErr)
+	}
+	return nil
+}
+
+// IsTokenRevoked reports whether token was revoked by itself
+// or was issued before all tokens of the user were revoked.
+func (s *Storage) IsTokenRevoked(ctx context.Context, jti, userID string, issuedAt time.Time) (bool, error) {
+	sql, params, err := isTokenRevokedQuery(jti, userID, issuedAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var isRevoked bool
+	if rErr := s.client.DB.QueryRow(ctx, sql, params...).Scan(&isRevoked); rErr != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", rErr)
+	}
+	return isRevoked, nil
+}
+
+// isTokenRevokedQuery builds the revocation check. Both tokens_revoked_at and iat of tokens have second
+// precision, so tokens issued in the second all tokens were revoked are rejected, including the one
+// issued right after the revocation, and the user has to sign in again.
+func isTokenRevokedQuery(jti, userID string, issuedAt time.Time) (string, []interface{}, error) {
+	revoked := squirrel.Select("1").
+		From(revokedTokenTable).
+		Where(squirrel.Eq{"jti": jti})
+	revokedBefore := squirrel.Select("1").
+		From(userTable).
+		Where(squirrel.Eq{"id": userID}).
+		Where(squirrel.GtOrEq{"tokens_revoked_at": issuedAt})
+	return postgresql.StatementBuilder.
+		Select().
+		Column(squirrel.Expr("EXISTS (?) OR EXISTS (?)", revoked, revokedBefore)).
+		ToSql()
+}
+
+// tokensRevokedAt truncates time of revocation to whole seconds of iat claim.
+func tokensRevokedAt(now time.Time) time.Time {
+	return now.Truncate(time.Second)
+}
+
+// RevokeUserTokens rejects all access tokens issued to the user so far and revokes its refresh tokens.
+func (s *Storage) RevokeUserTokens(ctx context.Context, userID string) error {
+	now := time.Now().UTC()
+	userSQL, userParams, err := postgresql.StatementBuilder.
+		Update(userTable).
+		Set("tokens_revoked_at", tokensRevokedAt(now)).
+		Where(squirrel.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+	refreshSQL, refreshParams, err := postgresql.StatementBuilder.
+		Update(refreshTokenTable).
+		Set("revoked_at", now).
+		Where(squirrel.Eq{"user_id": userID, "revoked_at": nil}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	return s.inTx(ctx, func(tx pgx.Tx) error {
+		ct, qErr := tx.Exec(ctx, userSQL, userParams...)
+		if qErr != nil {
+			return fmt.Errorf("failed to revoke access tokens: %w", qErr)
+		}
+		if ct.RowsAffected() == 0 {
+			return model.ErrNotFound
+		}
+		if _, qErr = tx.Exec(ctx, refreshSQL, refreshParams...); qErr != nil {
+			return fmt.Errorf("failed to revoke refresh tokens: %w", qErr)
+		}
+		return nil
+	})
+}
+
+// PruneExpiredTokens deletes revocation entries and refresh tokens that expired before given time.
+func (s *Storage) PruneExpiredTokens(ctx context.Context, before time.Time) (int64, error) {
+	var pruned int64
+	for _, table := range []string{revokedTokenTable, refreshTokenTable} {
+		sql, params, err := postgresql.StatementBuilder.
+			Delete(table).
+			Where(squirrel.Lt{"expires_at": before}).
+			ToSql()
+		if err != nil {
+			return pruned, fmt.Errorf("failed to build query: %w", err)
+		}
+		ct, qErr := s.client.DB.Exec(ctx, sql, params...)
+		if qErr != nil {
+			return pruned, fmt.Errorf("failed to prune %s: %w", table, qErr)
+		}
+		pruned += ct.RowsAffected()
+	}
+	return pruned, nil
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestTokensRevokedAt(t *testing.T) {
+	revokedAt := time.Date(2023, 5, 1, 12, 0, 0, 900*int(time.Millisecond), time.UTC)
+	got := tokensRevokedAt(revokedAt)
+	if want := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("tokensRevokedAt() = %v, want %v", got, want)
+	}
+}
+
+func TestIsTokenRevokedQuery(t *testing.T) {
+	second := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	revokedAt := tokensRevokedAt(second.Add(500 * time.Millisecond))
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		want     bool
+	}{
+		{name: "issued before revocation in the same second", issuedAt: second.Add(100 * time.Millisecond), want: true},
+		// the user signs in again right after revoke-all
+		{name: "issued after revocation in the same second", issuedAt: second.Add(900 * time.Millisecond), want: true},
+		{name: "issued in the next second", issuedAt: second.Add(time.Second), want: false},
+		{name: "issued in the previous second", issuedAt: second.Add(-time.Millisecond), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// iat is truncated to seconds the way tokens are issued
+			iat := jwt.NewNumericDate(tt.issuedAt).Time
+			sql, params, err := isTokenRevokedQuery("jti", "user", iat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(sql, "tokens_revoked_at >= $3") || len(params) != 3 {
+				t.Fatalf("isTokenRevokedQuery() = %s %v, want comparison with iat", sql, params)
+			}
+			// evaluates the condition the way postgres does
+			issuedAt, ok := params[2].(time.Time)
+			if !ok {
+				t.Fatalf("iat param = %T, want time", params[2])
+			}
+			if got := !revokedAt.Before(issuedAt); got != tt.want {
+				t.Errorf("revoked = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,10 +1,22 @@
 package storage
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
 	"github.com/triabokon/gotagv/internal/postgresql"
 )
 
-const uniqueViolation = "23505"
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
 
 type Storage struct {
 	client *postgresql.Client
@@ -15,3 +27,128 @@ func New(client *postgresql.Client) *Storage {
 		client: client,
 	}
 }
+
+// inTx runs f inside a transaction, which is committed if f succeeds and rolled back otherwise.
+func (s *Storage) inTx(ctx context.Context, f func(tx pgx.Tx) error) error {
+	tx, err := s.client.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		// rollback is no-op for committed transaction
+		_ = tx.Rollback(ctx)
+	}()
+
+	if fErr := f(tx); fErr != nil {
+		return fErr
+	}
+	if cErr := tx.Commit(ctx); cErr != nil {
+		return fmt.Errorf("failed to commit transaction: %w", cErr)
+	}
+	return nil
+}
+
+// ownedBy matches entity with given id in the workspace, restricted to rows of userID
+// unless userID is empty.
+func ownedBy(workspaceID, id, userID string) squirrel.Eq {
+	where := squirrel.Eq{"workspace_id": workspaceID, "id": id}
+	if userID != "" {
+		where["user_id"] = userID
+	}
+	return where
+}
+
+// rowQuerier is either the pool or a transaction.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// missingOrForbidden explains why an owner-scoped mutation affected no rows:
+// either the entity doesn't exist in the workspace or it belongs to someone else,
+// mutations inside a transaction must check with it.
+func missingOrForbidden(ctx context.Context, db rowQuerier, table, workspaceID, id string) error {
+	sql, params, err := postgresql.StatementBuilder.
+		Select("id").
+		From(table).
+		Where(squirrel.Eq{"workspace_id": workspaceID, "id": id}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var found string
+	rErr := db.QueryRow(ctx, sql, params...).Scan(&found)
+	if errors.Is(rErr, pgx.ErrNoRows) {
+		return model.ErrNotFound
+	}
+	if rErr != nil {
+		return fmt.Errorf("failed to check %s existence: %w", table, rErr)
+	}
+	return model.ErrForbidden
+}
+
+func columnList(columns []string) string {
+	return strings.Join(columns, ", ")
+}
+
+// nullString stores empty string as NULL.
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullFloat stores zero as NULL.
+func nullFloat(f float64) interface{} {
+	if f == 0 {
+		return nil
+	}
+	return f
+}
+
+// paginate orders rows by the sort column and id, and selects the page, one extra row is selected
+// to find out if there is the next page. Sort fields are mapped to columns through the whitelist.
+func paginate(
+	b squirrel.SelectBuilder, page *model.Page, columns map[model.SortField]string,
+) (squirrel.SelectBuilder, error) {
+	sort := model.Sort{Field: model.UpdatedAtSortField}
+	if page != nil {
+		sort = page.Sort
+	}
+	column, ok := columns[sort.Field]
+	if !ok {
+		return b, fmt.Errorf("unsupported sort field %q: %w", sort.Field, model.ErrInvalidArgument)
+	}
+	order, cmp := "ASC", ">"
+	if sort.Desc {
+		order, cmp = "DESC", "<"
+	}
+	b = b.OrderBy(column+" "+order, "id "+order)
+	if page == nil {
+		return b, nil
+	}
+	if page.After != nil {
+		if !page.After.Matches(sort) {
+			return b, fmt.Errorf("cursor doesn't match sort: %w", model.ErrInvalidArgument)
+		}
+		var value interface{} = page.After.Time
+		if sort.Field == model.StartTimeSortField {
+			value = page.After.Offset.Milliseconds()
+		}
+		b = b.Where(squirrel.Expr(fmt.Sprintf("(%s, id) %s (?, ?)", column, cmp), value, page.After.ID))
+	}
+	if page.Limit > 0 {
+		b = b.Limit(uint64(page.Limit) + 1)
+	}
+	return b, nil
+}
+
+// trimPage returns the number of the n selected rows that belong to the page and whether there is
+// the next page, paginate selects one row more than the limit to find it out.
+func trimPage(n int, page *model.Page) (int, bool) {
+	if page == nil || page.Limit <= 0 || n <= page.Limit {
+		return n, false
+	}
+	return page.Limit, true
+}
@@ -0,0 +1,268 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+	"github.com/triabokon/gotagv/internal/postgresql"
+)
+
+// fakeRow scans id of the entity or fails with err.
+type fakeRow struct {
+	err error
+}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*string) = "id"
+	return nil
+}
+
+// fakeQuerier records queries, e.g. of a transaction.
+type fakeQuerier struct {
+	err     error
+	queries []string
+}
+
+func (q *fakeQuerier) QueryRow(_ context.Context, sql string, _ ...interface{}) pgx.Row {
+	q.queries = append(q.queries, sql)
+	return fakeRow{err: q.err}
+}
+
+func TestMissingOrForbidden(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{name: "owned by other user", wantErr: model.ErrForbidden},
+		{name: "missing", err: pgx.ErrNoRows, wantErr: model.ErrNotFound},
+		{name: "query error", err: fmt.Errorf("connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &fakeQuerier{err: tt.err}
+			err := missingOrForbidden(context.Background(), q, annotationTable, "workspace", "id")
+			if err == nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("missingOrForbidden() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (errors.Is(err, model.ErrNotFound) || errors.Is(err, model.ErrForbidden)) {
+				t.Errorf("missingOrForbidden() error = %v, want query error", err)
+			}
+			want := "SELECT id FROM " + annotationTable + " WHERE id = $1 AND workspace_id = $2"
+			if len(q.queries) != 1 || q.queries[0] != want {
+				t.Errorf("missingOrForbidden() queries = %q, want %q", q.queries, want)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	updated := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		page       *model.Page
+		wantSQL    string
+		wantParams []interface{}
+		wantErr    bool
+	}{
+		{
+			name:    "no page",
+			wantSQL: "SELECT id FROM annotations ORDER BY updated_at ASC, id ASC",
+		},
+		{
+			name:    "limit selects one more row",
+			page:    &model.Page{Limit: 10, Sort: model.Sort{Field: model.CreatedAtSortField, Desc: true}},
+			wantSQL: "SELECT id FROM annotations ORDER BY created_at DESC, id DESC LIMIT 11",
+		},
+		{
+			// rows of the same start time are ordered and skipped by id
+			name: "after offset",
+			page: &model.Page{
+				Limit: 2, Sort: model.Sort{Field: model.StartTimeSortField},
+				After: &model.Cursor{Field: model.StartTimeSortField, Offset: 1500 * time.Millisecond, ID: "b"},
+			},
+			wantSQL:    "SELECT id FROM annotations WHERE (start_time, id) > ($1, $2) ORDER BY start_time ASC, id ASC LIMIT 3",
+			wantParams: []interface{}{int64(1500), "b"},
+		},
+		{
+			name: "after time desc",
+			page: &model.Page{
+				Sort:  model.Sort{Field: model.UpdatedAtSortField, Desc: true},
+				After: &model.Cursor{Field: model.UpdatedAtSortField, Desc: true, Time: updated, ID: "c"},
+			},
+			wantSQL:    "SELECT id FROM annotations WHERE (updated_at, id) < ($1, $2) ORDER BY updated_at DESC, id DESC",
+			wantParams: []interface{}{updated, "c"},
+		},
+		{
+			name: "cursor of other sort",
+			page: &model.Page{
+				Sort:  model.Sort{Field: model.StartTimeSortField},
+				After: &model.Cursor{Field: model.StartTimeSortField, Desc: true, ID: "c"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "column not in whitelist",
+			page:    &model.Page{Sort: model.Sort{Field: model.SortField("title")}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := paginate(postgresql.StatementBuilder.Select("id").From(annotationTable), tt.page, annotationSortColumns)
+			if tt.wantErr {
+				if !errors.Is(err, model.ErrInvalidArgument) {
+					t.Errorf("paginate() error = %v, want %v", err, model.ErrInvalidArgument)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			sql, params, err := b.ToSql()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("sql = %q, want %q", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("params = %v, want %v", params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestPaginateVideosByStartTime(t *testing.T) {
+	page := &model.Page{Sort: model.Sort{Field: model.StartTimeSortField}}
+	if _, err := paginate(postgresql.StatementBuilder.Select("id"), page, videoSortColumns); !errors.Is(
+		err, model.ErrInvalidArgument,
+	) {
+		t.Errorf("paginate() error = %v, want %v", err, model.ErrInvalidArgument)
+	}
+}
+
+func TestTrimPage(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        int
+		page     *model.Page
+		want     int
+		wantMore bool
+	}{
+		{name: "no page", n: 5, want: 5},
+		{name: "no limit", n: 5, page: &model.Page{}, want: 5},
+		{name: "less than limit", n: 2, page: &model.Page{Limit: 3}, want: 2},
+		{name: "exactly limit", n: 3, page: &model.Page{Limit: 3}, want: 3},
+		{name: "extra row", n: 4, page: &model.Page{Limit: 3}, want: 3, wantMore: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, more := trimPage(tt.n, tt.page)
+			if got != tt.want || more != tt.wantMore {
+				t.Errorf("trimPage() = %d, %t, want %d, %t", got, more, tt.want, tt.wantMore)
+			}
+		})
+	}
+}
+
+func TestLockAnnotationType(t *testing.T) {
+	tests := []struct {
+		name        string
+		typeName    model.AnnotationType
+		err         error
+		wantQueries int
+		wantErr     error
+	}{
+		{name: "built-in", typeName: model.TextAnnotationType},
+		{name: "custom", typeName: "quiz", wantQueries: 1},
+		{name: "deleted", typeName: "quiz", err: pgx.ErrNoRows, wantQueries: 1, wantErr: model.ErrInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &fakeQuerier{err: tt.err}
+			err := lockAnnotationType(context.Background(), q, tt.typeName)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("lockAnnotationType() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(q.queries) != tt.wantQueries {
+				t.Fatalf("lockAnnotationType() queries = %q, want %d", q.queries, tt.wantQueries)
+			}
+			want := "SELECT name FROM " + annotationTypeTable + " WHERE name = $1 FOR SHARE"
+			if tt.wantQueries > 0 && q.queries[0] != want {
+				t.Errorf("lockAnnotationType() query = %q, want %q", q.queries[0], want)
+			}
+		})
+	}
+}
+
+func TestOwnedBy(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  string
+		wantSQL string
+	}{
+		{name: "any user", wantSQL: "DELETE FROM videos WHERE id = $1 AND workspace_id = $2"},
+		{name: "owner", userID: "user", wantSQL: "DELETE FROM videos WHERE id = $1 AND user_id = $2 AND workspace_id = $3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, _, err := postgresql.StatementBuilder.Delete(videoTable).
+				Where(ownedBy("workspace", "video", tt.userID)).ToSql()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("sql = %q, want %q", sql, tt.wantSQL)
+			}
+		})
+	}
+}
+
+// valuesRow scans values into destinations of the same types, nil values are skipped.
+type valuesRow []interface{}
+
+func (r valuesRow) Scan(dest ...interface{}) error {
+	if len(dest) != len(r) {
+		return fmt.Errorf("scan of %d values into %d destinations", len(r), len(dest))
+	}
+	for i, v := range r {
+		if v != nil {
+			reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(v))
+		}
+	}
+	return nil
+}
+
+func TestScanMilliseconds(t *testing.T) {
+	now := time.Now().UTC()
+	a, err := scanAnnotation(valuesRow{
+		"a", "workspace", "video", "user", int64(1500), int64(2001),
+		model.TextAnnotationType, "text", "", "", nil, nil, now, now, int64(90061),
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.StartTime != 1500*time.Millisecond || a.EndTime != 2001*time.Millisecond ||
+		a.VideoDuration != 90061*time.Millisecond {
+		t.Errorf("annotation times = %v, %v, %v", a.StartTime, a.EndTime, a.VideoDuration)
+	}
+
+	v, err := scanVideo(valuesRow{"video", "workspace", "user", "https://example.com", int64(1), nil, false, now, now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Duration != time.Millisecond {
+		t.Errorf("video duration = %v, want %v", v.Duration, time.Millisecond)
+	}
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/jackc/pgconn"
@@ -15,46 +16,154 @@ import (
 
 const userTable = "users"
 
-func (s *Storage) GetUser(ctx context.Context, id string) (string, error) {
+func (s *Storage) GetUser(ctx context.Context, id string) (*model.User, error) {
+	return s.getUser(ctx, squirrel.Eq{"id": id})
+}
+
+func (s *Storage) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	return s.getUser(ctx, squirrel.Eq{"email": email})
+}
+
+func (s *Storage) GetUserByOIDCSubject(ctx context.Context, issuer, subject string) (*model.User, error) {
+	return s.getUser(ctx, squirrel.Eq{"oidc_issuer": issuer, "oidc_subject": subject})
+}
+
+func (s *Storage) getUser(ctx context.Context, where squirrel.Eq) (*model.User, error) {
 	sql, params, err := postgresql.StatementBuilder.
-		Select("id").
+		Select(userColumns()...).
 		From(userTable).
-		Where(squirrel.Eq{"id": id}).
+		Where(where).
 		ToSql()
 	if err != nil {
-		return "", fmt.Errorf("failed to build query: %w", err)
+		return nil, fmt.Errorf("failed to build query: %w", err)
 	}
 
 	row := s.client.DB.QueryRow(ctx, sql, params...)
-	var userID string
-	if rErr := row.Scan(&userID); rErr != nil {
-		return "", fmt.Errorf("failed to scan user id: %w", rErr)
+	u, sErr := scanUser(row)
+	if errors.Is(sErr, pgx.ErrNoRows) {
+		return nil, model.ErrNotFound
 	}
-	switch errors.Cause(err) {
-	case nil:
-		return userID, nil
-	case pgx.ErrNoRows:
-		return "", model.ErrNotFound
-	default:
-		return "", err
+	if sErr != nil {
+		return nil, fmt.Errorf("failed to get user: %w", sErr)
 	}
+	return u, nil
 }
 
-func (s *Storage) InsertUser(ctx context.Context, id string) error {
+// InsertUser creates user together with its personal workspace.
+func (s *Storage) InsertUser(ctx context.Context, u *model.User, w *model.Workspace) error {
 	query, args, err := postgresql.StatementBuilder.
 		Insert(userTable).
-		SetMap(map[string]interface{}{"id": id}).
+		SetMap(map[string]interface{}{
+			"id":            u.ID,
+			"email":         nullString(u.Email),
+			"password_hash": nullString(u.PasswordHash),
+			"oidc_issuer":   nullString(u.OIDCIssuer),
+			"oidc_subject":  nullString(u.OIDCSubject),
+			"role":          u.Role,
+			"workspace_id":  u.WorkspaceID,
+		}).
 		ToSql()
 	if err != nil {
 		return fmt.Errorf("failed to build query: %w", err)
 	}
 
-	if _, qErr := s.client.DB.Exec(ctx, query, args...); qErr != nil {
-		pgErr, ok := qErr.(*pgconn.PgError)
-		if ok && pgErr.Code == uniqueViolation {
-			return model.ErrAlreadyExists
+	return s.inTx(ctx, func(tx pgx.Tx) error {
+		if _, qErr := tx.Exec(ctx, query, args...); qErr != nil {
+			pgErr, ok := qErr.(*pgconn.PgError)
+			if ok && pgErr.Code == uniqueViolation {
+				return model.ErrAlreadyExists
+			}
+			return fmt.Errorf("failed to insert: %w", qErr)
 		}
-		return fmt.Errorf("failed to insert: %w", qErr)
+		return insertWorkspace(ctx, tx, w)
+	})
+}
+
+func (s *Storage) UpdateUserRole(ctx context.Context, id string, role model.Role) error {
+	sql, params, err := postgresql.StatementBuilder.
+		Update(userTable).
+		Set("role", role).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	ct, err := s.client.DB.Exec(ctx, sql, params...)
+	if err != nil {
+		return fmt.Errorf("failed to execute: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return model.ErrNotFound
 	}
 	return nil
 }
+
+// RecordFailedSignIn counts failed sign in attempt and locks the user until given time
+// once maxAttempts failures in a row are reached.
+func (s *Storage) RecordFailedSignIn(ctx context.Context, id string, maxAttempts int, lockUntil time.Time) error {
+	sql, params, err := postgresql.StatementBuilder.
+		Update(userTable).
+		Set("failed_sign_ins", squirrel.Expr(
+			"CASE WHEN failed_sign_ins + 1 >= ? THEN 0 ELSE failed_sign_ins + 1 END", maxAttempts,
+		)).
+		Set("locked_until", squirrel.Expr(
+			"CASE WHEN failed_sign_ins + 1 >= ? THEN ?::timestamp ELSE locked_until END", maxAttempts, lockUntil,
+		)).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, qErr := s.client.DB.Exec(ctx, sql, params...); qErr != nil {
+		return fmt.Errorf("failed to execute: %w", qErr)
+	}
+	return nil
+}
+
+func (s *Storage) ResetFailedSignIns(ctx context.Context, id string) error {
+	sql, params, err := postgresql.StatementBuilder.
+		Update(userTable).
+		Set("failed_sign_ins", 0).
+		Set("locked_until", nil).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, qErr := s.client.DB.Exec(ctx, sql, params...); qErr != nil {
+		return fmt.Errorf("failed to execute: %w", qErr)
+	}
+	return nil
+}
+
+func userColumns() []string {
+	columns := []string{
+		"id", "email", "password_hash", "role", "workspace_id", "failed_sign_ins", "locked_until",
+		"oidc_issuer", "oidc_subject",
+	}
+	return columns
+}
+
+func scanUser(row pgx.Row) (*model.User, error) {
+	var u model.User
+	var email, passwordHash, oidcIssuer, oidcSubject *string
+	if rErr := row.Scan(
+		&u.ID, &email, &passwordHash, &u.Role, &u.WorkspaceID, &u.FailedSignIns, &u.LockedUntil,
+		&oidcIssuer, &oidcSubject,
+	); rErr != nil {
+		return nil, fmt.Errorf("failed to scan user: %w", rErr)
+	}
+	if email != nil {
+		u.Email = *email
+	}
+	if passwordHash != nil {
+		u.PasswordHash = *passwordHash
+	}
+	if oidcIssuer != nil && oidcSubject != nil {
+		u.OIDCIssuer, u.OIDCSubject = *oidcIssuer, *oidcSubject
+	}
+	return &u, nil
+}
@@ -16,19 +16,37 @@ import (
 
 const videoTable = "videos"
 
-func (s *Storage) ListVideos(ctx context.Context) ([]*model.Video, error) {
-	sql, params, err := postgresql.StatementBuilder.
+// videoSortColumns is the whitelist of columns videos can be sorted by.
+var videoSortColumns = map[model.SortField]string{
+	model.CreatedAtSortField: "created_at",
+	model.UpdatedAtSortField: "updated_at",
+}
+
+func (s *Storage) ListVideos(
+	ctx context.Context, workspaceID string, f *model.VideoFilter, page *model.Page,
+) ([]*model.Video, *model.Cursor, error) {
+	builder := postgresql.StatementBuilder.
 		Select(videoColumns()...).
 		From(videoTable).
-		OrderBy("updated_at").
-		ToSql()
+		Where(squirrel.Eq{"workspace_id": workspaceID})
+	if f != nil && f.CreatedAfter != nil {
+		builder = builder.Where(squirrel.GtOrEq{"created_at": *f.CreatedAfter})
+	}
+	if f != nil && f.CreatedBefore != nil {
+		builder = builder.Where(squirrel.Lt{"created_at": *f.CreatedBefore})
+	}
+	builder, pErr := paginate(builder, page, videoSortColumns)
+	if pErr != nil {
+		return nil, nil, pErr
+	}
+	sql, params, err := builder.ToSql()
 	if err != nil {
-		return nil, fmt.Errorf("failed to build query: %w", err)
+		return nil, nil, fmt.Errorf("failed to build query: %w", err)
 	}
 
 	rows, err := s.client.DB.Query(ctx, sql, params...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to exec query: %w", err)
+		return nil, nil, fmt.Errorf("failed to exec query: %w", err)
 	}
 	defer rows.Close()
 
@@ -36,21 +54,27 @@ func (s *Storage) ListVideos(ctx context.Context) ([]*model.Video, error) {
 	for rows.Next() {
 		v, sErr := scanVideo(rows)
 		if sErr != nil {
-			return nil, fmt.Errorf("scan failed: %w", sErr)
+			return nil, nil, fmt.Errorf("scan failed: %w", sErr)
 		}
 		result = append(result, v)
 	}
 	if rErr := rows.Err(); rErr != nil {
-		return nil, rErr
+		return nil, nil, rErr
 	}
-	return result, nil
+
+	var next *model.Cursor
+	if n, more := trimPage(len(result), page); more {
+		result = result[:n]
+		next = result[n-1].Cursor(page.Sort)
+	}
+	return result, next, nil
 }
 
-func (s *Storage) GetVideo(ctx context.Context, id string) (*model.Video, error) {
+func (s *Storage) GetVideo(ctx context.Context, workspaceID, id string) (*model.Video, error) {
 	sql, params, err := postgresql.StatementBuilder.
 		Select(videoColumns()...).
 		From(videoTable).
-		Where(squirrel.Eq{"id": id}).
+		Where(squirrel.Eq{"workspace_id": workspaceID, "id": id}).
 		ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("failed to build query: %w", err)
@@ -71,12 +95,15 @@ func (s *Storage) InsertVideo(ctx context.Context, video *model.Video) error {
 	query, args, err := postgresql.StatementBuilder.
 		Insert(videoTable).
 		SetMap(map[string]interface{}{
-			"id":         video.ID,
-			"user_id":    video.UserID,
-			"url":        video.URL,
-			"duration":   video.Duration.Seconds(),
-			"created_at": video.CreatedAt,
-			"updated_at": video.UpdatedAt,
+			"id":           video.ID,
+			"workspace_id": video.WorkspaceID,
+			"user_id":      video.UserID,
+			"url":          video.URL,
+			"duration":     video.Duration.Milliseconds(),
+			"fps":          nullFloat(video.FPS),
+			"drop_frame":   video.DropFrame,
+			"created_at":   video.CreatedAt,
+			"updated_at":   video.UpdatedAt,
 		}).ToSql()
 	if err != nil {
 		return fmt.Errorf("failed to build query: %w", err)
@@ -87,42 +114,150 @@ func (s *Storage) InsertVideo(ctx context.Context, video *model.Video) error {
 		if ok && pgErr.Code == uniqueViolation {
 			return model.ErrAlreadyExists
 		}
+		if ok && pgErr.Code == foreignKeyViolation {
+			return model.ErrNotFound
+		}
 		return fmt.Errorf("failed to insert: %w", qErr)
 	}
 	return nil
 }
 
-func (s *Storage) DeleteVideo(ctx context.Context, id string) error {
+func (s *Storage) DeleteVideo(ctx context.Context, workspaceID, id, userID string) error {
 	deleteBuilder := postgresql.StatementBuilder.Delete(videoTable).
-		Where(squirrel.Eq{"id": id})
+		Where(ownedBy(workspaceID, id, userID))
 
 	sql, params, err := deleteBuilder.ToSql()
 	if err != nil {
 		return fmt.Errorf("failed to build query: %w", err)
 	}
-	_, err = s.client.DB.Exec(ctx, sql, params...)
+	ct, err := s.client.DB.Exec(ctx, sql, params...)
 	if err != nil {
 		return fmt.Errorf("failed to delete: %w", err)
 	}
+	if ct.RowsAffected() == 0 {
+		return missingOrForbidden(ctx, s.client.DB, videoTable, workspaceID, id)
+	}
 	return nil
 }
 
+// UpdateVideo changes the video, shrinking duration fails with model.ConflictError
+// if annotations end after the new duration. Video row is locked until update, annotations
+// are written under the same lock, so they can't be moved past the duration meanwhile.
+func (s *Storage) UpdateVideo(ctx context.Context, workspaceID, id string, p *model.UpdateVideoParams) error {
+	if p.NoUpdates() {
+		return fmt.Errorf("no updates")
+	}
+	lockSQL, lockParams, err := postgresql.StatementBuilder.
+		Select("id").
+		From(videoTable).
+		Where(squirrel.Eq{"workspace_id": workspaceID, "id": id}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+	builder := postgresql.StatementBuilder.
+		Update(videoTable).
+		Where(squirrel.Eq{"workspace_id": workspaceID, "id": id}).
+		Set("updated_at", time.Now())
+	if p.URL != nil {
+		builder = builder.Set("url", *p.URL)
+	}
+	if p.Duration != nil {
+		builder = builder.Set("duration", p.Duration.Milliseconds())
+	}
+	if p.FPS != nil {
+		builder = builder.Set("fps", nullFloat(*p.FPS))
+	}
+	if p.DropFrame != nil {
+		builder = builder.Set("drop_frame", *p.DropFrame)
+	}
+	updateSQL, updateParams, err := builder.ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	return s.inTx(ctx, func(tx pgx.Tx) error {
+		var videoID string
+		lErr := tx.QueryRow(ctx, lockSQL, lockParams...).Scan(&videoID)
+		if errors.Is(lErr, pgx.ErrNoRows) {
+			return model.ErrNotFound
+		}
+		if lErr != nil {
+			return fmt.Errorf("failed to lock video: %w", lErr)
+		}
+
+		if p.Duration != nil {
+			ids, oErr := annotationsEndingAfter(ctx, tx, workspaceID, id, *p.Duration)
+			if oErr != nil {
+				return oErr
+			}
+			if len(ids) > 0 {
+				return &model.ConflictError{Reason: "annotations end after the new duration", AnnotationIDs: ids}
+			}
+		}
+
+		if _, qErr := tx.Exec(ctx, updateSQL, updateParams...); qErr != nil {
+			return fmt.Errorf("failed to execute: %w", qErr)
+		}
+		return nil
+	})
+}
+
+func annotationsEndingAfter(
+	ctx context.Context, tx pgx.Tx, workspaceID, videoID string, duration time.Duration,
+) ([]string, error) {
+	sql, params, err := postgresql.StatementBuilder.
+		Select("id").
+		From(annotationTable).
+		Where(squirrel.Eq{"workspace_id": workspaceID, "video_id": videoID}).
+		Where(squirrel.Gt{"end_time": duration.Milliseconds()}).
+		OrderBy("start_time", "id").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if sErr := rows.Scan(&id); sErr != nil {
+			return nil, fmt.Errorf("scan failed: %w", sErr)
+		}
+		ids = append(ids, id)
+	}
+	if rErr := rows.Err(); rErr != nil {
+		return nil, rErr
+	}
+	return ids, nil
+}
+
 func videoColumns() []string {
 	columns := []string{
-		"id", "user_id", "url", "duration", "created_at", "updated_at",
+		"id", "workspace_id", "user_id", "url", "duration", "fps", "drop_frame", "created_at", "updated_at",
 	}
 	return columns
 }
 
 func scanVideo(row pgx.Row) (*model.Video, error) {
-	var durationSeconds int
+	var durationMillis int64
+	var fps *float64
 	var v model.Video
 	if rErr := row.Scan(
-		&v.ID, &v.UserID, &v.URL,
-		&durationSeconds, &v.CreatedAt, &v.UpdatedAt,
+		&v.ID, &v.WorkspaceID, &v.UserID, &v.URL,
+		&durationMillis, &fps, &v.DropFrame, &v.CreatedAt, &v.UpdatedAt,
 	); rErr != nil {
 		return nil, fmt.Errorf("failed to scan video: %w", rErr)
 	}
-	v.Duration = time.Duration(durationSeconds) * time.Second
+	if fps != nil {
+		v.FPS = *fps
+	}
+	v.Duration = time.Duration(durationMillis) * time.Millisecond
 	return &v, nil
 }
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+	"github.com/triabokon/gotagv/internal/postgresql"
+)
+
+const videoMemberTable = "video_members"
+
+func (s *Storage) GetVideoMember(ctx context.Context, workspaceID, videoID, userID string) (*model.VideoMember, error) {
+	sql, params, err := postgresql.StatementBuilder.
+		Select(videoMemberColumns()...).
+		From(videoMemberTable).
+		Where(squirrel.Eq{"workspace_id": workspaceID, "video_id": videoID, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	row := s.client.DB.QueryRow(ctx, sql, params...)
+	m, sErr := scanVideoMember(row)
+	if errors.Is(sErr, pgx.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if sErr != nil {
+		return nil, fmt.Errorf("failed to get video member: %w", sErr)
+	}
+	return m, nil
+}
+
+func (s *Storage) ListVideoMembers(ctx context.Context, workspaceID, videoID string) ([]*model.VideoMember, error) {
+	sql, params, err := postgresql.StatementBuilder.
+		Select(videoMemberColumns()...).
+		From(videoMemberTable).
+		Where(squirrel.Eq{"workspace_id": workspaceID, "video_id": videoID}).
+		OrderBy("created_at").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := s.client.DB.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*model.VideoMember
+	for rows.Next() {
+		m, sErr := scanVideoMember(rows)
+		if sErr != nil {
+			return nil, fmt.Errorf("scan failed: %w", sErr)
+		}
+		result = append(result, m)
+	}
+	if rErr := rows.Err(); rErr != nil {
+		return nil, rErr
+	}
+	return result, nil
+}
+
+func (s *Storage) InsertVideoMember(ctx context.Context, m *model.VideoMember) error {
+	query, args, err := postgresql.StatementBuilder.
+		Insert(videoMemberTable).
+		SetMap(map[string]interface{}{
+			"workspace_id": m.WorkspaceID,
+			"video_id":     m.VideoID,
+			"user_id":      m.UserID,
+			"permission":   m.Permission,
+			"created_at":   m.CreatedAt,
+		}).ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, qErr := s.client.DB.Exec(ctx, query, args...); qErr != nil {
+		pgErr, ok := qErr.(*pgconn.PgError)
+		if ok && pgErr.Code == uniqueViolation {
+			return model.ErrAlreadyExists
+		}
+		if ok && pgErr.Code == foreignKeyViolation {
+			return model.ErrNotFound
+		}
+		return fmt.Errorf("failed to insert: %w", qErr)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteVideoMember(ctx context.Context, workspaceID, videoID, userID string) error {
+	deleteBuilder := postgresql.StatementBuilder.Delete(videoMemberTable).
+		Where(squirrel.Eq{"workspace_id": workspaceID, "video_id": videoID, "user_id": userID})
+
+	sql, params, err := deleteBuilder.ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+	ct, err := s.client.DB.Exec(ctx, sql, params...)
+	if err != nil {
+		return fmt.Errorf("failed to delete: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func videoMemberColumns() []string {
+	columns := []string{"workspace_id", "video_id", "user_id", "permission", "created_at"}
+	return columns
+}
+
+func scanVideoMember(row pgx.Row) (*model.VideoMember, error) {
+	var m model.VideoMember
+	if rErr := row.Scan(&m.WorkspaceID, &m.VideoID, &m.UserID, &m.Permission, &m.CreatedAt); rErr != nil {
+		return nil, fmt.Errorf("failed to scan video member: %w", rErr)
+	}
+	return &m, nil
+}
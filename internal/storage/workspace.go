@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"github.com/triabokon/gotagv/internal/model"
+	"github.com/triabokon/gotagv/internal/postgresql"
+)
+
+const (
+	workspaceTable       = "workspaces"
+	workspaceMemberTable = "workspace_members"
+)
+
+func (s *Storage) GetWorkspace(ctx context.Context, id string) (*model.Workspace, error) {
+	sql, params, err := postgresql.StatementBuilder.
+		Select(workspaceColumns()...).
+		From(workspaceTable).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	row := s.client.DB.QueryRow(ctx, sql, params...)
+	w, sErr := scanWorkspace(row)
+	if errors.Is(sErr, pgx.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if sErr != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", sErr)
+	}
+	return w, nil
+}
+
+func (s *Storage) ListWorkspaces(ctx context.Context, userID string) ([]*model.Workspace, error) {
+	sql, params, err := postgresql.StatementBuilder.
+		Select(workspaceColumns()...).
+		From(workspaceTable).
+		Join(fmt.Sprintf(
+			"%s ON %s.workspace_id = %s.id", workspaceMemberTable, workspaceMemberTable, workspaceTable,
+		)).
+		Where(squirrel.Eq{"workspace_members.user_id": userID}).
+		OrderBy("workspaces.created_at").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := s.client.DB.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*model.Workspace
+	for rows.Next() {
+		w, sErr := scanWorkspace(rows)
+		if sErr != nil {
+			return nil, fmt.Errorf("scan failed: %w", sErr)
+		}
+		result = append(result, w)
+	}
+	if rErr := rows.Err(); rErr != nil {
+		return nil, rErr
+	}
+	return result, nil
+}
+
+// InsertWorkspace creates workspace and adds its owner as a member.
+func (s *Storage) InsertWorkspace(ctx context.Context, w *model.Workspace) error {
+	return s.inTx(ctx, func(tx pgx.Tx) error {
+		return insertWorkspace(ctx, tx, w)
+	})
+}
+
+func insertWorkspace(ctx context.Context, tx pgx.Tx, w *model.Workspace) error {
+	query, args, err := postgresql.StatementBuilder.
+		Insert(workspaceTable).
+		SetMap(map[string]interface{}{
+			"id":         w.ID,
+			"name":       w.Name,
+			"owner_id":   w.OwnerID,
+			"created_at": w.CreatedAt,
+		}).ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+	if _, qErr := tx.Exec(ctx, query, args...); qErr != nil {
+		pgErr, ok := qErr.(*pgconn.PgError)
+		if ok && pgErr.Code == uniqueViolation {
+			return model.ErrAlreadyExists
+		}
+		return fmt.Errorf("failed to insert workspace: %w", qErr)
+	}
+
+	memberQuery, memberArgs, err := workspaceMemberInsert(&model.WorkspaceMember{
+		WorkspaceID: w.ID,
+		UserID:      w.OwnerID,
+		CreatedAt:   w.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+	if _, qErr := tx.Exec(ctx, memberQuery, memberArgs...); qErr != nil {
+		return fmt.Errorf("failed to insert workspace owner: %w", qErr)
+	}
+	return nil
+}
+
+func (s *Storage) GetWorkspaceMember(ctx context.Context, workspaceID, userID string) (*model.WorkspaceMember, error) {
+	sql, params, err := postgresql.StatementBuilder.
+		Select("workspace_id", "user_id", "created_at").
+		From(workspaceMemberTable).
+		Where(squirrel.Eq{"workspace_id": workspaceID, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var m model.WorkspaceMember
+	rErr := s.client.DB.QueryRow(ctx, sql, params...).Scan(&m.WorkspaceID, &m.UserID, &m.CreatedAt)
+	if errors.Is(rErr, pgx.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if rErr != nil {
+		return nil, fmt.Errorf("failed to get workspace member: %w", rErr)
+	}
+	return &m, nil
+}
+
+func (s *Storage) InsertWorkspaceMember(ctx context.Context, m *model.WorkspaceMember) error {
+	query, args, err := workspaceMemberInsert(m)
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, qErr := s.client.DB.Exec(ctx, query, args...); qErr != nil {
+		pgErr, ok := qErr.(*pgconn.PgError)
+		if ok && pgErr.Code == uniqueViolation {
+			return model.ErrAlreadyExists
+		}
+		if ok && pgErr.Code == foreignKeyViolation {
+			return model.ErrNotFound
+		}
+		return fmt.Errorf("failed to insert: %w", qErr)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteWorkspaceMember(ctx context.Context, workspaceID, userID string) error {
+	deleteBuilder := postgresql.StatementBuilder.Delete(workspaceMemberTable).
+		Where(squirrel.Eq{"workspace_id": workspaceID, "user_id": userID})
+
+	sql, params, err := deleteBuilder.ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+	ct, err := s.client.DB.Exec(ctx, sql, params...)
+	if err != nil {
+		return fmt.Errorf("failed to delete: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func workspaceMemberInsert(m *model.WorkspaceMember) (string, []interface{}, error) {
+	return postgresql.StatementBuilder.
+		Insert(workspaceMemberTable).
+		SetMap(map[string]interface{}{
+			"workspace_id": m.WorkspaceID,
+			"user_id":      m.UserID,
+			"created_at":   m.CreatedAt,
+		}).ToSql()
+}
+
+func workspaceColumns() []string {
+	columns := []string{
+		"workspaces.id", "workspaces.name", "workspaces.owner_id", "workspaces.created_at",
+	}
+	return columns
+}
+
+func scanWorkspace(row pgx.Row) (*model.Workspace, error) {
+	var w model.Workspace
+	if rErr := row.Scan(&w.ID, &w.Name, &w.OwnerID, &w.CreatedAt); rErr != nil {
+		return nil, fmt.Errorf("failed to scan workspace: %w", rErr)
+	}
+	return &w, nil
+}
@@ -0,0 +1,344 @@
+// Package timecode parses and formats positions within a video in notations used by editors and tools.
+package timecode
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a notation of times in responses.
+type Format string
+
+const (
+	// NanosecondsFormat keeps times as integer nanoseconds.
+	NanosecondsFormat Format = ""
+	// GoFormat is the notation of time.ParseDuration, e.g. 1m32.5s.
+	GoFormat Format = "go"
+	// ClockFormat is HH:MM:SS.mmm, e.g. 00:01:32.500.
+	ClockFormat Format = "clock"
+	// SMPTEFormat is HH:MM:SS:FF timecode, it requires frame rate of the video.
+	SMPTEFormat Format = "smpte"
+	// ISO8601Format is ISO-8601 duration, e.g. PT1M32.5S.
+	ISO8601Format Format = "iso8601"
+	// SecondsFormat is the number of seconds, e.g. 92.5.
+	SecondsFormat Format = "seconds"
+)
+
+var ErrFrameRateRequired = errors.New("frame rate is required")
+
+// Rate is the frame rate of the video, zero FPS means it's unknown.
+// NTSC rates may be given rounded, e.g. 29.97 is treated as 30000/1001.
+// Drop-frame timecode skips frame numbers to keep up with the clock, it's defined for 29.97 and 59.94 fps only.
+type Rate struct {
+	FPS       float64
+	DropFrame bool
+}
+
+// ntscTolerance is the distance from exact NTSC rate within which the rate is considered NTSC.
+const ntscTolerance = 0.005
+
+func (r Rate) Known() bool {
+	return r.FPS > 0
+}
+
+func (r Rate) Validate() error {
+	if r.FPS < 0 {
+		return fmt.Errorf("negative frame rate %g", r.FPS)
+	}
+	if r.DropFrame && (!r.ntsc() || (r.timebase() != 30 && r.timebase() != 60)) {
+		return fmt.Errorf("drop-frame timecode is defined only for 29.97 and 59.94 fps, got %g", r.FPS)
+	}
+	return nil
+}
+
+// exact returns frames per second, NTSC rates are made exact.
+func (r Rate) exact() float64 {
+	if r.ntsc() {
+		return float64(r.timebase()) * 1000 / 1001
+	}
+	return r.FPS
+}
+
+func (r Rate) ntsc() bool {
+	base := float64(r.timebase())
+	return base != r.FPS && math.Abs(base*1000/1001-r.FPS) < ntscTolerance
+}
+
+// timebase is the number of frames in a second of timecode, e.g. 30 for 29.97 fps.
+func (r Rate) timebase() int64 {
+	return int64(math.Round(r.FPS))
+}
+
+// dropped is the number of frame numbers skipped every minute except every tenth one.
+func (r Rate) dropped() int64 {
+	if !r.DropFrame {
+		return 0
+	}
+	return r.timebase() / 15
+}
+
+// ToFormat checks that the format is known.
+func ToFormat(f string) (Format, error) {
+	switch Format(f) {
+	case NanosecondsFormat, GoFormat, ClockFormat, SMPTEFormat, ISO8601Format, SecondsFormat:
+		return Format(f), nil
+	default:
+		return "", fmt.Errorf("unknown time format %q", f)
+	}
+}
+
+// maxHours limits hours of clock and SMPTE times, so hours with minutes and seconds fit into time.Duration.
+const maxHours = int64(math.MaxInt64/time.Hour) - 1
+
+var (
+	numberRe  = regexp.MustCompile(`^\d+(\.\d+)?$`)
+	iso8601Re = regexp.MustCompile(
+		`^P(?:(\d+(?:[.,]\d+)?)W)?(?:(\d+(?:[.,]\d+)?)D)?` +
+			`(?:T(?:(\d+(?:[.,]\d+)?)H)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`,
+	)
+	iso8601Units = []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+)
+
+// Parse parses non-negative time in one of the notations:
+//   - plain seconds, e.g. 92.5,
+//   - HH:MM:SS.mmm or MM:SS.mmm, e.g. 01:32.5,
+//   - SMPTE HH:MM:SS:FF or HH:MM:SS;FF for drop-frame, frames are counted at fps rounded to integer,
+//   - ISO-8601 duration without years and months, e.g. PT1M32.5S,
+//   - time.ParseDuration notation, e.g. 1m32.5s.
+//
+// SMPTE timecode fails with ErrFrameRateRequired if the rate is unknown.
+func Parse(s string, rate Rate) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return 0, errors.New("empty time")
+	case numberRe.MatchString(s):
+		return parseDecimal(s, time.Second)
+	case strings.HasPrefix(s, "P"):
+		return parseISO8601(s)
+	case strings.Contains(s, ":"):
+		return parseClock(s, rate)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative time %q", s)
+	}
+	return d, nil
+}
+
+// parseDecimal converts decimal number of units into duration without loss of precision of floats.
+func parseDecimal(s string, unit time.Duration) (time.Duration, error) {
+	intPart, fracPart, _ := strings.Cut(strings.Replace(s, ",", ".", 1), ".")
+	n, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	if n > math.MaxInt64/int64(unit) {
+		return 0, fmt.Errorf("time %q is too large", s)
+	}
+	// fraction is less than a unit
+	var frac time.Duration
+	scale := unit
+	for _, digit := range fracPart {
+		if digit < '0' || digit > '9' {
+			return 0, fmt.Errorf("invalid number %q", s)
+		}
+		scale /= 10
+		frac += time.Duration(digit-'0') * scale
+	}
+	d, ok := add(time.Duration(n)*unit, frac)
+	if !ok {
+		return 0, fmt.Errorf("time %q is too large", s)
+	}
+	return d, nil
+}
+
+// add returns sum of non-negative durations, ok is false if it overflows.
+func add(a, b time.Duration) (time.Duration, bool) {
+	if a > math.MaxInt64-b {
+		return 0, false
+	}
+	return a + b, true
+}
+
+func parseISO8601(s string) (time.Duration, error) {
+	m := iso8601Re.FindStringSubmatch(s)
+	if m == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("invalid ISO-8601 duration %q", s)
+	}
+	var d time.Duration
+	for i, unit := range iso8601Units {
+		if m[i+1] == "" {
+			continue
+		}
+		part, err := parseDecimal(m[i+1], unit)
+		if err != nil {
+			return 0, err
+		}
+		var ok bool
+		if d, ok = add(d, part); !ok {
+			return 0, fmt.Errorf("time %q is too large", s)
+		}
+	}
+	return d, nil
+}
+
+func parseClock(s string, rate Rate) (time.Duration, error) {
+	parts := strings.Split(strings.Replace(s, ";", ":", 1), ":")
+	if len(parts) == 4 {
+		return parseSMPTE(s, parts, rate)
+	}
+	if len(parts) == 2 {
+		parts = append([]string{"0"}, parts...)
+	}
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	hours, hErr := parseInt(parts[0], maxHours)
+	minutes, mErr := parseInt(parts[1], 59)
+	if hErr != nil || mErr != nil || !numberRe.MatchString(parts[2]) {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	seconds, sErr := parseDecimal(parts[2], time.Second)
+	if sErr != nil || seconds >= time.Minute {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + seconds, nil
+}
+
+func parseSMPTE(s string, parts []string, rate Rate) (time.Duration, error) {
+	if !rate.Known() {
+		return 0, fmt.Errorf("can't parse timecode %q: %w", s, ErrFrameRateRequired)
+	}
+	base := rate.timebase()
+	hours, hErr := parseInt(parts[0], maxHours)
+	minutes, mErr := parseInt(parts[1], 59)
+	seconds, sErr := parseInt(parts[2], 59)
+	frames, fErr := parseInt(parts[3], base-1)
+	if hErr != nil || mErr != nil || sErr != nil || fErr != nil {
+		return 0, fmt.Errorf("invalid timecode %q at %g fps", s, rate.FPS)
+	}
+	totalMinutes := hours*60 + minutes
+	dropped := rate.dropped()
+	if dropped > 0 && seconds == 0 && minutes%10 != 0 && frames < dropped {
+		return 0, fmt.Errorf("timecode %q is skipped in drop-frame timecode", s)
+	}
+	number := (totalMinutes*60+seconds)*base + frames - dropped*(totalMinutes-totalMinutes/10)
+	// NTSC frames are longer than 1/timebase of a second, so the last hours overflow
+	if !FramesFit(number, rate) {
+		return 0, fmt.Errorf("timecode %q is too large", s)
+	}
+	return FramesToDuration(number, rate), nil
+}
+
+func parseInt(s string, upper int64) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > upper {
+		return 0, fmt.Errorf("%d is out of range", n)
+	}
+	return n, nil
+}
+
+// Precision is the precision times are stored with.
+const Precision = time.Millisecond
+
+// FramesFit reports whether start time of the frame can be represented by time.Duration,
+// FramesToDuration overflows otherwise.
+func FramesFit(frames int64, rate Rate) bool {
+	return frames >= 0 && float64(frames)/rate.exact() < (math.MaxInt64-float64(Precision))/float64(time.Second)
+}
+
+// FramesToDuration returns start time of the frame rounded up to Precision, so it stays within the frame.
+func FramesToDuration(frames int64, rate Rate) time.Duration {
+	d := time.Duration(math.Round(float64(frames) * float64(time.Second) / rate.exact()))
+	if rest := d % Precision; rest != 0 {
+		d += Precision - rest
+	}
+	return d
+}
+
+// DurationToFrames returns number of the frame shown at d.
+func DurationToFrames(d time.Duration, rate Rate) int64 {
+	// small epsilon keeps start times of frames, which are rounded, in their frame
+	return int64(math.Floor(d.Seconds()*rate.exact() + 1e-6))
+}
+
+// SnapToFrame moves d to the start of its frame.
+func SnapToFrame(d time.Duration, rate Rate) time.Duration {
+	return FramesToDuration(DurationToFrames(d, rate), rate)
+}
+
+// formatSMPTE converts frame number to timecode, drop-frame timecode adds numbers skipped before the frame.
+func formatSMPTE(d time.Duration, rate Rate) string {
+	base := rate.timebase()
+	number := DurationToFrames(d, rate)
+	sep := ":"
+	if dropped := rate.dropped(); dropped > 0 {
+		sep = ";"
+		perMinute := base*60 - dropped
+		perTenMinutes := perMinute*10 + dropped
+		tens, rest := number/perTenMinutes, number%perTenMinutes
+		number += 9 * dropped * tens
+		if rest > dropped {
+			number += dropped * ((rest - dropped) / perMinute)
+		}
+	}
+	secs := number / base
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", secs/3600, secs/60%60, secs%60, sep, number%base)
+}
+
+// Format renders d in the format, SMPTE timecode requires known rate.
+func (f Format) Format(d time.Duration, rate Rate) (string, error) {
+	switch f {
+	case NanosecondsFormat:
+		return strconv.FormatInt(int64(d), 10), nil
+	case GoFormat:
+		return d.String(), nil
+	case ClockFormat:
+		ms := d.Milliseconds()
+		return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000), nil
+	case SMPTEFormat:
+		if !rate.Known() {
+			return "", fmt.Errorf("can't format timecode: %w", ErrFrameRateRequired)
+		}
+		return formatSMPTE(d, rate), nil
+	case ISO8601Format:
+		return formatISO8601(d), nil
+	case SecondsFormat:
+		return strconv.FormatFloat(d.Seconds(), 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unknown time format %q", f)
+	}
+}
+
+func formatISO8601(d time.Duration) string {
+	if d == 0 {
+		return "PT0S"
+	}
+	var b strings.Builder
+	b.WriteString("PT")
+	if h := d / time.Hour; h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+		d -= h * time.Hour
+	}
+	if m := d / time.Minute; m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+		d -= m * time.Minute
+	}
+	if d > 0 {
+		b.WriteString(strconv.FormatFloat(d.Seconds(), 'f', -1, 64))
+		b.WriteString("S")
+	}
+	return b.String()
+}
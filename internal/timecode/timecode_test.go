@@ -0,0 +1,221 @@
+package timecode
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+var (
+	pal     = Rate{FPS: 25}
+	ntsc    = Rate{FPS: 29.97}
+	ntscDF  = Rate{FPS: 29.97, DropFrame: true}
+	ntsc60  = Rate{FPS: 59.94, DropFrame: true}
+	unknown = Rate{}
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		rate    Rate
+		want    time.Duration
+		wantErr bool
+	}{
+		// seconds
+		{name: "seconds", s: "92.5", want: 92500 * time.Millisecond},
+		{name: "seconds with spaces", s: " 92 ", want: 92 * time.Second},
+		{name: "seconds fraction", s: "0.0015", want: 1500 * time.Microsecond},
+		{name: "seconds fraction below nanosecond", s: "1.0000000009", want: time.Second},
+		{name: "largest seconds", s: "9223372036.854775807", want: math.MaxInt64},
+		{name: "too many seconds", s: "9223372037", wantErr: true},
+		{name: "seconds fraction overflow", s: "9223372036.9", wantErr: true},
+		{name: "empty", s: " ", wantErr: true},
+
+		// clock
+		{name: "minutes and seconds", s: "01:32.5", want: 92500 * time.Millisecond},
+		{name: "hours", s: "1:02:03.004", want: time.Hour + 2*time.Minute + 3004*time.Millisecond},
+		{name: "hundred hours", s: "100:00:00", want: 100 * time.Hour},
+		{name: "largest hours", s: "2562046:59:59.999", want: 2562047*time.Hour - time.Millisecond},
+		{name: "too many hours", s: "2562047:00:00", wantErr: true},
+		{name: "hours overflowing int32", s: "4294967296:00:00", wantErr: true},
+		{name: "60 minutes", s: "00:60:00", wantErr: true},
+		{name: "60 seconds", s: "00:00:60", wantErr: true},
+		{name: "negative seconds", s: "00:-1", wantErr: true},
+		{name: "too many parts", s: "1:2:3:4:5", wantErr: true},
+
+		// SMPTE
+		{name: "pal", s: "01:00:00:12", rate: pal, want: time.Hour + 480*time.Millisecond},
+		{name: "ntsc non-drop", s: "00:00:01:00", rate: ntsc, want: 1001 * time.Millisecond},
+		{name: "ntsc non-drop minute", s: "00:01:00:00", rate: ntsc, want: 60060 * time.Millisecond},
+		// 1800 frames, two frame numbers are dropped in the first minute
+		{name: "drop-frame minute", s: "00:01:00;02", rate: ntscDF, want: 60060 * time.Millisecond},
+		{name: "drop-frame before minute", s: "00:00:59;29", rate: ntscDF, want: 60027 * time.Millisecond},
+		{name: "drop-frame tenth minute keeps numbers", s: "00:10:00;00", rate: ntscDF, want: 600 * time.Second},
+		// drop-frame timecode runs 3.6ms behind the clock every hour
+		{name: "drop-frame hour", s: "01:00:00;00", rate: ntscDF, want: 3599997 * time.Millisecond},
+		{name: "drop-frame dropped number", s: "00:01:00;00", rate: ntscDF, wantErr: true},
+		{name: "drop-frame 59.94", s: "00:01:00;04", rate: ntsc60, want: 60060 * time.Millisecond},
+		{name: "drop-frame 59.94 dropped number", s: "00:01:00;03", rate: ntsc60, wantErr: true},
+		{name: "frame out of range", s: "00:00:00:25", rate: pal, wantErr: true},
+		{name: "unknown rate", s: "00:00:01:00", rate: unknown, wantErr: true},
+		{name: "ntsc overflow", s: "2562046:00:00:00", rate: ntsc, wantErr: true},
+
+		// ISO-8601
+		{name: "iso minutes", s: "PT1M32.5S", want: 92500 * time.Millisecond},
+		{name: "iso comma", s: "PT0,5S", want: 500 * time.Millisecond},
+		{name: "iso weeks and days", s: "P1W1DT1H", want: 193 * time.Hour},
+		{name: "iso fractional hours", s: "PT1.5H", want: 90 * time.Minute},
+		{name: "iso largest weeks", s: "P15250W", want: 15250 * 7 * 24 * time.Hour},
+		{name: "iso too many weeks", s: "P15251W", wantErr: true},
+		{name: "iso weeks overflowing int32", s: "P4294967296W", wantErr: true},
+		{name: "iso too many days", s: "P106752D", wantErr: true},
+		{name: "iso too many hours", s: "PT2562048H", wantErr: true},
+		{name: "iso sum overflow", s: "P15250WT700H", wantErr: true},
+		{name: "iso empty", s: "P", wantErr: true},
+		{name: "iso empty time", s: "P1DT", wantErr: true},
+		{name: "iso years", s: "P1Y", wantErr: true},
+
+		// time.ParseDuration
+		{name: "go", s: "1m32.5s", want: 92500 * time.Millisecond},
+		{name: "go negative", s: "-1s", wantErr: true},
+		{name: "garbage", s: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.s, tt.rate)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Parse(%q) = %v, want error", tt.s, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.s, err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFrameRateRequired(t *testing.T) {
+	if _, err := Parse("00:00:01:00", unknown); !errors.Is(err, ErrFrameRateRequired) {
+		t.Errorf("Parse() error = %v, want %v", err, ErrFrameRateRequired)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		d       time.Duration
+		rate    Rate
+		want    string
+		wantErr bool
+	}{
+		{name: "nanoseconds", format: NanosecondsFormat, d: 92500 * time.Millisecond, want: "92500000000"},
+		{name: "go", format: GoFormat, d: 92500 * time.Millisecond, want: "1m32.5s"},
+		{name: "clock", format: ClockFormat, d: 92500 * time.Millisecond, want: "00:01:32.500"},
+		{name: "clock hours", format: ClockFormat, d: 100*time.Hour + 1500*time.Millisecond, want: "100:00:01.500"},
+		{name: "iso zero", format: ISO8601Format, want: "PT0S"},
+		{name: "iso", format: ISO8601Format, d: time.Hour + time.Minute + 1500*time.Millisecond, want: "PT1H1M1.5S"},
+		{name: "iso whole hours", format: ISO8601Format, d: 26 * time.Hour, want: "PT26H"},
+		{name: "seconds", format: SecondsFormat, d: 92500 * time.Millisecond, want: "92.5"},
+		{name: "smpte pal", format: SMPTEFormat, d: time.Hour + 480*time.Millisecond, rate: pal, want: "01:00:00:12"},
+		{name: "smpte ntsc", format: SMPTEFormat, d: 60060 * time.Millisecond, rate: ntsc, want: "00:01:00:00"},
+		{name: "smpte within frame", format: SMPTEFormat, d: 50 * time.Millisecond, rate: pal, want: "00:00:00:01"},
+		{name: "drop-frame minute", format: SMPTEFormat, d: 60060 * time.Millisecond, rate: ntscDF, want: "00:01:00;02"},
+		{
+			name: "drop-frame before minute", format: SMPTEFormat, d: 60027 * time.Millisecond, rate: ntscDF,
+			want: "00:00:59;29",
+		},
+		{name: "drop-frame tenth minute", format: SMPTEFormat, d: 600 * time.Second, rate: ntscDF, want: "00:10:00;00"},
+		{name: "drop-frame hour", format: SMPTEFormat, d: time.Hour, rate: ntscDF, want: "01:00:00;00"},
+		{name: "drop-frame 59.94", format: SMPTEFormat, d: 60060 * time.Millisecond, rate: ntsc60, want: "00:01:00;04"},
+		{name: "smpte unknown rate", format: SMPTEFormat, d: time.Second, wantErr: true},
+		{name: "unknown format", format: "frames", d: time.Second, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.format.Format(tt.d, tt.rate)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Format() = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatParseRoundTrip(t *testing.T) {
+	// every frame of the first eleven minutes keeps its timecode
+	for _, rate := range []Rate{pal, ntsc, ntscDF, ntsc60} {
+		for frame := int64(0); frame < 11*60*int64(rate.timebase()); frame++ {
+			d := FramesToDuration(frame, rate)
+			tc, err := SMPTEFormat.Format(d, rate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, pErr := Parse(tc, rate)
+			if pErr != nil || parsed != d {
+				t.Fatalf("%g fps frame %d: Parse(%q) = %v, %v, want %v", rate.FPS, frame, tc, parsed, pErr, d)
+			}
+		}
+	}
+}
+
+func TestSnapToFrame(t *testing.T) {
+	tests := []struct {
+		name string
+		d    time.Duration
+		rate Rate
+		want time.Duration
+	}{
+		{name: "frame start", d: 40 * time.Millisecond, rate: pal, want: 40 * time.Millisecond},
+		{name: "within frame", d: 79 * time.Millisecond, rate: pal, want: 40 * time.Millisecond},
+		// frame 1 starts at 33.366ms and is stored rounded up
+		{name: "ntsc frame start", d: 34 * time.Millisecond, rate: ntsc, want: 34 * time.Millisecond},
+		{name: "ntsc within frame", d: 66 * time.Millisecond, rate: ntsc, want: 34 * time.Millisecond},
+		{name: "ntsc next frame", d: 67 * time.Millisecond, rate: ntsc, want: 67 * time.Millisecond},
+		{name: "drop-frame minute", d: 60070 * time.Millisecond, rate: ntscDF, want: 60060 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SnapToFrame(tt.d, tt.rate); got != tt.want {
+				t.Errorf("SnapToFrame(%v) = %v, want %v", tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFramesFit(t *testing.T) {
+	tests := []struct {
+		frames int64
+		rate   Rate
+		want   bool
+	}{
+		{frames: 0, rate: pal, want: true},
+		{frames: -1, rate: pal, want: false},
+		{frames: 230584300000, rate: pal, want: true},
+		{frames: 400000000000, rate: pal, want: false},
+		{frames: math.MaxInt64, rate: ntsc, want: false},
+	}
+	for _, tt := range tests {
+		if got := FramesFit(tt.frames, tt.rate); got != tt.want {
+			t.Errorf("FramesFit(%d, %g) = %t, want %t", tt.frames, tt.rate.FPS, got, tt.want)
+		}
+		if tt.want && FramesToDuration(tt.frames, tt.rate) < 0 {
+			t.Errorf("FramesToDuration(%d, %g) overflows", tt.frames, tt.rate.FPS)
+		}
+	}
+}